@@ -0,0 +1,368 @@
+package acme
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+const (
+	DefaultAdminAddr       = ""
+	DefaultShutdownTimeout = 10 * time.Second
+)
+
+// AdminServer serves the auxiliary health, status, and operational endpoints of the
+// webhook on a separate listener from the cert-manager webhook API server. The
+// health probes are always unauthenticated, but every other endpoint requires a
+// bearer token or a verified client certificate if either is configured since they
+// expose operational details.
+type AdminServer struct {
+	solver       *LinodeDNSProviderSolver
+	addr         string
+	tokenFile    string
+	token        string
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientCAs    *x509.CertPool
+	automate     bool
+	logLevel     logLevelOverride
+	srv          *http.Server
+}
+
+// NewAdminServer creates an admin server that reports on the specified solver.
+func NewAdminServer(solver *LinodeDNSProviderSolver) *AdminServer {
+	return &AdminServer{solver: solver}
+}
+
+// AddFlags registers the admin server command line flags on the specified flag set.
+func (a *AdminServer) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&a.addr, "admin-addr", envString("ADMIN_ADDR", DefaultAdminAddr), "address to serve the health, metrics, and admin endpoints on (disabled if empty)")
+	fs.StringVar(&a.tokenFile, "admin-token-file", envString("ADMIN_TOKEN_FILE", ""), "path to a file containing the bearer token required to access the metrics and admin endpoints")
+	fs.StringVar(&a.certFile, "admin-tls-cert-file", envString("ADMIN_TLS_CERT_FILE", ""), "path to the PEM encoded certificate used to serve the admin endpoints with TLS")
+	fs.StringVar(&a.keyFile, "admin-tls-key-file", envString("ADMIN_TLS_KEY_FILE", ""), "path to the PEM encoded private key used to serve the admin endpoints with TLS")
+	fs.BoolVar(&a.automate, "admin-automation-api", envBool("ADMIN_AUTOMATION_API", false), "serve the automation API that presents and cleans up challenge records for external automation (requires authentication)")
+	fs.StringVar(&a.clientCAFile, "admin-client-ca-file", envString("ADMIN_CLIENT_CA_FILE", ""), "path to a PEM encoded CA bundle used to authenticate client certificates on the admin endpoints (requires TLS)")
+}
+
+// Serve the admin endpoints until the context is canceled. If no address is
+// configured then Serve returns immediately without error.
+func (a *AdminServer) Serve(ctx context.Context) (err error) {
+	if a.addr == "" {
+		klog.V(2).Info("admin server is disabled")
+		return nil
+	}
+
+	if err = a.loadToken(); err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if tlsConfig, err = a.tlsConfig(); err != nil {
+		return err
+	}
+
+	if a.automate && a.token == "" && a.clientCAs == nil {
+		return errors.New("the admin automation API requires --admin-token-file or --admin-client-ca-file")
+	}
+
+	if a.token == "" && a.clientCAs == nil {
+		klog.Warning("admin server is running without authentication; set --admin-token-file or --admin-client-ca-file to protect the metrics and admin endpoints")
+	}
+
+	var sock net.Listener
+	if sock, err = net.Listen("tcp", a.addr); err != nil {
+		return fmt.Errorf("could not listen on admin address %q: %w", a.addr, err)
+	}
+
+	a.srv = &http.Server{
+		Handler:           a.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+		TLSConfig:         tlsConfig,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+		defer cancel()
+		if err := a.srv.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("could not gracefully shutdown admin server: %v", err)
+		}
+	}()
+
+	klog.Infof("admin server listening on %s (tls=%t)", sock.Addr(), tlsConfig != nil)
+	if tlsConfig != nil {
+		// The certificate is provided by the TLS config rather than the file paths.
+		err = a.srv.ServeTLS(sock, "", "")
+	} else {
+		err = a.srv.Serve(sock)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (a *AdminServer) loadToken() error {
+	if a.tokenFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.tokenFile)
+	if err != nil {
+		return fmt.Errorf("could not read admin token file: %w", err)
+	}
+
+	if a.token = strings.TrimSpace(string(data)); a.token == "" {
+		return fmt.Errorf("admin token file %q is empty", a.tokenFile)
+	}
+	return nil
+}
+
+// Returns the TLS configuration for the admin server or nil if TLS is not enabled.
+// The certificate is reloaded from disk when it changes so that rotated certificates
+// (e.g. issued by cert-manager) are served without restarting the webhook.
+func (a *AdminServer) tlsConfig() (_ *tls.Config, err error) {
+	if a.certFile == "" && a.keyFile == "" {
+		if a.clientCAFile != "" {
+			return nil, errors.New("admin client certificate authentication requires --admin-tls-cert-file and --admin-tls-key-file")
+		}
+		return nil, nil
+	}
+
+	if a.certFile == "" || a.keyFile == "" {
+		return nil, errors.New("both --admin-tls-cert-file and --admin-tls-key-file are required to serve the admin endpoints with TLS")
+	}
+
+	certs := &certReloader{certFile: a.certFile, keyFile: a.keyFile}
+	if _, err = certs.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+
+	if a.clientCAFile != "" {
+		var data []byte
+		if data, err = os.ReadFile(a.clientCAFile); err != nil {
+			return nil, fmt.Errorf("could not read admin client CA file: %w", err)
+		}
+
+		a.clientCAs = x509.NewCertPool()
+		if !a.clientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in %q", a.clientCAFile)
+		}
+
+		// Client certificates are verified if given but are not required so that the
+		// kubelet can still access the health probes without a certificate.
+		conf.ClientCAs = a.clientCAs
+		conf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return conf, nil
+}
+
+func (a *AdminServer) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// Health probes do not require authentication.
+	mux.HandleFunc("GET /healthz", a.healthz)
+	mux.HandleFunc("GET /readyz", a.readyz)
+
+	// All other endpoints require authentication when configured.
+	mux.Handle("GET /version", a.authenticate(http.HandlerFunc(a.version)))
+	mux.Handle("GET /status", a.authenticate(http.HandlerFunc(a.status)))
+	mux.Handle("GET /metrics", a.authenticate(metricsHandler()))
+	mux.Handle("GET /loglevel", a.authenticate(http.HandlerFunc(a.getLogLevel)))
+	mux.Handle("PUT /loglevel", a.authenticate(http.HandlerFunc(a.putLogLevel)))
+
+	if a.automate {
+		mux.Handle("POST /v1/challenges/present", a.authenticate(a.automation(v1alpha1.ChallengeActionPresent)))
+		mux.Handle("POST /v1/challenges/cleanup", a.authenticate(a.automation(v1alpha1.ChallengeActionCleanUp)))
+	}
+	return mux
+}
+
+//===========================================================================
+// Handlers
+//===========================================================================
+
+func (a *AdminServer) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+func (a *AdminServer) readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := a.solver.Ready(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "ok")
+}
+
+func (a *AdminServer) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"version":  Version(false),
+		"features": a.solver.features.Features(),
+	})
+}
+
+func (a *AdminServer) status(w http.ResponseWriter, r *http.Request) {
+	status := map[string]any{
+		"version":         Version(false),
+		"ready":           false,
+		"singleNamespace": a.solver.singleNamespace,
+	}
+
+	if err := a.solver.Ready(); err == nil {
+		status["ready"] = true
+	} else {
+		status["error"] = err.Error()
+	}
+
+	// The namespace is only resolved once the solver has been initialized.
+	if a.solver.initialized.Load() {
+		status["namespace"] = a.solver.PodNamespace()
+
+		inflight, queued := a.solver.admission.depth()
+		status["inflight"] = inflight
+		status["queued"] = queued
+		status["caches"] = cacheStats()
+
+		if stats := a.solver.tracker.Stats(); stats != nil {
+			status["reconcile"] = stats
+		}
+
+		if credentials := a.solver.credentialHealth.Stats(); credentials != nil {
+			status["credentials"] = credentials
+		}
+
+		if zones := a.solver.hygiene.Stats(); zones != nil {
+			status["zones"] = zones
+		}
+
+		if presents := a.solver.presents.Stats(); presents != nil {
+			status["presentCache"] = presents
+		}
+
+		if events := a.solver.cloudEvents.Stats(); events != nil {
+			status["cloudEvents"] = events
+		}
+
+		status["cleanups"] = a.solver.cleanups.Stats()
+		status["settings"] = a.solver.settings()
+		status["consecutiveFailures"] = a.solver.watchdog.consecutiveFailures()
+		status["recentErrors"] = a.solver.history.Entries()
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+//===========================================================================
+// Middleware and Helpers
+//===========================================================================
+
+// Requires the request to carry the configured bearer token or a verified client
+// certificate if either authentication method is configured.
+func (a *AdminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (a.token != "" || a.clientCAs != nil) && !a.authenticated(r) {
+			if a.token != "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="acme-linode"`)
+			}
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *AdminServer) authenticated(r *http.Request) bool {
+	// The TLS handshake only produces verified chains if the client CA was configured.
+	if a.clientCAs != nil && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return true
+	}
+
+	if a.token != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(a.token)) == 1
+		}
+	}
+	return false
+}
+
+// Loads the admin server certificate from disk, reloading it whenever the
+// modification time of the certificate or key file changes.
+type certReloader struct {
+	sync.Mutex
+	certFile string
+	keyFile  string
+	modified time.Time
+	cert     *tls.Certificate
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	var modified time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			if c.cert != nil {
+				// Serve the previous certificate if the files are mid-rotation.
+				return c.cert, nil
+			}
+			return nil, fmt.Errorf("could not stat admin tls file: %w", err)
+		}
+
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+
+	if c.cert == nil || modified.After(c.modified) {
+		cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+		if err != nil {
+			if c.cert != nil {
+				klog.Warningf("could not reload admin tls certificate, serving previous certificate: %v", err)
+				return c.cert, nil
+			}
+			return nil, fmt.Errorf("could not load admin tls certificate: %w", err)
+		}
+
+		klog.V(2).Info("loaded admin tls certificate")
+		c.cert, c.modified = &cert, modified
+	}
+	return c.cert, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		klog.Errorf("could not write admin response: %v", err)
+	}
+}
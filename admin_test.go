@@ -0,0 +1,78 @@
+package acme
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuthentication(t *testing.T) {
+	solver := &LinodeDNSProviderSolver{}
+	admin := &AdminServer{solver: solver, token: "supersecret"}
+
+	srv := httptest.NewServer(admin.routes())
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		header string
+		status int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/readyz", "", http.StatusServiceUnavailable},
+		{"/status", "", http.StatusUnauthorized},
+		{"/status", "Bearer wrong", http.StatusUnauthorized},
+		{"/status", "Basic supersecret", http.StatusUnauthorized},
+		{"/status", "Bearer supersecret", http.StatusOK},
+		{"/version", "", http.StatusUnauthorized},
+		{"/version", "Bearer supersecret", http.StatusOK},
+		{"/metrics", "", http.StatusUnauthorized},
+		{"/metrics", "Bearer supersecret", http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+
+		rep, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", tc.path, err)
+		}
+		rep.Body.Close()
+
+		if rep.StatusCode != tc.status {
+			t.Errorf("expected %s with %q to return %d got %d", tc.path, tc.header, tc.status, rep.StatusCode)
+		}
+	}
+}
+
+func TestAdminClientCertificates(t *testing.T) {
+	admin := &AdminServer{solver: &LinodeDNSProviderSolver{}, clientCAs: x509.NewCertPool()}
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	if admin.authenticated(req) {
+		t.Error("expected plaintext request without a certificate to be unauthenticated")
+	}
+
+	req.TLS = &tls.ConnectionState{}
+	if admin.authenticated(req) {
+		t.Error("expected tls request without a verified certificate to be unauthenticated")
+	}
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{{}}}
+	if !admin.authenticated(req) {
+		t.Error("expected tls request with a verified certificate to be authenticated")
+	}
+
+	// The bearer token is still accepted when client certificates are configured.
+	admin.token = "supersecret"
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Authorization", "Bearer supersecret")
+	if !admin.authenticated(req) {
+		t.Error("expected request with bearer token to be authenticated")
+	}
+}
@@ -0,0 +1,89 @@
+package acme
+
+import (
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const DefaultMaxQueueWait = 10 * time.Second
+
+// Admission control limits the number of challenge operations that are in flight
+// at once and sheds load by returning ErrOverloaded rather than queueing requests
+// indefinitely, so that webhook responses stay within the apiserver timeout during
+// renewal storms. A nil admission admits all operations.
+type admission struct {
+	slots    chan struct{}
+	waiting  atomic.Int64
+	maxQueue int64
+	maxWait  time.Duration
+}
+
+// Creates an admission controller that allows maxInflight concurrent operations
+// with up to maxQueue operations waiting at most maxWait for a slot. A negative
+// maxQueue queues as many operations as are in flight, and a maxQueue of zero sheds
+// every operation that does not get a slot immediately. If maxInflight is not
+// positive then no limits are applied and nil is returned.
+func newAdmission(maxInflight, maxQueue int, maxWait time.Duration) *admission {
+	if maxInflight <= 0 {
+		return nil
+	}
+
+	if maxQueue < 0 {
+		maxQueue = maxInflight
+	}
+
+	if maxWait <= 0 {
+		maxWait = DefaultMaxQueueWait
+	}
+
+	return &admission{
+		slots:    make(chan struct{}, maxInflight),
+		maxQueue: int64(maxQueue),
+		maxWait:  maxWait,
+	}
+}
+
+// Acquires a slot for an operation, returning the function to release it or
+// ErrOverloaded if the queue is full or no slot became available in time.
+func (a *admission) acquire(op string) (release func(), err error) {
+	if a == nil {
+		return func() {}, nil
+	}
+
+	release = func() { <-a.slots }
+
+	// Fast path: a slot is immediately available.
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if waiting := a.waiting.Add(1); waiting > a.maxQueue {
+		a.waiting.Add(-1)
+		klog.Warningf("shedding %s: %d operations in flight and %d queued", op, cap(a.slots), waiting-1)
+		return nil, ErrOverloaded
+	}
+	defer a.waiting.Add(-1)
+
+	timer := time.NewTimer(a.maxWait)
+	defer timer.Stop()
+
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		klog.Warningf("shedding %s: no slot available after waiting %s", op, a.maxWait)
+		return nil, ErrOverloaded
+	}
+}
+
+// Returns the number of operations in flight and waiting for a slot.
+func (a *admission) depth() (inflight, queued int) {
+	if a == nil {
+		return 0, 0
+	}
+	return len(a.slots), int(a.waiting.Load())
+}
@@ -0,0 +1,75 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	// A nil admission controller should admit everything.
+	var unlimited *admission
+	if _, err := unlimited.acquire("test"); err != nil {
+		t.Fatalf("expected unlimited admission, got %v", err)
+	}
+
+	a := newAdmission(2, 1, 50*time.Millisecond)
+	r1, _ := a.acquire("test")
+	r2, _ := a.acquire("test")
+
+	if inflight, _ := a.depth(); inflight != 2 {
+		t.Errorf("expected 2 in flight, got %d", inflight)
+	}
+
+	// The third operation waits in the queue and times out.
+	if _, err := a.acquire("test"); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected queued operation to time out with ErrOverloaded, got %v", err)
+	}
+
+	// Once the queue is full, operations are shed immediately.
+	done := make(chan func())
+	go func() {
+		release, _ := a.acquire("test")
+		done <- release
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	if _, err := a.acquire("test"); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("expected operation to be shed with ErrOverloaded, got %v", err)
+	}
+
+	if time.Since(start) > 10*time.Millisecond {
+		t.Error("expected operation to be shed without waiting")
+	}
+
+	// Releasing a slot admits the queued operation.
+	r1()
+	if r3 := <-done; r3 == nil {
+		t.Error("expected queued operation to be admitted when a slot was released")
+	} else {
+		r3()
+	}
+	r2()
+
+	if inflight, queued := a.depth(); inflight != 0 || queued != 0 {
+		t.Errorf("expected no operations in flight or queued, got %d and %d", inflight, queued)
+	}
+}
+
+func TestAdmissionQueueDefault(t *testing.T) {
+	// By default as many operations may wait as are in flight.
+	if a := newAdmission(3, -1, time.Second); a.maxQueue != 3 {
+		t.Errorf("expected the queue to default to the in flight limit, got %d", a.maxQueue)
+	}
+
+	// Without a queue, operations that do not get a slot are shed immediately.
+	a := newAdmission(1, 0, time.Second)
+	release, _ := a.acquire("test")
+	defer release()
+
+	start := time.Now()
+	if _, err := a.acquire("test"); !errors.Is(err, ErrOverloaded) || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("expected the operation to be shed without waiting, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package acme
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	k8sapiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	AnnotationDomainID = "acme-linode.rtnl.ai/domain-id"
+	AnnotationRecordID = "acme-linode.rtnl.ai/record-id"
+	annotateTimeout    = 10 * time.Second
+)
+
+// The cert-manager Challenge resource that is annotated with the Linode objects.
+var challengeResource = schema.GroupVersionResource{Group: "acme.cert-manager.io", Version: "v1", Resource: "challenges"}
+
+// Annotates Challenge resources with the Linode domain and record IDs used to solve
+// them and emits an Event targeting the Challenge, so that a stuck Challenge can be
+// traced directly to a Linode object. The ChallengeRequest does not identify the
+// Challenge resource, so it is found by its key. Annotation is best effort: missing
+// RBAC permissions are reported once and never fail the challenge.
+type challengeAnnotator struct {
+	k8s       kubernetes.Interface
+	dynamic   dynamic.Interface
+	history   *errorHistory
+	forbidden sync.Once
+}
+
+// Annotates the Challenge for the request with the domain and record IDs; a nil
+// annotator is a no-op.
+func (a *challengeAnnotator) annotate(ch *v1alpha1.ChallengeRequest, domainID, recordID int) {
+	if a == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), annotateTimeout)
+	defer cancel()
+
+	if err := a.annotateChallenge(ctx, ch, domainID, recordID); err != nil {
+		if k8serrors.IsForbidden(err) {
+			a.forbidden.Do(func() {
+				klog.Warningf("not permitted to annotate challenge resources, grant get, list, and patch on challenges.acme.cert-manager.io to enable: %v", err)
+			})
+			return
+		}
+		klog.Warningf("could not annotate challenge for %s: %v", ch.ResolvedFQDN, err)
+		if a.history != nil {
+			a.history.record("annotate", ch.ResolvedZone, ch.ResolvedFQDN, err)
+		}
+	}
+}
+
+func (a *challengeAnnotator) annotateChallenge(ctx context.Context, ch *v1alpha1.ChallengeRequest, domainID, recordID int) (err error) {
+	var challenge *unstructured.Unstructured
+	if challenge, err = a.findChallenge(ctx, ch); err != nil {
+		return err
+	}
+
+	if challenge == nil {
+		klog.V(2).Infof("no challenge resource found for %s", ch.ResolvedFQDN)
+		return nil
+	}
+
+	var patch []byte
+	if patch, err = json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{
+				AnnotationDomainID: strconv.Itoa(domainID),
+				AnnotationRecordID: strconv.Itoa(recordID),
+			},
+		},
+	}); err != nil {
+		return err
+	}
+
+	if _, err = a.dynamic.Resource(challengeResource).Namespace(challenge.GetNamespace()).Patch(ctx, challenge.GetName(), types.MergePatchType, patch, k8smetav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	now := k8smetav1.Now()
+	_, err = a.k8s.CoreV1().Events(challenge.GetNamespace()).Create(ctx, &k8sapiv1.Event{
+		ObjectMeta: k8smetav1.ObjectMeta{
+			GenerateName: challenge.GetName() + ".",
+			Namespace:    challenge.GetNamespace(),
+		},
+		InvolvedObject: k8sapiv1.ObjectReference{
+			APIVersion: challenge.GetAPIVersion(),
+			Kind:       challenge.GetKind(),
+			Name:       challenge.GetName(),
+			Namespace:  challenge.GetNamespace(),
+			UID:        challenge.GetUID(),
+		},
+		Reason:         "Presented",
+		Message:        fmt.Sprintf("Presented TXT record %s as Linode record ID %d in domain ID %d", ch.ResolvedFQDN, recordID, domainID),
+		Type:           k8sapiv1.EventTypeNormal,
+		Source:         k8sapiv1.EventSource{Component: "acme-linode"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}, k8smetav1.CreateOptions{})
+	return err
+}
+
+// Finds the Challenge with the request's key, searching all namespaces and falling
+// back to the resource namespace if the webhook cannot list challenges cluster-wide.
+func (a *challengeAnnotator) findChallenge(ctx context.Context, ch *v1alpha1.ChallengeRequest) (_ *unstructured.Unstructured, err error) {
+	var challenges *unstructured.UnstructuredList
+	if challenges, err = a.dynamic.Resource(challengeResource).Namespace(k8smetav1.NamespaceAll).List(ctx, k8smetav1.ListOptions{}); err != nil {
+		if !k8serrors.IsForbidden(err) || ch.ResourceNamespace == "" {
+			return nil, err
+		}
+
+		if challenges, err = a.dynamic.Resource(challengeResource).Namespace(ch.ResourceNamespace).List(ctx, k8smetav1.ListOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range challenges.Items {
+		if key, _, _ := unstructured.NestedString(challenges.Items[i].Object, "spec", "key"); key == ch.Key {
+			return &challenges.Items[i], nil
+		}
+	}
+	return nil, nil
+}
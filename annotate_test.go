@@ -0,0 +1,52 @@
+package acme
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAnnotateChallenge(t *testing.T) {
+	challenge := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "acme.cert-manager.io/v1",
+		"kind":       "Challenge",
+		"metadata":   map[string]any{"name": "example-1234", "namespace": "tenant"},
+		"spec":       map[string]any{"key": "key1", "dnsName": "example.com"},
+	}}
+
+	scheme := runtime.NewScheme()
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		challengeResource: "ChallengeList",
+	}, challenge)
+	k8s := fake.NewClientset()
+
+	annotator := &challengeAnnotator{k8s: k8s, dynamic: dyn}
+	annotator.annotate(&v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", Key: "key1"}, 1001, 1002)
+
+	ctx := context.Background()
+	obj, err := dyn.Resource(challengeResource).Namespace("tenant").Get(ctx, "example-1234", k8smetav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not get challenge: %v", err)
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations[AnnotationDomainID] != "1001" || annotations[AnnotationRecordID] != "1002" {
+		t.Errorf("unexpected annotations %v", annotations)
+	}
+
+	events, _ := k8s.CoreV1().Events("tenant").List(ctx, k8smetav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].InvolvedObject.Name != "example-1234" {
+		t.Errorf("expected an event targeting the challenge, got %+v", events.Items)
+	}
+
+	// A nil annotator is a no-op.
+	var disabled *challengeAnnotator
+	disabled.annotate(&v1alpha1.ChallengeRequest{Key: "key1"}, 1001, 1002)
+}
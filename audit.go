@@ -0,0 +1,174 @@
+package acme
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"k8s.io/klog/v2"
+)
+
+const (
+	DefaultAuditMaxSize    = 100
+	DefaultAuditMaxBackups = 5
+)
+
+// The audit log path that writes the audit events to stdout instead of a file, e.g. so
+// that a log shipping sidecar or the container runtime collects them; the webhook logs
+// are written to stderr so the streams are not interleaved.
+const AuditStdout = "-"
+
+// The operations recorded in the audit log.
+const (
+	AuditCreateZone   = "create-zone"
+	AuditPresent      = "present"
+	AuditCleanUp      = "cleanup"
+	AuditCreateRecord = "create-record"
+	AuditUpdateRecord = "update-record"
+	AuditDeleteRecord = "delete-record"
+)
+
+// AuditLog writes a JSON-lines record of every challenge operation and DNS mutation
+// to a file that is rotated once it reaches its maximum size, or to stdout, so that
+// the DNS change history can be reviewed after incidents and retained in environments
+// that cannot ship the webhook logs to a central system. A nil AuditLog discards all
+// events.
+type AuditLog struct {
+	sync.Mutex
+	out io.WriteCloser
+	enc *json.Encoder
+}
+
+// AuditEvent is a single line of the audit log.
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	UID       string    `json:"uid,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	Issuer    string    `json:"issuer,omitempty"`
+	FQDN      string    `json:"fqdn,omitempty"`
+	Zone      string    `json:"zone,omitempty"`
+	ZoneID    int       `json:"zoneID,omitempty"`
+	RecordID  int       `json:"recordID,omitempty"`
+	Entry     string    `json:"entry,omitempty"`
+	Value     string    `json:"value,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditCaller identifies the challenge on whose behalf the DNS mutations of a client
+// are made in the audit log.
+type AuditCaller struct {
+	UID       string
+	Namespace string
+	Issuer    string
+	FQDN      string
+}
+
+// NewAuditLog opens the audit log at the path, rotating it when it exceeds maxSize
+// megabytes and keeping at most maxBackups rotated files. If the path is AuditStdout
+// the events are written to stdout, which is never rotated or closed.
+func NewAuditLog(path string, maxSize, maxBackups int) *AuditLog {
+	if path == AuditStdout {
+		out := nopWriteCloser{os.Stdout}
+		return &AuditLog{out: out, enc: json.NewEncoder(out)}
+	}
+
+	out := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		LocalTime:  false,
+		Compress:   true,
+	}
+	return &AuditLog{out: out, enc: json.NewEncoder(out)}
+}
+
+// Record writes the event to the audit log, setting its time and outcome from err.
+func (a *AuditLog) Record(event AuditEvent, err error) {
+	if a == nil {
+		return
+	}
+
+	event.Time = time.Now().UTC()
+	event.Outcome = outcome(err)
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if err := a.enc.Encode(event); err != nil {
+		klog.Errorf("could not write audit event: %v", err)
+	}
+}
+
+// Close the audit log file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	return a.out.Close()
+}
+
+// SetAuditLog records every DNS mutation made by the client in the audit log.
+func (l *Linode) SetAuditLog(audit *AuditLog) *Linode {
+	l.audit = audit
+	return l
+}
+
+// SetAuditCaller records the challenge that the client makes DNS mutations for in
+// their audit events.
+func (l *Linode) SetAuditCaller(caller AuditCaller) *Linode {
+	l.auditCaller = caller
+	return l
+}
+
+// Returns the audit event of a DNS mutation in the zone made by the client, including
+// the name of the zone if the client looked it up.
+func (l *Linode) auditEvent(operation string, zoneID int) AuditEvent {
+	event := AuditEvent{
+		Operation: operation,
+		UID:       l.auditCaller.UID,
+		Namespace: l.auditCaller.Namespace,
+		Issuer:    l.auditCaller.Issuer,
+		FQDN:      l.auditCaller.FQDN,
+		ZoneID:    zoneID,
+	}
+
+	if zone, ok := l.auditZones.Load(zoneID); ok {
+		event.Zone = zone.(string)
+	}
+	return event
+}
+
+// Returns the identity of the challenge in the audit events of its DNS mutations; the
+// issuer is only included if the webhook reads the issuers of challenge resources.
+func (s *LinodeDNSProviderSolver) auditCaller(ch *v1alpha1.ChallengeRequest) AuditCaller {
+	caller := AuditCaller{UID: string(ch.UID), Namespace: ch.ResourceNamespace, FQDN: ch.ResolvedFQDN}
+	if s.auditIssuers && s.challenges != nil {
+		issuer, err := s.challengeIssuer(ch)
+		switch {
+		case err != nil:
+			klog.Warningf("could not find the issuer of the challenge for %s for the audit log: %v", ch.ResolvedFQDN, err)
+		case issuer.Name != "":
+			caller.Issuer = fmt.Sprintf("%s/%s", issuer.Kind, issuer.Name)
+		}
+	}
+	return caller
+}
+
+// Writes to stdout without closing it when the audit log is closed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
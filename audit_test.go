@@ -0,0 +1,116 @@
+package acme
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestAuditLog(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	zone := fake.AddDomain("example.com")
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	solver.audit = NewAuditLog(path, DefaultAuditMaxSize, DefaultAuditMaxBackups)
+
+	// The issuer of the challenge is read from its challenge resource.
+	solver.auditIssuers = true
+	challenge := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "acme.cert-manager.io/v1",
+		"kind":       "Challenge",
+		"metadata":   map[string]any{"name": "example-1234", "namespace": "default"},
+		"spec": map[string]any{
+			"key":       "key1",
+			"issuerRef": map[string]any{"kind": "ClusterIssuer", "name": "letsencrypt"},
+		},
+	}}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		challengeResource: "ChallengeList",
+	}, challenge)
+	solver.challenges = &challengeAnnotator{dynamic: dyn}
+
+	ch := &v1alpha1.ChallengeRequest{
+		UID:               "1234",
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if err := solver.audit.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []AuditEvent
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("could not parse audit event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	expected := []string{AuditCreateRecord, AuditPresent, AuditDeleteRecord, AuditCleanUp}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d audit events got %d: %+v", len(expected), len(events), events)
+	}
+
+	for i, op := range expected {
+		if events[i].Operation != op || events[i].Outcome != "success" {
+			t.Errorf("expected successful %s event got %+v", op, events[i])
+		}
+	}
+
+	if events[0].ZoneID != zone.ID || events[0].Zone != "example.com" || events[0].RecordID == 0 || events[0].Entry != "_acme-challenge" || events[0].Value != "key1" {
+		t.Errorf("expected the mutation to be recorded, got %+v", events[0])
+	}
+
+	// The mutations record the challenge they were made for.
+	for _, event := range []AuditEvent{events[0], events[2]} {
+		if event.UID != "1234" || event.Namespace != "default" || event.Issuer != "ClusterIssuer/letsencrypt" || event.Zone != "example.com" {
+			t.Errorf("expected the caller of the mutation to be recorded, got %+v", event)
+		}
+	}
+
+	if events[1].UID != "1234" || events[1].Namespace != "default" {
+		t.Errorf("expected the challenge to be recorded, got %+v", events[1])
+	}
+}
+
+func TestAuditLogStdout(t *testing.T) {
+	audit := NewAuditLog(AuditStdout, DefaultAuditMaxSize, DefaultAuditMaxBackups)
+	if _, ok := audit.out.(nopWriteCloser); !ok {
+		t.Fatalf("expected the audit log to be written to stdout, got %T", audit.out)
+	}
+
+	// Closing the audit log does not close stdout.
+	if err := audit.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stdout.Stat(); err != nil {
+		t.Errorf("expected stdout to remain open: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package acme
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
+)
+
+// The label that names of records managed through the automation API must start with.
+const AutomationLabel = "_acme-challenge"
+
+// AutomationRequest asks the webhook to present or clean up a challenge TXT record on
+// behalf of external automation such as a CI pipeline or a migration script.
+type AutomationRequest struct {
+	// The fully qualified name of the record, which must start with _acme-challenge.
+	FQDN string `json:"fqdn"`
+
+	// The value of the TXT record.
+	Value string `json:"value"`
+
+	// Optional zone that hosts the record; each parent of the fqdn is tried if unset.
+	Zone string `json:"zone,omitempty"`
+
+	// Optional identifier of the request in the request and audit logs.
+	UID string `json:"uid,omitempty"`
+}
+
+// AutomationResponse reports the outcome of an automation request.
+type AutomationResponse struct {
+	UID     string `json:"uid"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Validates the request and converts it into a challenge request. Automation requests
+// never carry a solver config, so they are solved with the default or routed
+// credentials of the webhook namespace rather than credentials chosen by the caller.
+func (r AutomationRequest) challenge(action v1alpha1.ChallengeAction, namespace string) (*v1alpha1.ChallengeRequest, error) {
+	fqdn := strings.ToLower(strings.TrimSpace(r.FQDN))
+	if fqdn == "" || r.Value == "" {
+		return nil, fmt.Errorf("%w: fqdn and value are required", ErrInvalidConfig)
+	}
+
+	if !strings.HasPrefix(fqdn, AutomationLabel+".") {
+		return nil, fmt.Errorf("%w: fqdn must start with %s", ErrInvalidConfig, AutomationLabel)
+	}
+
+	ch := &v1alpha1.ChallengeRequest{
+		UID:               types.UID(r.UID),
+		Action:            action,
+		Type:              ChallengeTypeDNS01,
+		Key:               r.Value,
+		ResolvedFQDN:      strings.TrimSuffix(fqdn, ".") + ".",
+		ResourceNamespace: namespace,
+	}
+
+	if ch.UID == "" {
+		ch.UID = "automation-" + uuid.NewUUID()
+	}
+
+	if zone := normalizeZone(r.Zone); zone != "" {
+		ch.ResolvedZone = zone + "."
+	}
+	return ch, nil
+}
+
+// Handles POST /v1/challenges/present and /v1/challenges/cleanup by calling Present or
+// CleanUp on the solver, so that automation goes through the same load shedding,
+// record modes, fallback, and request and audit logs as cert-manager challenges.
+func (a *AdminServer) automation(action v1alpha1.ChallengeAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.solver.initialized.Load() {
+			writeJSON(w, http.StatusServiceUnavailable, AutomationResponse{Outcome: outcome(ErrNotInitialized), Error: ErrNotInitialized.Error()})
+			return
+		}
+
+		var req AutomationRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, AutomationResponse{Outcome: outcome(err), Error: "could not parse request: " + err.Error()})
+			return
+		}
+
+		ch, err := req.challenge(action, a.solver.PodNamespace())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, AutomationResponse{UID: req.UID, Outcome: outcome(err), Error: err.Error()})
+			return
+		}
+
+		klog.Infof("automation API %s request for fqdn=%s uid=%s", action, ch.ResolvedFQDN, ch.UID)
+		if action == v1alpha1.ChallengeActionPresent {
+			err = a.solver.Present(ch)
+		} else {
+			err = a.solver.CleanUp(ch)
+		}
+
+		rep := AutomationResponse{UID: string(ch.UID), Outcome: outcome(err)}
+		if err != nil {
+			rep.Error = err.Error()
+		}
+		writeJSON(w, automationStatus(err), rep)
+	}
+}
+
+// Returns the HTTP status code of the automation response for the error.
+func automationStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	switch errorClass(err) {
+	case ErrorClassConfig:
+		return http.StatusBadRequest
+	case ErrorClassNotFound:
+		return http.StatusNotFound
+	case ErrorClassOverloaded:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
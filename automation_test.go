@@ -0,0 +1,63 @@
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAutomationAPI(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.initialized.Store(true)
+	zone := fake.AddDomain("example.com")
+
+	admin := &AdminServer{solver: solver, token: "supersecret", automate: true}
+	srv := httptest.NewServer(admin.routes())
+	defer srv.Close()
+
+	post := func(path, token string, body any) (int, AutomationResponse) {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rep, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		defer rep.Body.Close()
+
+		var out AutomationResponse
+		if err := json.NewDecoder(rep.Body).Decode(&out); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		return rep.StatusCode, out
+	}
+
+	record := AutomationRequest{FQDN: "_acme-challenge.www.example.com", Value: "key1", UID: "ci-1234"}
+	if code, _ := post("/v1/challenges/present", "wrong", record); code != http.StatusUnauthorized {
+		t.Errorf("expected unauthenticated requests to be rejected, got %d", code)
+	}
+
+	code, rep := post("/v1/challenges/present", "supersecret", record)
+	if code != http.StatusOK || rep.Outcome != "success" || rep.UID != "ci-1234" {
+		t.Fatalf("could not present record: %d %+v", code, rep)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www", "key1")
+
+	if code, rep = post("/v1/challenges/cleanup", "supersecret", record); code != http.StatusOK {
+		t.Fatalf("could not clean up record: %d %+v", code, rep)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www")
+
+	// Only challenge records may be managed.
+	if code, _ = post("/v1/challenges/present", "supersecret", AutomationRequest{FQDN: "www.example.com", Value: "v=spf1 -all"}); code != http.StatusBadRequest {
+		t.Errorf("expected records that are not challenges to be rejected, got %d", code)
+	}
+
+	if code, _ = post("/v1/challenges/present", "supersecret", AutomationRequest{FQDN: "_acme-challenge.example.org", Value: "key1"}); code != http.StatusNotFound {
+		t.Errorf("expected zones that are not hosted to be not found, got %d", code)
+	}
+}
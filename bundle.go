@@ -0,0 +1,315 @@
+package acme
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linode/linodego"
+	"github.com/spf13/pflag"
+)
+
+const (
+	DefaultLinodeURL     = "https://api.linode.com"
+	DefaultBundleTimeout = 10 * time.Second
+	redacted             = "REDACTED"
+)
+
+// SupportBundle collects sanitized logs, the effective configuration, version info,
+// the status and recent errors reported by a running webhook, and the results of
+// connectivity and token checks into a single gzipped tar archive that can be
+// attached to support issues. Secrets are redacted from every file in the bundle.
+type SupportBundle struct {
+	AdminURL        string            // base URL of the webhook's admin server (optional)
+	AdminToken      string            // bearer token for the admin server (optional)
+	LinodeToken     string            // linode API token used for the token check (optional)
+	LinodeTransport http.RoundTripper // transport of the token check, see LinodeTransport (optional)
+	LogFiles        []string          // log files to include after sanitizing them
+	Config          map[string]string // effective configuration, see EffectiveConfig
+	Timeout         time.Duration     // timeout for each check
+}
+
+// CheckResult is the outcome of a connectivity or token check in the bundle.
+type CheckResult struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// Write the support bundle archive to the writer.
+func (b *SupportBundle) Write(w io.Writer) (err error) {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	add := func(name string, data []byte) error {
+		data = []byte(b.sanitize(string(data)))
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+			return err
+		}
+		_, err := archive.Write(data)
+		return err
+	}
+
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, append(data, '\n'))
+	}
+
+	if err = addJSON("version.json", map[string]string{
+		"version":  Version(false),
+		"linodego": linodego.Version,
+	}); err != nil {
+		return err
+	}
+
+	if err = addJSON("config.json", b.Config); err != nil {
+		return err
+	}
+
+	if b.AdminURL != "" {
+		status, serr := b.adminStatus()
+		if serr != nil {
+			err = add("status.error.txt", []byte(serr.Error()+"\n"))
+		} else {
+			err = add("status.json", status)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = addJSON("checks.json", b.Checks()); err != nil {
+		return err
+	}
+
+	for _, path := range b.LogFiles {
+		data, rerr := os.ReadFile(path)
+		if rerr != nil {
+			data = []byte(fmt.Sprintf("could not read log file: %v\n", rerr))
+		}
+
+		if err = add("logs/"+filepath.Base(path), data); err != nil {
+			return err
+		}
+	}
+
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Checks runs the connectivity and token checks included in the bundle.
+func (b *SupportBundle) Checks() []CheckResult {
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = DefaultBundleTimeout
+	}
+
+	check := func(name string, skip string, fn func(context.Context) (string, error)) CheckResult {
+		result := CheckResult{Name: name}
+		if skip != "" {
+			result.Skipped, result.Detail = true, skip
+			return result
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		detail, err := fn(ctx)
+		result.Duration = time.Since(start).Round(time.Millisecond).String()
+
+		if err != nil {
+			result.Detail = err.Error()
+			return result
+		}
+
+		result.OK, result.Detail = true, detail
+		return result
+	}
+
+	apiURL := linodeURL()
+	results := []CheckResult{
+		check("linode-api-dns", "", func(ctx context.Context) (string, error) {
+			u, err := url.Parse(apiURL)
+			if err != nil {
+				return "", err
+			}
+
+			addrs, err := net.DefaultResolver.LookupHost(ctx, u.Hostname())
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s resolves to %s", u.Hostname(), strings.Join(addrs, ", ")), nil
+		}),
+	}
+
+	var skipToken string
+	if b.LinodeToken == "" {
+		skipToken = "no linode API token was provided"
+	}
+
+	// The token is checked against the same API endpoint, and with the same transport,
+	// as the webhook, e.g. through an egress proxy that is trusted with --linode-ca-file.
+	var hc *http.Client
+	if b.LinodeTransport != nil {
+		hc = &http.Client{Transport: b.LinodeTransport}
+	}
+
+	results = append(results, check("linode-api-token", skipToken, func(ctx context.Context) (string, error) {
+		domains, err := NewLinodeWithHTTPClient(b.LinodeToken, hc).SetBaseURL(apiURL).client.ListDomains(ctx, linodego.NewListOptions(1, ""))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("token can list domains (%d on the first page)", len(domains)), nil
+	}))
+
+	var skipAdmin string
+	if b.AdminURL == "" {
+		skipAdmin = "no admin server URL was provided"
+	}
+
+	results = append(results, check("admin-readyz", skipAdmin, func(ctx context.Context) (string, error) {
+		body, err := b.adminGet(ctx, "/readyz")
+		return strings.TrimSpace(string(body)), err
+	}))
+	return results
+}
+
+func (b *SupportBundle) adminStatus() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultBundleTimeout)
+	defer cancel()
+	return b.adminGet(ctx, "/status")
+}
+
+func (b *SupportBundle) adminGet(ctx context.Context, path string) (_ []byte, err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(b.AdminURL, "/")+path, nil); err != nil {
+		return nil, err
+	}
+
+	if b.AdminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.AdminToken)
+	}
+
+	var rep *http.Response
+	if rep, err = http.DefaultClient.Do(req); err != nil {
+		return nil, err
+	}
+	defer rep.Body.Close()
+
+	var body []byte
+	if body, err = io.ReadAll(io.LimitReader(rep.Body, 1<<20)); err != nil {
+		return nil, err
+	}
+
+	if rep.StatusCode != http.StatusOK {
+		return body, fmt.Errorf("admin server returned %s: %s", rep.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+var sanitizers = []struct {
+	pattern *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`), "${1}" + redacted},
+	{regexp.MustCompile(`(?i)((?:token|secret|password)["']?\s*[:=]\s*["']?)[^\s"',]+`), "${1}" + redacted},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`), redacted},
+}
+
+// Sanitize redacts bearer tokens, token and secret assignments, and strings that look
+// like Linode API tokens from the text.
+func Sanitize(text string) string {
+	for _, s := range sanitizers {
+		text = s.pattern.ReplaceAllString(text, s.replace)
+	}
+	return text
+}
+
+func (b *SupportBundle) sanitize(text string) string {
+	for _, secret := range []string{b.LinodeToken, b.AdminToken} {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, redacted)
+		}
+	}
+	return Sanitize(text)
+}
+
+// EffectiveConfig returns the value of every flag in the flag set along with the
+// environment variables read by the webhook. The values of environment variables
+// that hold secrets are redacted.
+func EffectiveConfig(fs *pflag.FlagSet) map[string]string {
+	config := make(map[string]string)
+	fs.VisitAll(func(f *pflag.Flag) {
+		config["--"+f.Name] = f.Value.String()
+	})
+
+	for _, env := range os.Environ() {
+		key, val, _ := strings.Cut(env, "=")
+		if !configEnv(key) {
+			continue
+		}
+
+		if secretEnv(key) {
+			val = redacted
+		}
+		config[key] = val
+	}
+	return config
+}
+
+var configEnvPrefixes = []string{"LINODE_", "ADMIN_", "KUBE_API_", "GROUP_NAME", "POD_NAMESPACE", "SINGLE_NAMESPACE", "MAX_", "CACHE_", "TRACK_", "RECONCILE_", "DEDUPE_", "PURGE_", "ZONE_", "CREDENTIAL_", "PRESENT_CACHE_", "PREWARM_", "ANNOTATE_", "ROUTES_", "SOLVERS_", "POLICY_", "FALLBACK_", "RFC2136_", "FAILURE_", "POD_NAME", "NAMESPACE_FILE", "RECORD_", "MIN_RECORD_AGE", "NAMESPACE_CREDENTIALS", "CHECK_DELEGATION", "CONFIRM_EVENTS", "AUDIT_", "CLOUDEVENTS_", "SETTINGS_FILE", "FEATURE_GATES", "QUORUM"}
+
+func configEnv(key string) bool {
+	for _, prefix := range configEnvPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Environment variables hold secrets if they are named for a token or secret, unless
+// they only refer to a file or secret resource holding it.
+func secretEnv(key string) bool {
+	if !strings.Contains(key, "TOKEN") && !strings.Contains(key, "SECRET") {
+		return false
+	}
+
+	for _, suffix := range []string{"_FILE", "_NAME", "_KEY"} {
+		if strings.HasSuffix(key, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the base URL of the Linode API, which is overridden by LINODE_API_URL or
+// LINODE_URL.
+func linodeURL() string {
+	for _, env := range []string{"LINODE_API_URL", "LINODE_URL"} {
+		if u := strings.TrimSpace(os.Getenv(env)); u != "" {
+			return u
+		}
+	}
+	return DefaultLinodeURL
+}
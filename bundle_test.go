@@ -0,0 +1,132 @@
+package acme_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/pflag"
+	acme "go.rtnl.ai/acme-linode"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"Authorization: Bearer abc.def", "Authorization: Bearer REDACTED"},
+		{`{"token": "s3cret", "name": "linode"}`, `{"token": "REDACTED", "name": "linode"}`},
+		{"password=hunter2 user=admin", "password=REDACTED user=admin"},
+		{"key " + strings.Repeat("ab", 32) + " end", "key REDACTED end"},
+		{"nothing to see here", "nothing to see here"},
+	}
+
+	for _, tc := range tests {
+		if actual := acme.Sanitize(tc.in); actual != tc.expected {
+			t.Errorf("expected %q got %q", tc.expected, actual)
+		}
+	}
+}
+
+func TestSupportBundle(t *testing.T) {
+	t.Setenv("LINODE_TOKEN", "supersecret")
+	t.Setenv("LINODE_TOKEN_SECRET_NAME", "linode-credentials")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	(&acme.LinodeDNSProviderSolver{}).AddFlags(fs)
+
+	config := acme.EffectiveConfig(fs)
+	if config["LINODE_TOKEN"] != "REDACTED" {
+		t.Errorf("expected LINODE_TOKEN to be redacted, got %q", config["LINODE_TOKEN"])
+	}
+
+	if config["LINODE_TOKEN_SECRET_NAME"] != "linode-credentials" {
+		t.Errorf("expected secret name to be reported, got %q", config["LINODE_TOKEN_SECRET_NAME"])
+	}
+
+	if _, ok := config["--single-namespace"]; !ok {
+		t.Error("expected solver flags to be reported")
+	}
+
+	bundle := &acme.SupportBundle{Config: config}
+	buf := &bytes.Buffer{}
+	if err := bundle.Write(buf); err != nil {
+		t.Fatalf("could not write bundle: %v", err)
+	}
+
+	gz, err := gzip.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	archive := tar.NewReader(gz)
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := io.ReadAll(archive)
+		files[hdr.Name] = string(data)
+	}
+
+	for _, name := range []string{"version.json", "config.json", "checks.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in the bundle", name)
+		}
+	}
+
+	if strings.Contains(files["config.json"], "supersecret") {
+		t.Error("expected the linode token to be redacted from the bundle")
+	}
+}
+
+// Counts the requests sent through the transport.
+type countingTransport struct {
+	http.RoundTripper
+	requests atomic.Int64
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return t.RoundTripper.RoundTrip(req)
+}
+
+func TestSupportBundleTokenCheck(t *testing.T) {
+	fake := linodetest.New()
+	defer fake.Close()
+	t.Setenv("LINODE_URL", fake.URL())
+	fake.AddDomain("example.com")
+
+	// The token is checked against the configured API with the configured transport.
+	transport := &countingTransport{RoundTripper: fake.Client().Transport}
+	bundle := &acme.SupportBundle{LinodeToken: "linodetest", LinodeTransport: transport}
+
+	var checked bool
+	for _, result := range bundle.Checks() {
+		if result.Name == "linode-api-token" {
+			checked = true
+			if !result.OK {
+				t.Errorf("expected the token check to pass, got %+v", result)
+			}
+		}
+	}
+
+	if !checked {
+		t.Error("expected the token to be checked")
+	}
+
+	if n := transport.requests.Load(); n == 0 {
+		t.Error("expected the token check to use the linode transport")
+	}
+}
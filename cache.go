@@ -0,0 +1,241 @@
+package acme
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+)
+
+const DefaultCacheSize = 4096
+
+// Every bounded cache registers itself here, keyed by its solver and name, so that its
+// size can be reported by the admin server and resized from the command line.
+var caches sync.Map
+
+// Caches of solver instances have the name of their solver; caches that are shared by
+// every solver have no solver.
+type cacheKey struct {
+	solver string
+	name   string
+}
+
+// CacheStats reports the size and eviction count of a bounded in-memory cache.
+type CacheStats struct {
+	Solver    string `json:"solver,omitempty"`
+	Name      string `json:"name"`
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type boundedCache interface {
+	Stats() CacheStats
+	Resize(capacity int)
+}
+
+// A bounded cache with least recently used eviction so that the memory used by the
+// webhook is predictable even when it serves thousands of zones. If pinned is set,
+// entries for which it returns true are not evicted (e.g. locks that are held); in
+// that case the cache may temporarily exceed its capacity. If evicted is set, it is
+// called with every evicted entry while the cache is locked.
+type lru[K comparable, V any] struct {
+	sync.Mutex
+	solver    string
+	name      string
+	capacity  int
+	evictions uint64
+	items     map[K]*list.Element
+	order     *list.List
+	pinned    func(V) bool
+	evicted   func(K, V)
+}
+
+type lruEntry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// Creates a new LRU cache with the specified capacity (DefaultCacheSize if not
+// positive) and registers it by the name of its solver and its name for reporting.
+func newLRU[K comparable, V any](solver, name string, capacity int, pinned func(V) bool) *lru[K, V] {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+
+	c := &lru[K, V]{
+		solver:   solver,
+		name:     name,
+		capacity: capacity,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+		pinned:   pinned,
+	}
+
+	caches.Store(cacheKey{solver: solver, name: name}, c)
+	return c
+}
+
+// Returns the cached value for the key and marks it as recently used.
+func (c *lru[K, V]) Get(key K) (val V, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	return c.get(key)
+}
+
+// Adds or replaces the value for the key, evicting the least recently used entries
+// if the cache is over capacity.
+func (c *lru[K, V]) Add(key K, val V) {
+	c.Lock()
+	defer c.Unlock()
+	c.add(key, val)
+}
+
+// Returns the cached value for the key, calling create to add it if it is missing.
+// The create function is called with the cache lock held so it must not block.
+func (c *lru[K, V]) GetOrAdd(key K, create func() V) V {
+	c.Lock()
+	defer c.Unlock()
+
+	if val, ok := c.get(key); ok {
+		return val
+	}
+
+	val := create()
+	c.add(key, val)
+	return val
+}
+
+// Removes the key from the cache.
+func (c *lru[K, V]) Remove(key K) {
+	c.Lock()
+	defer c.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// Removes the entries for which match returns true, returning the number removed.
+func (c *lru[K, V]) RemoveFunc(match func(K, V) bool) (removed int) {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, elem := range c.items {
+		if match(key, elem.Value.(*lruEntry[K, V]).val) {
+			c.order.Remove(elem)
+			delete(c.items, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Returns the values in the cache from the most to the least recently used.
+func (c *lru[K, V]) Values() []V {
+	c.Lock()
+	defer c.Unlock()
+
+	vals := make([]V, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		vals = append(vals, elem.Value.(*lruEntry[K, V]).val)
+	}
+	return vals
+}
+
+// Returns the number of entries in the cache.
+func (c *lru[K, V]) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.order.Len()
+}
+
+func (c *lru[K, V]) Stats() CacheStats {
+	c.Lock()
+	defer c.Unlock()
+
+	return CacheStats{
+		Solver:    c.solver,
+		Name:      c.name,
+		Size:      c.order.Len(),
+		Capacity:  c.capacity,
+		Evictions: c.evictions,
+	}
+}
+
+// Changes the capacity of the cache, evicting entries if it is now over capacity.
+func (c *lru[K, V]) Resize(capacity int) {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+func (c *lru[K, V]) get(key K) (val V, ok bool) {
+	var elem *list.Element
+	if elem, ok = c.items[key]; !ok {
+		return val, false
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).val, true
+}
+
+func (c *lru[K, V]) add(key K, val V) {
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry[K, V]).val = val
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry[K, V]{key: key, val: val})
+	c.evict()
+}
+
+// Evicts the least recently used entries that are not pinned until the cache is
+// within its capacity.
+func (c *lru[K, V]) evict() {
+	for elem := c.order.Back(); elem != nil && c.order.Len() > c.capacity; {
+		prev := elem.Prev()
+		entry := elem.Value.(*lruEntry[K, V])
+		if c.pinned == nil || !c.pinned(entry.val) {
+			c.order.Remove(elem)
+			delete(c.items, entry.key)
+			c.evictions++
+
+			if c.evicted != nil {
+				c.evicted(entry.key, entry.val)
+			}
+		}
+		elem = prev
+	}
+}
+
+// Resizes every registered cache to the specified capacity.
+func resizeCaches(capacity int) {
+	caches.Range(func(_, c any) bool {
+		c.(boundedCache).Resize(capacity)
+		return true
+	})
+}
+
+// Returns the stats of every registered cache sorted by solver and name.
+func cacheStats() []CacheStats {
+	stats := make([]CacheStats, 0)
+	caches.Range(func(_, c any) bool {
+		stats = append(stats, c.(boundedCache).Stats())
+		return true
+	})
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Solver != stats[j].Solver {
+			return stats[i].Solver < stats[j].Solver
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
@@ -0,0 +1,63 @@
+package acme
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	cache := newLRU[string, int]("test", "test", 2, func(v int) bool { return v < 0 })
+	defer caches.Delete(cacheKey{solver: "test", name: "test"})
+
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+
+	// Accessing a marks it as recently used so b is evicted.
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected a=1 got %d (%t)", v, ok)
+	}
+
+	cache.Add("c", 3)
+	if _, ok := cache.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+
+	if stats := cache.Stats(); stats.Size != 2 || stats.Evictions != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// Pinned entries are not evicted, so the unpinned entry is evicted instead.
+	cache.Add("a", -1)
+	cache.Add("c", -3)
+	cache.Add("d", 4)
+	if _, ok := cache.Get("d"); ok {
+		t.Error("expected d to be evicted instead of the pinned entries")
+	}
+
+	// The cache exceeds its capacity if all of its entries are pinned.
+	cache.Resize(1)
+	if n := cache.Len(); n != 2 {
+		t.Errorf("expected only the pinned entries after resize, got %d entries", n)
+	}
+
+	if v := cache.GetOrAdd("e", func() int { return 5 }); v != 5 {
+		t.Errorf("expected e=5 got %d", v)
+	}
+}
+
+func TestZoneLockEviction(t *testing.T) {
+	zoneLocks.Resize(1)
+	defer zoneLocks.Resize(DefaultCacheSize)
+
+	// A held lock must not be evicted when other zones are locked.
+	unlock := lockZone(1)
+	lockZone(2)()
+	lockZone(3)()
+
+	if _, ok := zoneLocks.Get(1); !ok {
+		t.Fatal("expected held zone lock to remain cached")
+	}
+	unlock()
+
+	lockZone(4)()
+	if n := zoneLocks.Len(); n != 1 {
+		t.Errorf("expected released zone locks to be evicted, got %d", n)
+	}
+}
@@ -0,0 +1,105 @@
+package acme
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// Proxy tokens of child accounts are replaced this long before they expire so that a
+// challenge is never solved with a token that expires while it is in flight.
+const childTokenRefresh = 2 * time.Minute
+
+// Caches the short-lived proxy tokens that a parent account creates to manage the
+// domains of its Akamai child accounts, keyed by the account key of the parent token
+// and the EUUID of the child account, so that a proxy token is not created for every
+// challenge.
+type childTokenCache struct {
+	tokens *lru[string, linodego.Token]
+}
+
+func newChildTokenCache(solver string) *childTokenCache {
+	return &childTokenCache{tokens: newLRU[string, linodego.Token](solver, "childAccountTokens", DefaultCacheSize, nil)}
+}
+
+// Returns the cached proxy token if it does not expire within the refresh period; a
+// nil cache never has a token.
+func (c *childTokenCache) get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	token, ok := c.tokens.Get(key)
+	if !ok || (token.Expiry != nil && time.Until(*token.Expiry) < childTokenRefresh) {
+		return "", false
+	}
+	return token.Token, true
+}
+
+// Caches the proxy token; a nil cache is a no-op.
+func (c *childTokenCache) add(key string, token linodego.Token) {
+	if c == nil {
+		return
+	}
+	c.tokens.Add(key, token)
+}
+
+// Forgets the cached proxy tokens, e.g. if the Linode API rejected one of them; a nil
+// cache is a no-op.
+func (c *childTokenCache) reset() {
+	if c == nil {
+		return
+	}
+	c.tokens.RemoveFunc(func(string, linodego.Token) bool { return true })
+}
+
+// CreateChildAccountToken creates a short-lived proxy token with which the parent
+// account manages the child account with the EUUID. The token of the client must be
+// a token of the parent account with the child_account:read_write scope.
+func (l *Linode) CreateChildAccountToken(euuid string) (token *linodego.Token, err error) {
+	ctx, cancel := l.writeContext()
+	defer cancel()
+
+	if token, err = l.client.CreateChildAccountToken(ctx, euuid); err != nil {
+		switch {
+		case linodego.IsNotFound(err):
+			return nil, fmt.Errorf("%w: child account %q is not a child of the account of the token", ErrInvalidConfig, euuid)
+		case linodego.ErrHasStatus(err, http.StatusForbidden):
+			return nil, fmt.Errorf("%w: creating a token for child account %q requires a parent account token with the child_account:read_write scope", ErrForbidden, euuid)
+		}
+		return nil, apiError(err)
+	}
+	return token, nil
+}
+
+// Returns the proxy token of the child account of the config, creating one with the
+// parent account token if there is no cached token that is still valid.
+func (s *LinodeDNSProviderSolver) childAccountToken(apiKey string, cfg LinodeDNSProviderConfig) (_ string, err error) {
+	key := accountKey(apiKey) + "/" + cfg.ChildAccount
+	if token, ok := s.childTokens.get(key); ok {
+		return token, nil
+	}
+
+	var token *linodego.Token
+	if token, err = NewLinodeWithHTTPClient(apiKey, s.linodeHTTPClient(apiKey)).
+		SetRetryPolicy(s.retryPolicy).
+		SetTimeout(cfg.ReadTimeout()).
+		SetWriteTimeout(cfg.WriteTimeout()).
+		SetBaseURL(cfg.APIURL).
+		SetContext(s.ctx).
+		SetDebug(s.linodeDebug).
+		SetUserAgentSuffix(s.userAgentSuffix).
+		CreateChildAccountToken(cfg.ChildAccount); err != nil {
+		return "", err
+	}
+
+	if token.Expiry != nil {
+		klog.V(2).Infof("created a proxy token for child account %s that expires at %s", cfg.ChildAccount, token.Expiry.Format(time.RFC3339))
+	}
+
+	s.childTokens.add(key, *token)
+	return token.Token, nil
+}
@@ -0,0 +1,114 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestChildAccount(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.childTokens = newChildTokenCache("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "childAccountTokens"})
+
+	zone := fake.AddDomain("example.com")
+	fake.AddChildAccount("A1BC2DEF-34GH")
+
+	// Record the token that authenticates each request and reject the revoked token.
+	var (
+		mu      sync.Mutex
+		tokens  = make(map[string]string)
+		revoked string
+	)
+	solver.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		tokens[req.Method+" "+req.URL.Path] = token
+		rejected := revoked != "" && token == revoked
+		mu.Unlock()
+
+		if rejected {
+			body := `{"errors": [{"reason": "Invalid Token"}]}`
+			return &http.Response{
+				StatusCode: http.StatusUnauthorized,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Request:    req,
+			}, nil
+		}
+		return fake.Client().Transport.RoundTrip(req)
+	})})
+
+	present := func(key, childAccount string) error {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge." + key + ".example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: key}
+		ch.Config = &extapi.JSON{Raw: []byte(`{"apiKeySecretRef": {"name": "linode-credentials", "key": "token"}, "childAccount": "` + childAccount + `"}`)}
+		return solver.Present(ch)
+	}
+
+	for _, key := range []string{"key1", "key2"} {
+		if err := present(key, "A1BC2DEF-34GH"); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	// The parent token creates a single proxy token, which manages the child account.
+	if calls := fake.Calls("POST /v4/account/child-accounts/{euuid}/token"); calls != 1 {
+		t.Errorf("expected the proxy token to be cached, got %d token requests", calls)
+	}
+
+	if token := tokens["POST /v4/account/child-accounts/A1BC2DEF-34GH/token"]; token != "linodetest" {
+		t.Errorf("expected the proxy token to be created with the parent token, got %q", token)
+	}
+
+	if token := tokens[fmt.Sprintf("POST /v4/domains/%d/records", zone.ID)]; !strings.HasPrefix(token, "proxy-A1BC2DEF-34GH-") {
+		t.Errorf("expected the records to be created with the proxy token, got %q", token)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 2 {
+		t.Errorf("expected 2 records, got %+v", records)
+	}
+
+	// A cached proxy token that the Linode API rejects is replaced by the retry.
+	mu.Lock()
+	revoked = tokens[fmt.Sprintf("POST /v4/domains/%d/records", zone.ID)]
+	mu.Unlock()
+
+	if err := present("key5", "A1BC2DEF-34GH"); err != nil {
+		t.Fatalf("could not present challenge with a revoked proxy token: %v", err)
+	}
+
+	if calls := fake.Calls("POST /v4/account/child-accounts/{euuid}/token"); calls != 2 {
+		t.Errorf("expected a new proxy token after the revoked token was rejected, got %d token requests", calls)
+	}
+
+	if token := tokens[fmt.Sprintf("POST /v4/domains/%d/records", zone.ID)]; token == revoked || !strings.HasPrefix(token, "proxy-A1BC2DEF-34GH-") {
+		t.Errorf("expected the record to be created with a new proxy token, got %q", token)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 3 {
+		t.Errorf("expected 3 records, got %+v", records)
+	}
+
+	// Child accounts that the parent account does not have are reported.
+	if err := present("key3", "UNKNOWN"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected an invalid config error for an unknown child account, got %v", err)
+	}
+
+	// The default token of the webhook may not be used for child accounts of the issuer.
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.key4.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key4"}
+	ch.Config = &extapi.JSON{Raw: []byte(`{"childAccount": "A1BC2DEF-34GH"}`)}
+	if err := solver.Present(ch); !errors.Is(err, ErrChildAccountNotAllowed) {
+		t.Errorf("expected ErrChildAccountNotAllowed, got %v", err)
+	}
+
+	if _, err := LoadConfig(&extapi.JSON{Raw: []byte(`{"childAccount": "../profile"}`)}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected an invalid child account to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package acme
+
+import (
+	"sync/atomic"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+// Counts the CleanUp requests that deleted a challenge record separately from those
+// that found nothing to delete, so that patterns that are otherwise invisible, such as
+// records removed by other tools or challenges cleaned up twice, can be spotted. The
+// zero value is ready to use.
+type cleanupCounters struct {
+	deleted atomic.Uint64
+	noop    atomic.Uint64
+}
+
+// CleanupStats reports the number of CleanUp requests by their result.
+type CleanupStats struct {
+	Deleted uint64 `json:"deleted"`
+	NoOp    uint64 `json:"noop"`
+}
+
+func (c *cleanupCounters) observe(ch *v1alpha1.ChallengeRequest, deleted bool) {
+	if deleted {
+		c.deleted.Add(1)
+		return
+	}
+
+	c.noop.Add(1)
+	klog.Infof("no challenge record to clean up for fqdn=%s (already deleted or never presented)", ch.ResolvedFQDN)
+}
+
+// Stats returns the current cleanup counts.
+func (c *cleanupCounters) Stats() CleanupStats {
+	return CleanupStats{Deleted: c.deleted.Load(), NoOp: c.noop.Load()}
+}
@@ -0,0 +1,187 @@
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// Pools the http clients of the Linode API tokens used by the webhook so that the
+// connections and TLS sessions of a token are reused across challenges, even though a
+// new Linode client is created for every challenge. Clients are keyed by the account
+// key of the token, never the token itself. When the token held by a secret changes
+// (e.g. it is rotated) the client of the previous token is evicted and its idle
+// connections are closed.
+type clientPool struct {
+	transport *http.Transport
+	clients   *lru[string, *http.Client]
+	secrets   *lru[string, string]
+}
+
+// Creates a pool of clients with their own clone of the transport; a nil transport
+// uses the default transport.
+func newClientPool(solver string, transport *http.Transport) *clientPool {
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+
+	return &clientPool{
+		transport: transport,
+		clients:   newLRU[string, *http.Client](solver, "linodeClients", DefaultCacheSize, nil),
+		secrets:   newLRU[string, string](solver, "linodeClientSecrets", DefaultCacheSize, nil),
+	}
+}
+
+// LinodeTransport returns the transport of the linode API requests configured by the
+// --linode-ca-file and linode transport flags, e.g. for the checks of a support bundle.
+func (s *LinodeDNSProviderSolver) LinodeTransport() (http.RoundTripper, error) {
+	transport, err := linodeTransport(s.transportOptions)
+	if err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+// Tuning of the transport of the pooled clients; zero values keep the defaults of
+// http.DefaultTransport. The TLS version is "1.2" (the default) or "1.3", and the IP
+// family is "ipv4" or "ipv6" to only dial addresses of that family, or empty for both.
+type transportOptions struct {
+	caFile              string
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	dialTimeout         time.Duration
+	tlsMinVersion       string
+	ipFamily            string
+}
+
+var (
+	tlsVersions = map[string]uint16{"": tls.VersionTLS12, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+	ipFamilies  = map[string]string{"": "", "ipv4": "4", "ipv6": "6"}
+)
+
+// Returns the transport of the pooled clients, which is the default transport (so
+// requests are sent through the proxy of $HTTPS_PROXY unless the Linode API is
+// excluded by $NO_PROXY) tuned by the options. If a CA file is set, the transport
+// also trusts the certificates of the PEM bundle, e.g. of a TLS-intercepting proxy,
+// in addition to the system roots.
+func linodeTransport(opts transportOptions) (_ *http.Transport, err error) {
+	if opts.maxIdleConns < 0 || opts.maxIdleConnsPerHost < 0 || opts.idleConnTimeout < 0 || opts.dialTimeout < 0 {
+		return nil, fmt.Errorf("%w: the linode transport limits and timeouts may not be negative", ErrInvalidConfig)
+	}
+
+	minVersion, ok := tlsVersions[opts.tlsMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w: the minimum TLS version must be 1.2 or 1.3, not %q", ErrInvalidConfig, opts.tlsMinVersion)
+	}
+
+	family, ok := ipFamilies[strings.ToLower(opts.ipFamily)]
+	if !ok {
+		return nil, fmt.Errorf("%w: the IP family must be ipv4 or ipv6, not %q", ErrInvalidConfig, opts.ipFamily)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: minVersion}
+
+	if opts.maxIdleConns > 0 {
+		transport.MaxIdleConns = opts.maxIdleConns
+	}
+
+	if opts.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.maxIdleConnsPerHost
+	}
+
+	if opts.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.idleConnTimeout
+	}
+
+	// The default dialer is replaced to bound the dial or restrict the address family,
+	// since Happy Eyeballs may choose a family with broken egress, e.g. partial IPv6.
+	if opts.dialTimeout > 0 || family != "" {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if opts.dialTimeout > 0 {
+			dialer.Timeout = opts.dialTimeout
+		}
+
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if network == "tcp" {
+				network += family
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+
+	if opts.caFile == "" {
+		return transport, nil
+	}
+
+	var bundle []byte
+	if bundle, err = os.ReadFile(opts.caFile); err != nil {
+		return nil, fmt.Errorf("could not read linode CA file: %w", err)
+	}
+
+	var roots *x509.CertPool
+	if roots, err = x509.SystemCertPool(); err != nil {
+		klog.Warningf("could not load the system CA certificates, only trusting %s: %v", opts.caFile, err)
+		roots = x509.NewCertPool()
+	}
+
+	if !roots.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%w: no CA certificates found in %s", ErrInvalidConfig, opts.caFile)
+	}
+
+	transport.TLSClientConfig.RootCAs = roots
+	return transport, nil
+}
+
+// Returns the pooled http client of the token, creating it with its own transport if
+// there is none; a nil pool returns nil so that the default client is used.
+func (p *clientPool) get(apiKey string) *http.Client {
+	if p == nil {
+		return nil
+	}
+
+	return p.clients.GetOrAdd(accountKey(apiKey), func() *http.Client {
+		return &http.Client{Transport: p.transport.Clone()}
+	})
+}
+
+// Returns the http client of the Linode API requests made with the token, which is the
+// provided http client if set or else the pooled client of the token.
+func (s *LinodeDNSProviderSolver) linodeHTTPClient(apiKey string) *http.Client {
+	if s.httpClient != nil {
+		return s.httpClient
+	}
+	return s.clients.get(apiKey)
+}
+
+// Records the token read from the secret, evicting the client of the token that the
+// secret held before if it has changed; a nil pool is a no-op.
+func (p *clientPool) observe(ref cmmeta.SecretKeySelector, namespace, apiKey string) {
+	if p == nil {
+		return
+	}
+
+	secret := fmt.Sprintf("%s/%s[%s]", namespace, ref.LocalObjectReference.Name, ref.Key)
+	account := accountKey(apiKey)
+	previous, ok := p.secrets.Get(secret)
+	p.secrets.Add(secret, account)
+	if !ok || previous == account {
+		return
+	}
+
+	if hc, ok := p.clients.Get(previous); ok {
+		klog.V(2).Infof("the linode API token in secret %s has changed, closing the connections of the previous token", secret)
+		p.clients.Remove(previous)
+		hc.CloseIdleConnections()
+	}
+}
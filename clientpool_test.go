@@ -0,0 +1,186 @@
+package acme
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	k8sapiv1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClientPool(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.clients = newClientPool("test", nil)
+	defer caches.Delete(cacheKey{solver: "test", name: "linodeClients"})
+	defer caches.Delete(cacheKey{solver: "test", name: "linodeClientSecrets"})
+	fake.AddDomain("example.com")
+
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	for range 3 {
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	// Every challenge with the token shares one pooled client.
+	if n := solver.clients.clients.Len(); n != 1 {
+		t.Fatalf("expected 1 pooled client, got %d", n)
+	}
+	pooled := solver.clients.get("linodetest")
+
+	// Rotating the token in the secret evicts the client of the previous token.
+	secret := &k8sapiv1.Secret{
+		ObjectMeta: k8smetav1.ObjectMeta{Name: "linode-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("rotated")},
+	}
+	if _, err := solver.k8s.CoreV1().Secrets("default").Update(solver.ctx, secret, k8smetav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if _, ok := solver.clients.clients.Get(accountKey("linodetest")); ok {
+		t.Error("expected the client of the rotated token to be evicted")
+	}
+
+	if rotated := solver.clients.get("rotated"); rotated == pooled || solver.clients.clients.Len() != 1 {
+		t.Errorf("expected a new client for the rotated token")
+	}
+}
+
+func TestLinodeTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(transport *http.Transport) error {
+		rep, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			rep.Body.Close()
+		}
+		return err
+	}
+
+	// Without the CA file the certificate of the proxy is not trusted.
+	transport, err := linodeTransport(transportOptions{})
+	if err != nil {
+		t.Fatalf("could not create transport: %v", err)
+	}
+
+	if transport.Proxy == nil {
+		t.Error("expected the transport to use the proxy of the environment")
+	}
+
+	if err := get(transport); err == nil {
+		t.Error("expected the certificate not to be trusted without the CA file")
+	}
+
+	if transport, err = linodeTransport(transportOptions{caFile: caFile}); err != nil {
+		t.Fatalf("could not create transport: %v", err)
+	}
+
+	if err := get(transport); err != nil {
+		t.Errorf("expected the certificate to be trusted with the CA file: %v", err)
+	}
+
+	// The pooled clients use the transport.
+	pool := newClientPool("test", transport)
+	defer caches.Delete(cacheKey{solver: "test", name: "linodeClients"})
+	defer caches.Delete(cacheKey{solver: "test", name: "linodeClientSecrets"})
+	if err := get(pool.get("token").Transport.(*http.Transport)); err != nil {
+		t.Errorf("expected the pooled client to trust the CA file: %v", err)
+	}
+
+	// Files without certificates are rejected.
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := linodeTransport(transportOptions{caFile: empty}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected an invalid config error, got %v", err)
+	}
+
+	if _, err := linodeTransport(transportOptions{caFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
+
+func TestLinodeTransportTuning(t *testing.T) {
+	transport, err := linodeTransport(transportOptions{maxIdleConns: 10, maxIdleConnsPerHost: 5, idleConnTimeout: time.Minute, dialTimeout: 5 * time.Second, tlsMinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("could not create transport: %v", err)
+	}
+
+	if transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 5 || transport.IdleConnTimeout != time.Minute || transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("unexpected transport tuning %d %d %s %x", transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, transport.TLSClientConfig.MinVersion)
+	}
+
+	// Zero values keep the defaults, with TLS 1.2 as the minimum version.
+	if transport, err = linodeTransport(transportOptions{}); err != nil {
+		t.Fatalf("could not create transport: %v", err)
+	}
+
+	defaults := http.DefaultTransport.(*http.Transport)
+	if transport.MaxIdleConns != defaults.MaxIdleConns || transport.IdleConnTimeout != defaults.IdleConnTimeout || transport.TLSClientConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("expected the default transport tuning, got %d %s %x", transport.MaxIdleConns, transport.IdleConnTimeout, transport.TLSClientConfig.MinVersion)
+	}
+
+	for _, opts := range []transportOptions{{tlsMinVersion: "1.1"}, {maxIdleConns: -1}, {dialTimeout: -time.Second}} {
+		if _, err := linodeTransport(opts); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected an invalid config error for %+v, got %v", opts, err)
+		}
+	}
+}
+
+func TestLinodeTransportIPFamily(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// The test server only listens on an IPv4 loopback address.
+	get := func(family string) error {
+		transport, err := linodeTransport(transportOptions{ipFamily: family})
+		if err != nil {
+			t.Fatalf("could not create transport: %v", err)
+		}
+
+		rep, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			rep.Body.Close()
+		}
+		return err
+	}
+
+	for _, family := range []string{"", "ipv4", "IPv4"} {
+		if err := get(family); err != nil {
+			t.Errorf("expected %q to connect over ipv4: %v", family, err)
+		}
+	}
+
+	if err := get("ipv6"); err == nil {
+		t.Error("expected ipv6 not to connect to an ipv4 address")
+	}
+
+	if _, err := linodeTransport(transportOptions{ipFamily: "ipx"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected an invalid config error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/klog/v2"
+)
+
+const (
+	DefaultCloudEventsSource = "acme-linode"
+	CloudEventsSpecVersion   = "1.0"
+	cloudEventsQueueSize     = 256
+	cloudEventsTimeout       = 10 * time.Second
+)
+
+// The types of the CloudEvents emitted for the challenge lifecycle.
+const (
+	CloudEventPresented = "ai.rtnl.acme-linode.challenge.presented"
+	CloudEventVerified  = "ai.rtnl.acme-linode.challenge.verified"
+	CloudEventCleanedUp = "ai.rtnl.acme-linode.challenge.cleanedup"
+)
+
+// CloudEvent is a CloudEvents 1.0 event in the structured JSON content mode.
+type CloudEvent struct {
+	SpecVersion     string             `json:"specversion"`
+	ID              string             `json:"id"`
+	Source          string             `json:"source"`
+	Type            string             `json:"type"`
+	Subject         string             `json:"subject,omitempty"`
+	Time            time.Time          `json:"time"`
+	DataContentType string             `json:"datacontenttype"`
+	Data            ChallengeEventData `json:"data"`
+}
+
+// ChallengeEventData describes the challenge of a lifecycle event; the key is not
+// included since it is only useful to complete the challenge.
+type ChallengeEventData struct {
+	UID       string `json:"uid,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	FQDN      string `json:"fqdn"`
+	Zone      string `json:"zone,omitempty"`
+}
+
+// The CloudEvents sink posts challenge lifecycle events to an HTTP endpoint such as a
+// Knative broker so that event buses and automation can react to validation activity
+// without scraping logs. Events are delivered in the background and never delay or
+// fail a challenge: if the sink falls behind, events are dropped rather than queued
+// without bound. A nil sink discards all events.
+type cloudEventSink struct {
+	url       string
+	source    string
+	client    *http.Client
+	queue     chan CloudEvent
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// CloudEventStats reports the delivery of the events posted to the CloudEvents sink.
+type CloudEventStats struct {
+	Sink      string `json:"sink"`
+	Delivered uint64 `json:"delivered"`
+	Failed    uint64 `json:"failed"`
+	Dropped   uint64 `json:"dropped"`
+	Queued    int    `json:"queued"`
+}
+
+// Creates a sink that posts events to the url until the stop channel is closed, or
+// returns nil if the url is empty.
+func newCloudEventSink(url, source string, hc *http.Client, stopCh <-chan struct{}) *cloudEventSink {
+	if url == "" {
+		return nil
+	}
+
+	if source == "" {
+		source = DefaultCloudEventsSource
+	}
+
+	if hc == nil {
+		hc = &http.Client{Timeout: cloudEventsTimeout}
+	}
+
+	sink := &cloudEventSink{url: url, source: source, client: hc, queue: make(chan CloudEvent, cloudEventsQueueSize)}
+	go sink.run(stopCh)
+	return sink
+}
+
+// Queues an event of the type for the challenge.
+func (c *cloudEventSink) emit(eventType string, ch *v1alpha1.ChallengeRequest) {
+	if c == nil {
+		return
+	}
+
+	event := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              string(uuid.NewUUID()),
+		Source:          c.source,
+		Type:            eventType,
+		Subject:         ch.ResolvedFQDN,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data: ChallengeEventData{
+			UID:       string(ch.UID),
+			Namespace: ch.ResourceNamespace,
+			FQDN:      ch.ResolvedFQDN,
+			Zone:      ch.ResolvedZone,
+		},
+	}
+
+	select {
+	case c.queue <- event:
+	default:
+		if c.dropped.Add(1) == 1 {
+			klog.Warningf("cloudevents sink %s is not keeping up, dropping events", c.url)
+		}
+	}
+}
+
+func (c *cloudEventSink) run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case event := <-c.queue:
+			if err := c.send(event); err != nil {
+				c.failed.Add(1)
+				klog.Warningf("could not deliver %s cloudevent for %s: %v", event.Type, event.Subject, err)
+				continue
+			}
+			c.delivered.Add(1)
+		}
+	}
+}
+
+// Posts the event in the structured content mode, which any CloudEvents HTTP
+// receiver accepts.
+func (c *cloudEventSink) send(event CloudEvent) (err error) {
+	var body []byte
+	if body, err = json.Marshal(event); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloudEventsTimeout)
+	defer cancel()
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+	req.Header.Set("User-Agent", UserAgent)
+
+	var rep *http.Response
+	if rep, err = c.client.Do(req); err != nil {
+		return err
+	}
+	defer rep.Body.Close()
+
+	if rep.StatusCode < 200 || rep.StatusCode >= 300 {
+		return fmt.Errorf("sink responded %s", rep.Status)
+	}
+	return nil
+}
+
+// Stats returns the delivery counts of the sink; a nil sink returns nil.
+func (c *cloudEventSink) Stats() *CloudEventStats {
+	if c == nil {
+		return nil
+	}
+
+	return &CloudEventStats{
+		Sink:      c.url,
+		Delivered: c.delivered.Load(),
+		Failed:    c.failed.Load(),
+		Dropped:   c.dropped.Load(),
+		Queued:    len(c.queue),
+	}
+}
@@ -0,0 +1,92 @@
+package acme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestCloudEvents(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []CloudEvent
+		bodies []string
+	)
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/cloudevents+json") {
+			t.Errorf("unexpected content type %q", ct)
+		}
+
+		var raw json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			t.Errorf("could not decode event: %v", err)
+		}
+
+		var event CloudEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			t.Errorf("could not decode event: %v", err)
+		}
+
+		mu.Lock()
+		events = append(events, event)
+		bodies = append(bodies, string(raw))
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sink.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	solver, fake := newTestSolver(t)
+	solver.cloudEvents = newCloudEventSink(sink.URL, "", sink.Client(), stop)
+	fake.AddDomain("example.com")
+
+	ch := &v1alpha1.ChallengeRequest{UID: "1234", ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "secret-key"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 2
+	})
+
+	for i, expected := range []string{CloudEventPresented, CloudEventCleanedUp} {
+		event := events[i]
+		if event.SpecVersion != CloudEventsSpecVersion || event.ID == "" || event.Source != DefaultCloudEventsSource || event.Type != expected || event.Subject != ch.ResolvedFQDN {
+			t.Errorf("unexpected event %+v", event)
+		}
+
+		if event.Data.UID != "1234" || event.Data.Namespace != "default" || event.Data.Zone != "example.com." {
+			t.Errorf("unexpected event data %+v", event.Data)
+		}
+
+		if strings.Contains(bodies[i], "secret-key") {
+			t.Errorf("expected the challenge key to be omitted from the event: %s", bodies[i])
+		}
+	}
+
+	eventually(t, func() bool { return solver.cloudEvents.Stats().Delivered == 2 })
+	if stats := solver.cloudEvents.Stats(); stats.Failed != 0 || stats.Dropped != 0 {
+		t.Errorf("unexpected cloudevent stats %+v", stats)
+	}
+
+	// A nil sink discards events.
+	var disabled *cloudEventSink
+	disabled.emit(CloudEventPresented, ch)
+	if disabled.Stats() != nil {
+		t.Error("expected no stats for a disabled sink")
+	}
+}
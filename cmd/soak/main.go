@@ -0,0 +1,228 @@
+// Command soak simulates many concurrent Present/CleanUp challenge cycles against the
+// Linode API (or an in-memory fake of it) and reports throughput, latency
+// percentiles, and API call counts so that operators can size deployments.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+var (
+	cycles      = flag.Int("cycles", 500, "total number of present/cleanup cycles to run")
+	concurrency = flag.Int("concurrency", 50, "number of concurrent challenge cycles")
+	names       = flag.Int("names", 25, "number of distinct challenge names to spread cycles across")
+	zone        = flag.String("zone", "example.com", "the zone to create challenge records in")
+	fake        = flag.Bool("fake", true, "run against an in-memory fake of the Linode API instead of a real account")
+	token       = flag.String("token", os.Getenv("LINODE_TOKEN"), "linode API token to use when not running against the fake")
+)
+
+func main() {
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	if *cycles < 1 || *concurrency < 1 || *names < 1 {
+		return errors.New("cycles, concurrency, and names must be positive")
+	}
+
+	var server *linodetest.Server
+	if *fake {
+		server = linodetest.New()
+		defer server.Close()
+		server.AddDomain(*zone)
+
+		// The linodego client uses the fake as its base URL.
+		os.Setenv("LINODE_URL", server.URL())
+		*token = "soak-fake-token"
+	} else if *token == "" {
+		return errors.New("a linode API token is required to run against a real account (use -token or $LINODE_TOKEN)")
+	} else {
+		// Put the zone back as it was in case any cycles leave records behind.
+		var snapshot *linodetest.ZoneSnapshot
+		client := linodego.NewClient(nil)
+		client.SetToken(*token)
+		if snapshot, err = linodetest.Snapshot(context.Background(), &client, *zone); err != nil {
+			return err
+		}
+
+		defer func() {
+			if rerr := snapshot.Restore(context.Background(), &client); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("could not restore zone %s: %w", *zone, rerr))
+			}
+		}()
+	}
+
+	linode := acme.NewLinode(*token)
+	stats := &Stats{}
+
+	fmt.Printf("running %d cycles with concurrency %d against zone %s (fake=%t)\n", *cycles, *concurrency, *zone, *fake)
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range *concurrency {
+		wg.Go(func() {
+			for i := range work {
+				stats.cycle(linode, i)
+			}
+		})
+	}
+
+	start := time.Now()
+	for i := range *cycles {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	stats.Report(elapsed)
+	if server != nil {
+		reportCalls(server.AllCalls())
+	}
+
+	if n := stats.errors.Load(); n > 0 {
+		return fmt.Errorf("%d of %d cycles failed", n, *cycles)
+	}
+	return nil
+}
+
+// Stats collects the latencies and errors of the soak test operations.
+type Stats struct {
+	sync.Mutex
+	present []time.Duration
+	cleanup []time.Duration
+	errors  atomic.Int64
+}
+
+// Runs a single present/cleanup cycle, mirroring what the solver does for a challenge.
+func (s *Stats) cycle(linode *acme.Linode, i int) {
+	fqdn := fmt.Sprintf("_acme-challenge.soak-%03d.%s.", i%*names, *zone)
+	entry, domain := acme.DomainEntry(fqdn, *zone+".")
+	key := challengeKey()
+
+	start := time.Now()
+	err := present(linode, domain, entry, key)
+	s.observe(&s.present, time.Since(start), err)
+	if err != nil {
+		return
+	}
+
+	start = time.Now()
+	err = cleanup(linode, domain, entry, key)
+	s.observe(&s.cleanup, time.Since(start), err)
+}
+
+func (s *Stats) observe(latencies *[]time.Duration, latency time.Duration, err error) {
+	if err != nil {
+		s.errors.Add(1)
+		fmt.Fprintf(os.Stderr, "cycle failed: %v\n", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	*latencies = append(*latencies, latency)
+}
+
+// Report prints the throughput and latency percentiles of the soak test.
+func (s *Stats) Report(elapsed time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+
+	completed := len(s.cleanup)
+	fmt.Printf("\ncompleted %d cycles in %s (%.2f cycles/sec), %d errors\n\n", completed, elapsed.Round(time.Millisecond), float64(completed)/elapsed.Seconds(), s.errors.Load())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "operation\tcount\tp50\tp90\tp99\tmax")
+	for _, op := range []struct {
+		name      string
+		latencies []time.Duration
+	}{{"present", s.present}, {"cleanup", s.cleanup}} {
+		if len(op.latencies) == 0 {
+			continue
+		}
+
+		slices.Sort(op.latencies)
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", op.name, len(op.latencies),
+			percentile(op.latencies, 50), percentile(op.latencies, 90),
+			percentile(op.latencies, 99), op.latencies[len(op.latencies)-1].Round(time.Microsecond),
+		)
+	}
+	w.Flush()
+}
+
+func reportCalls(calls map[string]int) {
+	routes := make([]string, 0, len(calls))
+	total := 0
+	for route, n := range calls {
+		routes = append(routes, route)
+		total += n
+	}
+	sort.Strings(routes)
+
+	fmt.Printf("\n%d linode API calls\n", total)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, route := range routes {
+		fmt.Fprintf(w, "%s\t%d\n", route, calls[route])
+	}
+	w.Flush()
+}
+
+// Expects the latencies to be sorted.
+func percentile(latencies []time.Duration, p int) time.Duration {
+	idx := (len(latencies)*p + 99) / 100
+	return latencies[max(0, min(idx, len(latencies))-1)].Round(time.Microsecond)
+}
+
+func present(linode *acme.Linode, domain, entry, key string) error {
+	zone, err := linode.FindZone(domain)
+	if err != nil {
+		return err
+	}
+	_, err = linode.EnsureTXT(zone.ID, entry, key)
+	return err
+}
+
+func cleanup(linode *acme.Linode, domain, entry, key string) error {
+	zone, err := linode.FindZone(domain)
+	if err != nil {
+		return err
+	}
+
+	// Only delete the record of the cycle, other cycles may share the name.
+	record, err := linode.FindRecordByValue(zone.ID, entry, key)
+	if err != nil {
+		if errors.Is(err, acme.ErrNoRecord) {
+			return nil
+		}
+		return err
+	}
+	return linode.DeleteRecord(zone.ID, record.ID)
+}
+
+func challengeKey() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(buf), "=")
+}
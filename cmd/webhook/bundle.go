@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.rtnl.ai/acme-linode"
+)
+
+// Creates the support-bundle subcommand, which writes a sanitized debug archive. The
+// effective configuration is reported from the flags of the webhook command, whose
+// defaults are read from the same environment as the running webhook, and the linode
+// API token is checked with the linode transport configured by those flags.
+func supportBundleCommand(flags *pflag.FlagSet, solver *acme.LinodeDNSProviderSolver) *cobra.Command {
+	var (
+		output          string
+		adminURL        string
+		adminTokenFile  string
+		linodeTokenFile string
+		logFiles        []string
+		timeout         time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "collect a sanitized debug archive to attach to support issues",
+		RunE: func(c *cobra.Command, args []string) (err error) {
+			bundle := &acme.SupportBundle{
+				AdminURL:    adminURL,
+				LinodeToken: strings.TrimSpace(os.Getenv("LINODE_TOKEN")),
+				LogFiles:    logFiles,
+				Config:      acme.EffectiveConfig(flags),
+				Timeout:     timeout,
+			}
+
+			if bundle.LinodeTransport, err = solver.LinodeTransport(); err != nil {
+				return err
+			}
+
+			if bundle.AdminToken, err = readTokenFile(adminTokenFile); err != nil {
+				return err
+			}
+
+			if linodeTokenFile != "" {
+				if bundle.LinodeToken, err = readTokenFile(linodeTokenFile); err != nil {
+					return err
+				}
+			}
+
+			if output == "" {
+				output = fmt.Sprintf("acme-linode-support-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+			}
+
+			var f *os.File
+			if f, err = os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600); err != nil {
+				return err
+			}
+
+			if err = bundle.Write(f); err != nil {
+				f.Close()
+				return err
+			}
+
+			if err = f.Close(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "support bundle written to %s\n", output)
+			return nil
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVarP(&output, "output", "o", "", "path to write the archive to (default acme-linode-support-<timestamp>.tar.gz)")
+	fs.StringVar(&adminURL, "admin-url", adminURLFromEnv(), "base URL of the running webhook's admin server to collect its status and recent errors from")
+	fs.StringVar(&adminTokenFile, "admin-token-file", os.Getenv("ADMIN_TOKEN_FILE"), "path to a file containing the admin server bearer token")
+	fs.StringVar(&linodeTokenFile, "linode-token-file", "", "path to a file containing the linode API token to check (defaults to $LINODE_TOKEN)")
+	fs.StringSliceVar(&logFiles, "log-file", nil, "log files to include in the archive after redacting secrets (repeatable)")
+	fs.DurationVar(&timeout, "timeout", acme.DefaultBundleTimeout, "timeout for each connectivity check")
+	return cmd
+}
+
+// Derives the admin server URL from $ADMIN_ADDR so that the bundle can be collected
+// with kubectl exec in the webhook container without any flags.
+func adminURLFromEnv() string {
+	addr := strings.TrimSpace(os.Getenv("ADMIN_ADDR"))
+	if addr == "" {
+		return ""
+	}
+
+	scheme := "http"
+	if os.Getenv("ADMIN_TLS_CERT_FILE") != "" {
+		scheme = "https"
+	}
+
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	return scheme + "://" + addr
+}
+
+func readTokenFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("could not read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
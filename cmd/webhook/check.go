@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.rtnl.ai/acme-linode"
+)
+
+// Creates the check subcommand, which verifies that a challenge record is served by
+// the authoritative nameservers of its zone in the same way as the cert-manager DNS01
+// pre-check, e.g. to debug a challenge that is stuck waiting for propagation.
+func checkCommand() *cobra.Command {
+	var (
+		check   acme.SelfCheck
+		wait    bool
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "check FQDN VALUE",
+		Short: "check that a TXT record is served by the authoritative nameservers",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) (err error) {
+			ctx, cancel := context.WithTimeout(c.Context(), timeout)
+			defer cancel()
+
+			if wait {
+				err = check.Wait(ctx, args[0], args[1])
+			} else {
+				err = check.Check(ctx, args[0], args[1])
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(c.OutOrStdout(), "TXT record %s is served by all authoritative nameservers\n", args[0])
+			return nil
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringSliceVar(&check.Resolvers, "resolver", nil, "recursive resolvers used to discover the authoritative nameservers (default from /etc/resolv.conf)")
+	fs.StringSliceVar(&check.Nameservers, "nameserver", nil, "authoritative nameservers to query instead of discovering them")
+	fs.IntVar(&check.Retries, "retries", acme.DefaultSelfCheckRetries, "number of times a failed query is retried")
+	fs.BoolVar(&wait, "wait", false, "poll the nameservers until the record has propagated or the timeout elapses")
+	fs.DurationVar(&check.Interval, "interval", acme.PropagationInterval, "how often the nameservers are polled with --wait")
+	fs.DurationVar(&timeout, "timeout", acme.DefaultTimeout, "timeout of the check")
+	return cmd
+}
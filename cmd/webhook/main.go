@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd/server"
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"go.rtnl.ai/acme-linode"
+	"k8s.io/component-base/logs"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 var GroupName = os.Getenv("GROUP_NAME")
@@ -16,12 +26,86 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// Mirrors cmd.RunWebhookServer from cert-manager so that the solver can register its
+// own command line flags on the webhook server command before it is executed.
+func run() error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logs.InitLogs()
+	defer logs.FlushLogs()
+	ctrl.SetLogger(logf.Log)
+	ctx = logf.NewContext(ctx, logf.Log, "acme-dns-webhook")
+
 	// This will register our custom DNS provider with the webhook serving
 	// library, making it available as an API under the provided GroupName.
 	// You can register multiple DNS provider implementations with a single
 	// webhook, where the Name() method will be used to disambiguate between
 	// the different implementations.
-	cmd.RunWebhookServer(GroupName,
-		&acme.LinodeDNSProviderSolver{},
-	)
+	solver := &acme.LinodeDNSProviderSolver{}
+	solvers := []webhook.Solver{solver}
+
+	// Additional solver instances are registered from the solvers file, which is read
+	// from the environment since the solvers are registered before the flags are parsed.
+	var instances []*acme.LinodeDNSProviderSolver
+	if path := strings.TrimSpace(os.Getenv("SOLVERS_FILE")); path != "" {
+		registry, err := acme.LoadSolverRegistry(path)
+		if err != nil {
+			logf.Log.Error(err, "could not load solver registry")
+			return err
+		}
+
+		for _, instance := range registry.Solvers {
+			instances = append(instances, instance.NewSolver())
+			solvers = append(solvers, instances[len(instances)-1])
+		}
+	}
+
+	cmd := server.NewCommandStartWebhookServer(ctx, GroupName, solvers...)
+
+	// Behave like a well-mannered service when run outside of Kubernetes.
+	all := append([]*acme.LinodeDNSProviderSolver{solver}, instances...)
+	reloadOnSignal(ctx, all)
+	go notifySystemd(ctx, all)
+
+	// The solver and admin server flags are kept in their own flag set so that the
+	// support bundle can report the effective configuration of the webhook.
+	flags := pflag.NewFlagSet("acme-linode", pflag.ExitOnError)
+	solver.AddFlags(flags)
+
+	// The admin server runs alongside the webhook server once the flags are parsed.
+	admin := acme.NewAdminServer(solver)
+	admin.AddFlags(flags)
+
+	cmd.Flags().AddFlagSet(flags)
+	cmd.AddCommand(supportBundleCommand(flags, solver))
+	cmd.AddCommand(checkCommand())
+
+	runWebhook := cmd.RunE
+	cmd.RunE = func(c *cobra.Command, args []string) error {
+		for _, instance := range instances {
+			if err := instance.ApplyFlags(flags); err != nil {
+				return fmt.Errorf("could not apply flags to solver %s: %w", instance.Name(), err)
+			}
+		}
+
+		go func() {
+			if err := admin.Serve(c.Context()); err != nil {
+				logf.Log.Error(err, "admin server failed")
+				cancel()
+			}
+		}()
+		return runWebhook(c, args)
+	}
+
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		logf.Log.Error(err, "error executing command")
+		return err
+	}
+	return nil
 }
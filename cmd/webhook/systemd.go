@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	logf "github.com/cert-manager/cert-manager/pkg/logs"
+	"github.com/coreos/go-systemd/v22/daemon"
+	"go.rtnl.ai/acme-linode"
+)
+
+// How often the solvers are checked for readiness before systemd is notified.
+const readyPollInterval = time.Second
+
+// Notifies systemd when the webhook is run as a service with Type=notify outside of
+// Kubernetes: READY=1 once every solver is ready, WATCHDOG=1 while every solver is
+// healthy if WatchdogSec is set (so that systemd restarts a webhook that has lost the
+// Linode API), and STOPPING=1 when the context is canceled. Notifications are no-ops
+// unless $NOTIFY_SOCKET is set.
+func notifySystemd(ctx context.Context, solvers []*acme.LinodeDNSProviderSolver) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+
+	ready := func() error {
+		for _, solver := range solvers {
+			if err := solver.Ready(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for ready() != nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	sdNotify(daemon.SdNotifyReady)
+
+	// The watchdog is pinged at half the interval as recommended by sd_watchdog_enabled.
+	var watchdog <-chan time.Time
+	if interval, err := daemon.SdWatchdogEnabled(false); err != nil {
+		logf.Log.Error(err, "could not read the systemd watchdog interval")
+	} else if interval > 0 {
+		pinger := time.NewTicker(interval / 2)
+		defer pinger.Stop()
+		watchdog = pinger.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			sdNotify(daemon.SdNotifyStopping)
+			return
+		case <-watchdog:
+			if err := ready(); err != nil {
+				logf.Log.Info("withholding the systemd watchdog ping", "reason", err.Error())
+				continue
+			}
+			sdNotify(daemon.SdNotifyWatchdog)
+		}
+	}
+}
+
+// Reloads the configuration files of the solvers on SIGHUP, which would otherwise
+// terminate the webhook, until the context is canceled. The signal is handled as soon
+// as this returns. systemd is told that the webhook is reloading so that `systemctl
+// reload` waits for the reload to finish.
+func reloadOnSignal(ctx context.Context, solvers []*acme.LinodeDNSProviderSolver) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logf.Log.Info("received SIGHUP, reloading configuration files")
+				sdNotify(daemon.SdNotifyReloading)
+
+				for _, solver := range solvers {
+					if err := solver.Reload(); err != nil && !errors.Is(err, acme.ErrNotInitialized) {
+						logf.Log.Error(err, "could not reload configuration", "solver", solver.Name())
+					}
+				}
+				sdNotify(daemon.SdNotifyReady)
+			}
+		}
+	}()
+}
+
+func sdNotify(state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		logf.Log.Error(err, "could not notify systemd", "state", state)
+	}
+}
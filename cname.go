@@ -0,0 +1,79 @@
+package acme
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// The maximum number of CNAME records followed from the challenge name.
+const MaxCNAMEHops = 8
+
+// ResolveCNAME follows the chain of CNAME records that starts at the fqdn with the
+// recursive resolvers, returning the fqdn at the end of the chain, which is the fqdn
+// itself if it has no CNAME record. ErrCNAMEChain is returned if the chain loops or is
+// longer than MaxCNAMEHops.
+func (c *SelfCheck) ResolveCNAME(ctx context.Context, fqdn string) (_ string, err error) {
+	name := dns.Fqdn(asciiName(fqdn))
+	seen := make(map[string]bool, MaxCNAMEHops)
+	for hops := 0; hops <= MaxCNAMEHops; hops++ {
+		if seen[strings.ToLower(name)] {
+			return "", fmt.Errorf("%w: the CNAME records of %q loop at %q", ErrCNAMEChain, fqdn, name)
+		}
+		seen[strings.ToLower(name)] = true
+
+		msg := new(dns.Msg)
+		msg.SetQuestion(name, dns.TypeCNAME)
+
+		var reply *dns.Msg
+		if reply, err = c.resolve(ctx, msg); err != nil {
+			return "", err
+		}
+
+		var target string
+		for _, rr := range reply.Answer {
+			if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, name) {
+				target = cname.Target
+			}
+		}
+
+		if target == "" {
+			return name, nil
+		}
+		name = dns.Fqdn(target)
+	}
+	return "", fmt.Errorf("%w: %q has more than %d CNAME records", ErrCNAMEChain, fqdn, MaxCNAMEHops)
+}
+
+// Returns the challenge with its fqdn replaced by the target of the CNAME records at
+// the fqdn, if any, so that the record is created in the validation zone that the
+// challenge name is delegated to ("alias mode"). The zone of the target is found from
+// its parents, since the zone resolved by cert-manager is the zone of the CNAME.
+func (s *LinodeDNSProviderSolver) followCNAME(ch *v1alpha1.ChallengeRequest) (*v1alpha1.ChallengeRequest, error) {
+	check := s.cnames
+	if check == nil {
+		check = &SelfCheck{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultQueryTimeout*2)
+	defer cancel()
+
+	target, err := check.ResolveCNAME(ctx, ch.ResolvedFQDN)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the CNAME records of %s: %w", ch.ResolvedFQDN, err)
+	}
+
+	if sameName(target, ch.ResolvedFQDN) {
+		return ch, nil
+	}
+
+	klog.V(2).Infof("following the CNAME of %s to %s", ch.ResolvedFQDN, target)
+	delegated := ch.DeepCopy()
+	delegated.ResolvedFQDN = target
+	delegated.ResolvedZone = ""
+	return delegated, nil
+}
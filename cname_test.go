@@ -0,0 +1,105 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestFollowCNAME(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	zone := fake.AddDomain("example.com")
+	validation := fake.AddDomain("validation.net")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeCNAME, Name: "_acme-challenge", Target: "example.com.validation.net"})
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeCNAME, Name: "_acme-challenge.loop", Target: "_acme-challenge.loop.example.com"})
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	solver.cnames = &SelfCheck{Resolvers: []string{dns.Addr()}}
+
+	challenge := func(fqdn string) *v1alpha1.ChallengeRequest {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: fqdn, ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+		ch.Config = &extapi.JSON{Raw: []byte(`{"followCNAME": true}`)}
+		return ch
+	}
+
+	// The record is created at the target of the CNAME in the validation zone.
+	ch := challenge("_acme-challenge.example.com.")
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	assertTargets(t, fake, validation.ID, "example.com", "key1")
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	assertTargets(t, fake, validation.ID, "example.com")
+
+	// Names without a CNAME are presented as they are.
+	www := challenge("_acme-challenge.www.example.com.")
+	if err := solver.Present(www); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www", "key1")
+
+	// Chains that loop are not followed.
+	if err := solver.Present(challenge("_acme-challenge.loop.example.com.")); !errors.Is(err, ErrCNAMEChain) {
+		t.Errorf("expected ErrCNAMEChain, got %v", err)
+	}
+
+	target, err := solver.cnames.ResolveCNAME(context.Background(), "_acme-challenge.example.com")
+	if err != nil || target != "example.com.validation.net." {
+		t.Errorf("unexpected target %q: %v", target, err)
+	}
+
+	// The zone of a delegated challenge cannot be fixed by the issuer, nor by the
+	// defaults that are merged into its config.
+	for _, config := range []string{`{"followCNAME": true, "domainID": 1}`, `{"followCNAME": true, "zoneName": "example.com"}`} {
+		if _, err := LoadConfig(&extapi.JSON{Raw: []byte(config)}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected %s to be rejected, got %v", config, err)
+		}
+	}
+
+	// A zone route that sets the domainID or zoneName is rejected before the CNAME of
+	// the challenge is resolved.
+	for _, selector := range []string{fmt.Sprintf("domainID: %d", validation.ID), "zoneName: validation.net"} {
+		path := filepath.Join(t.TempDir(), "routes.yaml")
+		data := "routes:\n  - zone: example.com\n    apiKeySecretRef: {name: linode-credentials, key: token}\n    " + selector + "\n"
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		routes, err := LoadRoutes(path)
+		if err != nil {
+			t.Fatalf("could not load routes: %v", err)
+		}
+		solver.routes.Store(routes)
+
+		queries := dns.Queries()
+		for _, op := range []func(*v1alpha1.ChallengeRequest) error{solver.Present, solver.CleanUp} {
+			if err := op(challenge("_acme-challenge.example.com.")); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expected the route with %s to be rejected, got %v", selector, err)
+			}
+		}
+
+		if n := dns.Queries() - queries; n != 0 {
+			t.Errorf("expected no CNAME lookups for the rejected config, got %d", n)
+		}
+	}
+	assertTargets(t, fake, validation.ID, "example.com")
+}
@@ -0,0 +1,59 @@
+package acme
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+// The challenge type solved by the webhook.
+const ChallengeTypeDNS01 = "dns-01"
+
+// Adapts the challenge request to the form sent by current cert-manager releases so
+// that the webhook does not have to be upgraded in lock-step with cert-manager. All
+// releases send the acme.cert-manager.io/v1alpha1 ChallengePayload, but the contents
+// of the request have changed over time:
+//
+//   - the resolvedFQDN and resolvedZone fields are optional and were not sent by early
+//     releases, so the FQDN is derived from the dnsName and the zone is discovered;
+//   - the action was sent in lower case (present or cleanup) by early releases;
+//   - the FQDN and zone are not always fully qualified with a trailing dot.
+//
+// A copy of the request is returned so that the request is not modified. Requests for
+// other challenge types or whose action does not match the method are rejected with
+// ErrUnsupportedChallenge rather than being solved incorrectly.
+func adaptChallenge(ch *v1alpha1.ChallengeRequest, action v1alpha1.ChallengeAction) (*v1alpha1.ChallengeRequest, error) {
+	if ch == nil {
+		return nil, fmt.Errorf("%w: missing challenge request", ErrUnsupportedChallenge)
+	}
+
+	adapted := *ch
+	if adapted.Type != "" && !strings.EqualFold(adapted.Type, ChallengeTypeDNS01) {
+		return nil, fmt.Errorf("%w: challenge type %q is not supported, only %s", ErrUnsupportedChallenge, adapted.Type, ChallengeTypeDNS01)
+	}
+
+	if adapted.Action != "" {
+		if !strings.EqualFold(string(adapted.Action), string(action)) {
+			return nil, fmt.Errorf("%w: %s request has action %q", ErrUnsupportedChallenge, action, adapted.Action)
+		}
+		adapted.Action = action
+	}
+
+	if adapted.ResolvedFQDN == "" {
+		if adapted.DNSName == "" {
+			return nil, fmt.Errorf("%w: the request has neither a resolvedFQDN nor a dnsName", ErrUnsupportedChallenge)
+		}
+		adapted.ResolvedFQDN = "_acme-challenge." + strings.TrimPrefix(adapted.DNSName, "*.")
+	}
+
+	adapted.ResolvedFQDN = fullyQualified(adapted.ResolvedFQDN)
+	if adapted.ResolvedZone != "" {
+		adapted.ResolvedZone = fullyQualified(adapted.ResolvedZone)
+	}
+	return &adapted, nil
+}
+
+func fullyQualified(name string) string {
+	return strings.TrimSuffix(strings.TrimSpace(name), ".") + "."
+}
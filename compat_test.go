@@ -0,0 +1,47 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestAdaptChallenge(t *testing.T) {
+	// Requests from early releases without the resolved fields or a canonical action.
+	ch := &v1alpha1.ChallengeRequest{Action: "present", DNSName: "*.example.com", Key: "key1"}
+	adapted, err := adaptChallenge(ch, v1alpha1.ChallengeActionPresent)
+	if err != nil {
+		t.Fatalf("could not adapt challenge: %v", err)
+	}
+
+	if adapted.ResolvedFQDN != "_acme-challenge.example.com." || adapted.ResolvedZone != "" || adapted.Action != v1alpha1.ChallengeActionPresent {
+		t.Errorf("unexpected adapted challenge %+v", adapted)
+	}
+
+	if ch.ResolvedFQDN != "" {
+		t.Error("expected the original request not to be modified")
+	}
+
+	// Names are fully qualified.
+	ch = &v1alpha1.ChallengeRequest{Type: "dns-01", ResolvedFQDN: "_acme-challenge.example.com", ResolvedZone: "example.com"}
+	if adapted, err = adaptChallenge(ch, v1alpha1.ChallengeActionCleanUp); err != nil {
+		t.Fatalf("could not adapt challenge: %v", err)
+	}
+
+	if adapted.ResolvedFQDN != "_acme-challenge.example.com." || adapted.ResolvedZone != "example.com." {
+		t.Errorf("expected fully qualified names, got %q and %q", adapted.ResolvedFQDN, adapted.ResolvedZone)
+	}
+
+	// Requests that cannot be solved are rejected.
+	for _, ch := range []*v1alpha1.ChallengeRequest{
+		nil,
+		{Type: "http-01", ResolvedFQDN: "_acme-challenge.example.com."},
+		{Action: "cleanup", ResolvedFQDN: "_acme-challenge.example.com."},
+		{Action: "Present"},
+	} {
+		if _, err := adaptChallenge(ch, v1alpha1.ChallengeActionPresent); !errors.Is(err, ErrUnsupportedChallenge) {
+			t.Errorf("expected ErrUnsupportedChallenge for %+v, got %v", ch, err)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The solver config is versioned so that its layout can evolve without breaking
+// existing Issuer manifests. Configs without an apiVersion use the original
+// unversioned layout and are migrated to the current version when decoded.
+const (
+	ConfigGroup     = "acme-linode.rtnl.ai"
+	ConfigKind      = "LinodeDNSProviderConfig"
+	ConfigVersionV1 = ConfigGroup + "/v1"
+
+	CurrentConfigVersion = ConfigVersionV1
+)
+
+// Limits of the per-issuer tuning fields of the solver config.
+const (
+	MaxTTLSeconds     = 2419200
+	MaxTimeoutSeconds = 600
+	MaxRetryCount     = 10
+)
+
+// PropagationCheck specifies how Present verifies that the record has propagated.
+type PropagationCheck string
+
+const (
+	// Present returns once the record is stored by the Linode API; cert-manager then
+	// performs its own self check (the default).
+	PropagationCheckNone PropagationCheck = "none"
+
+	// Present waits until the record is served by all of the Linode nameservers.
+	PropagationCheckAuthoritative PropagationCheck = "authoritative"
+
+	// Present waits until the record is served by all of the Linode nameservers and
+	// then by a quorum of public recursive resolvers.
+	PropagationCheckQuorum PropagationCheck = "quorum"
+)
+
+func (p PropagationCheck) validate() error {
+	switch p {
+	case "", PropagationCheckNone, PropagationCheckAuthoritative, PropagationCheckQuorum:
+		return nil
+	default:
+		return fmt.Errorf("%w: propagationCheck must be %q, %q, or %q", ErrInvalidConfig, PropagationCheckNone, PropagationCheckAuthoritative, PropagationCheckQuorum)
+	}
+}
+
+// A decoder parses a specific version of the config layout into the current config.
+type configDecoder func(raw []byte) (LinodeDNSProviderConfig, error)
+
+var configDecoders = map[string]configDecoder{
+	"":              decodeUnversionedConfig,
+	ConfigVersionV1: decodeConfigV1,
+}
+
+// LoadConfig is a small helper function that decodes JSON configuration into
+// the typed config struct, migrating older config layouts to the current version.
+func LoadConfig(data *extapi.JSON) (cfg LinodeDNSProviderConfig, err error) {
+	// handle the 'base case' where no configuration has been provided
+	if data == nil {
+		cfg.APIVersion, cfg.Kind = CurrentConfigVersion, ConfigKind
+		return cfg, nil
+	}
+
+	var meta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+
+	if err = json.Unmarshal(data.Raw, &meta); err != nil {
+		return cfg, fmt.Errorf("error decoding solver config: %v", err)
+	}
+
+	decode, ok := configDecoders[meta.APIVersion]
+	if !ok {
+		return cfg, fmt.Errorf("%w: %q (supported versions: %s)", ErrUnsupportedConfig, meta.APIVersion, CurrentConfigVersion)
+	}
+
+	if meta.APIVersion != "" && meta.Kind != ConfigKind {
+		return cfg, fmt.Errorf("%w: expected kind %q got %q", ErrUnsupportedConfig, ConfigKind, meta.Kind)
+	}
+
+	if cfg, err = decode(data.Raw); err != nil {
+		return cfg, fmt.Errorf("error decoding solver config: %v", err)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return cfg, err
+	}
+
+	cfg.APIVersion, cfg.Kind = CurrentConfigVersion, ConfigKind
+	return cfg, nil
+}
+
+// The original config layout, which did not have an apiVersion or kind. Unknown
+// fields are ignored for backwards compatibility with existing Issuers.
+func decodeUnversionedConfig(raw []byte) (cfg LinodeDNSProviderConfig, err error) {
+	if err = json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// Versioned configs are decoded strictly so that typos in field names are caught.
+func decodeConfigV1(raw []byte) (cfg LinodeDNSProviderConfig, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	if err = decoder.Decode(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// SecretKeyLabelSelector selects a key of the Secret that matches the label selector.
+type SecretKeyLabelSelector struct {
+	k8smetav1.LabelSelector
+
+	// The key of the secret that holds the Linode API token.
+	Key string `json:"key"`
+}
+
+// String returns the label selector in the format used by list requests.
+func (s SecretKeyLabelSelector) String() (string, error) {
+	selector, err := k8smetav1.LabelSelectorAsSelector(&s.LabelSelector)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid apiKeySecretSelector: %v", ErrInvalidConfig, err)
+	}
+
+	if selector.Empty() {
+		return "", fmt.Errorf("%w: apiKeySecretSelector must specify matchLabels or matchExpressions", ErrInvalidConfig)
+	}
+	return selector.String(), nil
+}
+
+// Returns true if the config specifies its own credentials rather than falling back to
+// the credentials of a zone route or the webhook.
+func (c LinodeDNSProviderConfig) hasCredentials() bool {
+	return c.APIKeySecretRef.LocalObjectReference.Name != "" || c.APIKeySecretSelector != nil || c.OAuth != nil
+}
+
+// Validate the secret selector and tuning fields of the config.
+func (c LinodeDNSProviderConfig) Validate() error {
+	if c.APIKeySecretSelector != nil {
+		if c.APIKeySecretRef.LocalObjectReference.Name != "" {
+			return fmt.Errorf("%w: only one of apiKeySecretRef and apiKeySecretSelector may be specified", ErrInvalidConfig)
+		}
+
+		if c.APIKeySecretSelector.Key == "" {
+			return fmt.Errorf("%w: apiKeySecretSelector must specify a key", ErrInvalidConfig)
+		}
+
+		if _, err := c.APIKeySecretSelector.String(); err != nil {
+			return err
+		}
+	}
+
+	if c.OAuth != nil {
+		if c.APIKeySecretRef.LocalObjectReference.Name != "" || c.APIKeySecretSelector != nil {
+			return fmt.Errorf("%w: oauth may not be specified with apiKeySecretRef or apiKeySecretSelector", ErrInvalidConfig)
+		}
+
+		if err := c.OAuth.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.APIURL != "" {
+		if u, err := url.Parse(c.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: apiURL must be an absolute http or https URL", ErrInvalidConfig)
+		}
+	}
+
+	if strings.ContainsFunc(c.ChildAccount, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' }) {
+		return fmt.Errorf("%w: childAccount must be the EUUID of a child account", ErrInvalidConfig)
+	}
+
+	for _, id := range c.DomainIDs {
+		if id <= 0 {
+			return fmt.Errorf("%w: domainIDs must be positive", ErrInvalidConfig)
+		}
+	}
+
+	if c.ZoneName != "" && (normalizeZone(c.ZoneName) == "" || strings.ContainsFunc(c.ZoneName, func(r rune) bool { return r <= ' ' || r == '*' || r == '/' || r == '@' })) {
+		return fmt.Errorf("%w: zoneName must be a domain name", ErrInvalidConfig)
+	}
+
+	if c.DomainID < 0 {
+		return fmt.Errorf("%w: domainID must be positive", ErrInvalidConfig)
+	}
+
+	if c.DomainID != 0 && (len(c.DomainIDs) > 0 || c.DomainTag != "" || c.CreateZoneIfMissing) {
+		return fmt.Errorf("%w: domainID may not be specified with domainIDs, domainTag, or createZoneIfMissing", ErrInvalidConfig)
+	}
+
+	// A created zone has a new ID, so it could never be selected by the domainIDs.
+	if c.CreateZoneIfMissing && len(c.DomainIDs) > 0 {
+		return fmt.Errorf("%w: createZoneIfMissing may not be specified with domainIDs", ErrInvalidConfig)
+	}
+
+	if err := c.validateFollowCNAME(); err != nil {
+		return err
+	}
+
+	if err := validateSOAEmail(c.ZoneSOAEmail); err != nil {
+		return err
+	}
+
+	if c.TTLSeconds < 0 || c.TTLSeconds > MaxTTLSeconds {
+		return fmt.Errorf("%w: ttlSeconds must be between 0 and %d", ErrInvalidConfig, MaxTTLSeconds)
+	}
+
+	if c.TimeoutSeconds < 0 || c.TimeoutSeconds > MaxTimeoutSeconds {
+		return fmt.Errorf("%w: timeoutSeconds must be between 0 and %d", ErrInvalidConfig, MaxTimeoutSeconds)
+	}
+
+	if c.ReadTimeoutSeconds < 0 || c.ReadTimeoutSeconds > MaxTimeoutSeconds || c.WriteTimeoutSeconds < 0 || c.WriteTimeoutSeconds > MaxTimeoutSeconds {
+		return fmt.Errorf("%w: readTimeoutSeconds and writeTimeoutSeconds must be between 0 and %d", ErrInvalidConfig, MaxTimeoutSeconds)
+	}
+
+	if c.MaxRetries != nil && (*c.MaxRetries < 0 || *c.MaxRetries > MaxRetryCount) {
+		return fmt.Errorf("%w: maxRetries must be between 0 and %d", ErrInvalidConfig, MaxRetryCount)
+	}
+
+	if c.APIQPS < 0 || c.APIBurst < 0 {
+		return fmt.Errorf("%w: apiQPS and apiBurst must not be negative", ErrInvalidConfig)
+	}
+
+	return c.PropagationCheck.validate()
+}
+
+// Returns the zone of the challenge records, which is the zoneName if it is set or
+// else the zone resolved by cert-manager.
+func (c LinodeDNSProviderConfig) resolvedZone(ch *v1alpha1.ChallengeRequest) string {
+	if c.ZoneName != "" {
+		return c.ZoneName
+	}
+	return ch.ResolvedZone
+}
+
+// Timeout returns the timeout of Linode API operations or DefaultTimeout if unset.
+func (c LinodeDNSProviderConfig) Timeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// Returns the timeout in whole seconds, rounded up, for the timeout fields of the config.
+func timeoutSeconds(timeout time.Duration) int {
+	return int((timeout + time.Second - 1) / time.Second)
+}
+
+// ReadTimeout returns the timeout of the Linode API operations that read domains and
+// records, which is the Timeout unless readTimeoutSeconds is set.
+func (c LinodeDNSProviderConfig) ReadTimeout() time.Duration {
+	if c.ReadTimeoutSeconds > 0 {
+		return time.Duration(c.ReadTimeoutSeconds) * time.Second
+	}
+	return c.Timeout()
+}
+
+// WriteTimeout returns the timeout of the Linode API operations that create, update,
+// or delete records, which is the Timeout unless writeTimeoutSeconds is set.
+func (c LinodeDNSProviderConfig) WriteTimeout() time.Duration {
+	if c.WriteTimeoutSeconds > 0 {
+		return time.Duration(c.WriteTimeoutSeconds) * time.Second
+	}
+	return c.Timeout()
+}
+
+// Rejects followCNAME with a domainID or zoneName, which the CNAME target may not be in.
+func (c LinodeDNSProviderConfig) validateFollowCNAME() error {
+	if c.FollowCNAME && (c.DomainID != 0 || c.ZoneName != "") {
+		return fmt.Errorf("%w: followCNAME may not be specified with domainID or zoneName", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// WithDefaults returns a copy of the config with any unset tuning fields set from
+// the defaults; the version, kind, and secret reference are not changed.
+func (c LinodeDNSProviderConfig) WithDefaults(defaults LinodeDNSProviderConfig) LinodeDNSProviderConfig {
+	if c.APIURL == "" {
+		c.APIURL = defaults.APIURL
+	}
+
+	if c.ChildAccount == "" {
+		c.ChildAccount = defaults.ChildAccount
+	}
+
+	if len(c.DomainIDs) == 0 {
+		c.DomainIDs = defaults.DomainIDs
+	}
+
+	if c.DomainTag == "" {
+		c.DomainTag = defaults.DomainTag
+	}
+
+	if c.ZoneName == "" {
+		c.ZoneName = defaults.ZoneName
+	}
+
+	// The domain of the defaults is not used if the config selects its own domains.
+	if c.DomainID == 0 && len(c.DomainIDs) == 0 && c.DomainTag == "" {
+		c.DomainID = defaults.DomainID
+	}
+
+	if c.TTLSeconds == 0 {
+		c.TTLSeconds = defaults.TTLSeconds
+	}
+
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = defaults.TimeoutSeconds
+	}
+
+	if c.ReadTimeoutSeconds == 0 {
+		c.ReadTimeoutSeconds = defaults.ReadTimeoutSeconds
+	}
+
+	if c.WriteTimeoutSeconds == 0 {
+		c.WriteTimeoutSeconds = defaults.WriteTimeoutSeconds
+	}
+
+	if c.MaxRetries == nil {
+		c.MaxRetries = defaults.MaxRetries
+	}
+
+	if c.PropagationCheck == "" {
+		c.PropagationCheck = defaults.PropagationCheck
+	}
+
+	if c.APIQPS == 0 {
+		c.APIQPS = defaults.APIQPS
+	}
+
+	if c.APIBurst == 0 {
+		c.APIBurst = defaults.APIBurst
+	}
+
+	if c.ZoneSOAEmail == "" {
+		c.ZoneSOAEmail = defaults.ZoneSOAEmail
+	}
+
+	c.ForceCleanup = c.ForceCleanup || defaults.ForceCleanup
+	c.CreateZoneIfMissing = c.CreateZoneIfMissing || defaults.CreateZoneIfMissing
+	c.FollowCNAME = c.FollowCNAME || defaults.FollowCNAME
+	return c
+}
@@ -0,0 +1,96 @@
+package acme_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.rtnl.ai/acme-linode"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		err  error
+	}{
+		{"unversioned", `{"apiKeySecretRef": {"name": "linode-credentials", "key": "token"}}`, nil},
+		{"unversioned unknown fields", `{"apiKeySecretRef": {"name": "linode-credentials", "key": "token"}, "extra": true}`, nil},
+		{"v1", `{"apiVersion": "acme-linode.rtnl.ai/v1", "kind": "LinodeDNSProviderConfig", "apiKeySecretRef": {"name": "linode-credentials", "key": "token"}}`, nil},
+		{"v1 wrong kind", `{"apiVersion": "acme-linode.rtnl.ai/v1", "kind": "Secret"}`, acme.ErrUnsupportedConfig},
+		{"unknown version", `{"apiVersion": "acme-linode.rtnl.ai/v9", "kind": "LinodeDNSProviderConfig"}`, acme.ErrUnsupportedConfig},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := acme.LoadConfig(&extapi.JSON{Raw: []byte(tc.raw)})
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error %v got %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("could not load config: %v", err)
+			}
+
+			if cfg.APIVersion != acme.CurrentConfigVersion || cfg.Kind != acme.ConfigKind {
+				t.Errorf("expected config to be migrated to %s, got %s %s", acme.CurrentConfigVersion, cfg.APIVersion, cfg.Kind)
+			}
+
+			if cfg.APIKeySecretRef.Name != "linode-credentials" || cfg.APIKeySecretRef.Key != "token" {
+				t.Errorf("unexpected secret ref decoded: %+v", cfg.APIKeySecretRef)
+			}
+		})
+	}
+
+	// Versioned configs are decoded strictly.
+	if _, err := acme.LoadConfig(&extapi.JSON{Raw: []byte(`{"apiVersion": "acme-linode.rtnl.ai/v1", "kind": "LinodeDNSProviderConfig", "apiKeySecretRf": {}}`)}); err == nil {
+		t.Error("expected unknown field in versioned config to be rejected")
+	}
+
+	// Tuning fields are validated.
+	invalid := []string{
+		`{"ttlSeconds": -1}`,
+		`{"ttlSeconds": 99999999}`,
+		`{"timeoutSeconds": 3600}`,
+		`{"apiURL": "api.linode.com/v4"}`,
+		`{"apiURL": "ftp://api.linode.com"}`,
+		`{"readTimeoutSeconds": -1}`,
+		`{"writeTimeoutSeconds": 3600}`,
+		`{"maxRetries": -1}`,
+		`{"propagationCheck": "recursive"}`,
+	}
+
+	for _, raw := range invalid {
+		if _, err := acme.LoadConfig(&extapi.JSON{Raw: []byte(raw)}); !errors.Is(err, acme.ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %s got %v", raw, err)
+		}
+	}
+
+	cfg, err := acme.LoadConfig(&extapi.JSON{Raw: []byte(`{"ttlSeconds": 30, "timeoutSeconds": 20, "maxRetries": 0, "propagationCheck": "authoritative"}`)})
+	if err != nil {
+		t.Fatalf("could not load tuned config: %v", err)
+	}
+
+	if cfg.TTLSeconds != 30 || cfg.Timeout() != 20*time.Second || cfg.MaxRetries == nil || *cfg.MaxRetries != 0 || cfg.PropagationCheck != acme.PropagationCheckAuthoritative {
+		t.Errorf("unexpected tuning decoded: %+v", cfg)
+	}
+
+	// Read and write timeouts default to the timeout of every operation.
+	if cfg.ReadTimeout() != 20*time.Second || cfg.WriteTimeout() != 20*time.Second {
+		t.Errorf("expected read and write timeouts of 20s, got %s and %s", cfg.ReadTimeout(), cfg.WriteTimeout())
+	}
+
+	cfg.WriteTimeoutSeconds = 120
+	if cfg.ReadTimeout() != 20*time.Second || cfg.WriteTimeout() != 2*time.Minute {
+		t.Errorf("expected a write timeout of 2m, got %s and %s", cfg.ReadTimeout(), cfg.WriteTimeout())
+	}
+
+	// No config should return the default config.
+	if cfg, err := acme.LoadConfig(nil); err != nil || cfg.APIVersion != acme.CurrentConfigVersion {
+		t.Errorf("expected default config, got %+v (%v)", cfg, err)
+	}
+}
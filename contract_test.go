@@ -0,0 +1,115 @@
+package acme
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+// TestContract runs the record lifecycle against the fake and validates every request
+// against the Linode OpenAPI specification, which is downloaded by make contract or
+// specified by $LINODE_OPENAPI_SPEC; the test is skipped if the specification is missing.
+func TestContract(t *testing.T) {
+	path := os.Getenv("LINODE_OPENAPI_SPEC")
+	if path == "" {
+		path = "testdata/linode-openapi.json"
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("linode openapi specification not found at %s: run make contract", path)
+	}
+
+	contract, err := linodetest.LoadContract(path)
+	if err != nil {
+		t.Fatalf("could not load linode openapi specification: %v", err)
+	}
+
+	solver, fake := newTestSolver(t)
+	fake.SetContract(contract)
+	solver.confirmEvents = true
+	fake.AddDomain("example.com")
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	// Present twice with different keys so that records are created and updated.
+	for _, key := range []string{"key1", "key2"} {
+		ch.Key = key
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	for _, violation := range fake.Violations() {
+		t.Errorf("request does not conform to the linode openapi specification: %s", violation)
+	}
+}
+
+func TestContractValidation(t *testing.T) {
+	contract, err := linodetest.ParseContract([]byte(`{
+		"openapi": "3.0.1",
+		"servers": [{"url": "https://api.linode.com/v4"}],
+		"paths": {
+			"/domains/{domainId}/records": {
+				"parameters": [{"name": "domainId", "in": "path", "required": true, "schema": {"type": "integer"}}],
+				"get": {"parameters": [{"$ref": "#/components/parameters/Page"}]},
+				"post": {"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/DomainRecord"}}}}}
+			}
+		},
+		"components": {
+			"parameters": {"Page": {"name": "page", "in": "query", "schema": {"type": "integer"}}},
+			"schemas": {
+				"DomainRecord": {
+					"type": "object",
+					"required": ["type"],
+					"properties": {
+						"type": {"type": "string", "enum": ["A", "TXT"]},
+						"ttl_sec": {"type": "integer"}
+					}
+				}
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method   string
+		path     string
+		query    string
+		body     string
+		expected string
+	}{
+		{"GET", "/v4/domains/1/records", "page=1", "", ""},
+		{"POST", "/v4/domains/1/records", "", `{"type": "TXT", "ttl_sec": 30}`, ""},
+		{"GET", "/v4/domains/1/records", "page_size=100", "", "query parameter"},
+		{"POST", "/v4/domains/1/records", "", `{"type": "MX", "ttl_sec": "30"}`, "ttl_sec"},
+		{"DELETE", "/v4/domains/1/records", "", "", "method is not allowed"},
+		{"GET", "/v4/domains/1", "", "", "path is not in the specification"},
+	}
+
+	for _, tc := range tests {
+		query, _ := url.ParseQuery(tc.query)
+		err := contract.Validate(tc.method, tc.path, query, []byte(tc.body))
+
+		switch {
+		case tc.expected == "" && err != nil:
+			t.Errorf("expected %s %s to conform: %v", tc.method, tc.path, err)
+		case tc.expected != "" && (err == nil || !strings.Contains(err.Error(), tc.expected)):
+			t.Errorf("expected %s %s to be rejected with %q, got %v", tc.method, tc.path, tc.expected, err)
+		}
+	}
+}
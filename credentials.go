@@ -0,0 +1,25 @@
+package acme
+
+import (
+	"errors"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+// Runs the challenge operation and, if the Linode API rejects the token as invalid,
+// runs it once more. Every attempt creates a new client from the credentials secret
+// read directly from the Kubernetes API, so a token that was rotated mid-challenge is
+// picked up by the retry rather than failing the challenge until cert-manager retries.
+// Cached OAuth access tokens and child account proxy tokens are discarded so that the
+// retry obtains new ones.
+func (s *LinodeDNSProviderSolver) retryUnauthorized(ch *v1alpha1.ChallengeRequest, op func(*v1alpha1.ChallengeRequest) error) (err error) {
+	if err = op(ch); err == nil || !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+
+	klog.Warningf("linode API rejected the token for fqdn=%s, re-reading the credentials and retrying: %v", ch.ResolvedFQDN, err)
+	s.oauthTokens.reset()
+	s.childTokens.reset()
+	return op(ch)
+}
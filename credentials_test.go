@@ -0,0 +1,50 @@
+package acme
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"go.rtnl.ai/acme-linode/linodetest"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRetryUnauthorized(t *testing.T) {
+	solver, api := newTestSolver(t)
+	zone := api.AddDomain("example.com")
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	// The token is rejected once, e.g. while the secret is being rotated.
+	api.Fail("GET /v4/domains", linodetest.Fault{Status: http.StatusUnauthorized, Reason: "Invalid Token"})
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("expected present to be retried with the re-read credentials: %v", err)
+	}
+	assertTargets(t, api, zone.ID, "_acme-challenge", "key1")
+
+	var reads int
+	for _, action := range solver.k8s.(*fake.Clientset).Actions() {
+		if action.GetVerb() == "get" && action.GetResource().Resource == "secrets" {
+			reads++
+		}
+	}
+
+	if reads != 2 {
+		t.Errorf("expected the credentials secret to be read twice, got %d", reads)
+	}
+
+	// Tokens that are still rejected fail the challenge after a single retry.
+	api.Fail("GET /v4/domains", linodetest.Fault{Status: http.StatusUnauthorized}, linodetest.Fault{Status: http.StatusUnauthorized})
+	if err := solver.CleanUp(ch); err == nil {
+		t.Error("expected cleanup to fail with an invalid token")
+	}
+
+	if calls := api.Calls("GET /v4/domains"); calls != 4 {
+		t.Errorf("expected 4 list domain requests, got %d", calls)
+	}
+}
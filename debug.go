@@ -0,0 +1,33 @@
+package acme
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// SetDebug enables the linodego logging of every request and response for
+// troubleshooting, which is also enabled by $LINODE_DEBUG. The logs are written by
+// klog with bearer tokens and token material redacted.
+func (l *Linode) SetDebug(debug bool) *Linode {
+	if debug {
+		l.client.SetDebug(true)
+	}
+	return l
+}
+
+// Writes the debug logs of linodego to klog, redacting the Authorization header and
+// anything that looks like a token so that credentials never reach the logs.
+type linodeLogger struct{}
+
+func (linodeLogger) Errorf(format string, v ...any) {
+	klog.ErrorDepth(1, Sanitize(fmt.Sprintf(format, v...)))
+}
+
+func (linodeLogger) Warnf(format string, v ...any) {
+	klog.WarningDepth(1, Sanitize(fmt.Sprintf(format, v...)))
+}
+
+func (linodeLogger) Debugf(format string, v ...any) {
+	klog.InfoDepth(1, Sanitize(fmt.Sprintf(format, v...)))
+}
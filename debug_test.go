@@ -0,0 +1,49 @@
+package acme
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"go.rtnl.ai/acme-linode/linodetest"
+	"k8s.io/klog/v2"
+)
+
+func TestDebugRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	fs.Set("logtostderr", "false")
+	klog.SetOutput(&buf)
+	defer func() {
+		fs.Set("logtostderr", "true")
+		klog.SetOutput(nil)
+	}()
+
+	fake := linodetest.New()
+	defer fake.Close()
+	fake.AddDomain("example.com")
+
+	const token = "s3cr3t-linode-token"
+	linode := NewLinodeWithHTTPClient(token, fake.Client()).SetDebug(true)
+	linode.SetBaseURL(fake.URL())
+
+	if _, err := linode.FindZone("example.com"); err != nil {
+		t.Fatalf("could not find zone: %v", err)
+	}
+	klog.Flush()
+
+	logs := buf.String()
+	if !strings.Contains(logs, "/v4/domains") {
+		t.Fatalf("expected the requests to be logged, got:\n%s", logs)
+	}
+
+	if strings.Contains(logs, token) {
+		t.Errorf("expected the token to be redacted from the debug logs:\n%s", logs)
+	}
+
+	if !strings.Contains(logs, "Bearer "+redacted) {
+		t.Errorf("expected the authorization header to be redacted:\n%s", logs)
+	}
+}
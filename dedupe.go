@@ -0,0 +1,71 @@
+package acme
+
+import (
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// DeduplicateTXT collapses TXT records in the Linode Zone that have the same name and
+// value, e.g. left behind by past races between concurrent challenges, into a single
+// record. Duplicates serve the same answer so removing them does not change what the
+// zone serves, but they slow down lookups and make the zone harder to audit. The
+// record with one of the keep IDs (e.g. a record tracked by the solver) is preferred,
+// otherwise the oldest record is kept. Returns the number of records deleted.
+func (l *Linode) DeduplicateTXT(zoneID int, keep ...int) (deleted int, err error) {
+	unlock := lockZone(zoneID)
+	defer unlock()
+
+	var records []linodego.DomainRecord
+	if records, err = l.listZoneRecords(zoneID); err != nil {
+		return 0, err
+	}
+
+	keeping := make(map[int]bool, len(keep))
+	for _, id := range keep {
+		keeping[id] = true
+	}
+
+	// Group the TXT records by name and value, preserving the order of the records.
+	type txt struct {
+		name   string
+		target string
+	}
+
+	groups := make(map[txt][]linodego.DomainRecord)
+	for _, record := range records {
+		if record.Type != linodego.RecordTypeTXT {
+			continue
+		}
+		key := txt{record.Name, record.Target}
+		groups[key] = append(groups[key], record)
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		kept := group[0]
+		for _, record := range group[1:] {
+			if keeping[record.ID] && !keeping[kept.ID] || keeping[record.ID] == keeping[kept.ID] && record.ID < kept.ID {
+				kept = record
+			}
+		}
+
+		for _, record := range group {
+			if record.ID == kept.ID {
+				continue
+			}
+
+			klog.Infof("removing duplicate TXT record %s (ID %d, duplicates ID %d) in zone ID %d", record.Name, record.ID, kept.ID, zoneID)
+			if err = l.DeleteRecord(zoneID, record.ID); err != nil {
+				if linodego.IsNotFound(err) {
+					continue
+				}
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,49 @@
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// Verifies that the zone is delegated to the Linode nameservers before the challenge
+// is presented, failing fast with ErrZoneDelegated if the NS records published for the
+// zone do not include any of the Linode nameservers since the record would never be
+// served to the ACME server. The check is skipped if the NS records cannot be resolved
+// so that a resolver outage does not block challenges.
+func (s *LinodeDNSProviderSolver) checkDelegation(zone string) error {
+	check := s.currentDelegationCheck()
+	if check == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultQueryTimeout*2)
+	defer cancel()
+
+	published, err := check.ZoneNameservers(ctx, zone)
+	if err != nil {
+		klog.Warningf("skipping delegation check for zone %s: %v", zone, err)
+		return nil
+	}
+
+	expected := make(map[string]struct{}, len(LinodeNameservers))
+	for _, ns := range LinodeNameservers {
+		host, _, err := net.SplitHostPort(ns)
+		if err != nil {
+			host = ns
+		}
+		expected[strings.ToLower(strings.TrimSuffix(host, "."))] = struct{}{}
+	}
+
+	for _, host := range published {
+		if _, ok := expected[host]; ok {
+			return nil
+		}
+	}
+
+	klog.Warningf("zone %s is delegated to %s rather than the linode nameservers", zone, strings.Join(published, ", "))
+	return fmt.Errorf("%w: zone %q is served by %s", ErrZoneDelegated, zone, strings.Join(published, ", "))
+}
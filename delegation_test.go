@@ -0,0 +1,43 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestCheckDelegation(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	fake.AddDomain("example.com")
+	delegated := fake.AddDomain("delegated.com")
+	fake.AddRecord(delegated.ID, linodego.DomainRecord{Type: linodego.RecordTypeNS, Name: "", Target: "ns1.other.net"})
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	solver.delegation = &SelfCheck{Resolvers: []string{dns.Addr()}}
+
+	challenge := func(zone string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResolvedFQDN:      "_acme-challenge." + zone + ".",
+			ResolvedZone:      zone + ".",
+			ResourceNamespace: "default",
+			Key:               "key1",
+		}
+	}
+
+	if err := solver.Present(challenge("example.com")); err != nil {
+		t.Errorf("expected zone delegated to linode to be presented: %v", err)
+	}
+
+	if err := solver.Present(challenge("delegated.com")); !errors.Is(err, ErrZoneDelegated) {
+		t.Errorf("expected ErrZoneDelegated got %v", err)
+	}
+	assertTargets(t, fake, delegated.ID, "_acme-challenge")
+}
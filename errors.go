@@ -1,8 +1,87 @@
 package acme
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/linode/linodego"
+)
 
 var (
+	ErrNoZone                 = errors.New("no zone found in the linode account")
+	ErrAmbiguousZone          = errors.New("more than one domain in the linode account matches the zone")
+	ErrSecondaryZone          = errors.New("the zone is a secondary (slave) zone in linode: its records can only be changed on its primary nameservers")
+	ErrZoneDelegated          = errors.New("zone delegated elsewhere: the linode nameservers are not authoritative for the zone")
+	ErrCNAMEChain             = errors.New("the CNAME records of the challenge name cannot be followed")
 	ErrNoRecord               = errors.New("no matching DNS record found for the specified entry")
+	ErrRecordMismatch         = errors.New("created DNS record does not match the requested record")
+	ErrNotConfirmed           = errors.New("the linode account events did not confirm the record operation")
+	ErrRecordTooYoung         = errors.New("refusing to delete a challenge record younger than the minimum record age")
 	ErrInvalidSecretReference = errors.New("invalid secret reference: must contain name and key values")
+	ErrSecretSelector         = errors.New("the apiKeySecretSelector must match exactly one secret")
+	ErrNotInitialized         = errors.New("the linode dns provider solver has not been initialized")
+	ErrInvalidConfig          = errors.New("invalid solver config")
+	ErrNotPropagated          = errors.New("the challenge record has not propagated to the linode nameservers")
+	ErrNoNameservers          = errors.New("could not find the authoritative nameservers")
+	ErrUnsupportedConfig      = errors.New("unsupported solver config version")
+	ErrUnsupportedChallenge   = errors.New("unsupported challenge request")
+	ErrUnhealthy              = errors.New("too many consecutive linode API failures")
+	ErrOverloaded             = errors.New("the webhook is overloaded with challenge requests, retry later")
+	ErrInvalidPolicy          = errors.New("invalid authorization policy")
+	ErrPolicyDenied           = errors.New("the challenge was denied by the authorization policy")
+	ErrInvalidSolver          = errors.New("invalid solver instance")
+	ErrZoneNotAllowed         = errors.New("the zone is not solved by this solver instance")
+	ErrInvalidRoute           = errors.New("zone routes must specify a zone")
+	ErrSecretRefNotAllowed    = errors.New("apiKeySecretRef is not allowed when the webhook is running in single namespace mode")
+	ErrAPIURLNotAllowed       = errors.New("the apiURL of the issuer is not one of the --linode-allowed-api-urls")
+	ErrChildAccountNotAllowed = errors.New("the childAccount of the issuer requires its own apiKeySecretRef, apiKeySecretSelector, or oauth credentials")
+)
+
+// Errors returned by the Linode API are wrapped with one of these errors so that
+// callers can branch on the kind of failure with errors.Is; the underlying
+// *linodego.Error can still be inspected with errors.As.
+var (
+	ErrUnauthorized      = errors.New("the linode API rejected the token: check that the API token is valid and has not expired")
+	ErrForbidden         = errors.New("the linode API token is not allowed to manage domains: it requires the domains:read_write scope")
+	ErrTokenScope        = errors.New("the linode API token lacks the domains:read_write scope")
+	ErrRateLimited       = errors.New("the linode API rate limit was exceeded, retry later")
+	ErrLinodeUnavailable = errors.New("the linode API is unavailable")
 )
+
+// Wraps an error returned by the linodego client with the error of its kind, if any.
+// Other errors, such as not found errors that callers translate themselves, are
+// returned unchanged.
+func apiError(err error) error {
+	var lerr *linodego.Error
+	if err == nil || !errors.As(err, &lerr) {
+		return err
+	}
+
+	var kind error
+	switch {
+	case lerr.Code == http.StatusUnauthorized:
+		kind = ErrUnauthorized
+	case lerr.Code == http.StatusForbidden:
+		kind = ErrForbidden
+	case lerr.Code == http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case lerr.Code < 100 && strings.HasSuffix(lerr.Message, context.Canceled.Error()):
+		// linodego flattens the errors of the HTTP client into its message, so requests
+		// canceled when the webhook stops are only recognizable by it.
+		kind = context.Canceled
+	case lerr.Code < 100, lerr.Code >= 500:
+		// Errors raised by the HTTP client (e.g. timeouts or refused connections) are
+		// not given an HTTP status code by linodego.
+		kind = ErrLinodeUnavailable
+	default:
+		return err
+	}
+
+	if errors.Is(err, kind) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", kind, err)
+}
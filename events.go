@@ -0,0 +1,101 @@
+package acme
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// The account event actions of the domain record mutations made by the webhook.
+const (
+	EventDomainRecordCreate linodego.EventAction = "domain_record_create"
+	EventDomainRecordUpdate linodego.EventAction = "domain_record_update"
+	EventDomainRecordDelete linodego.EventAction = "domain_record_delete"
+)
+
+// How often the account events are polled while confirming a record mutation.
+var EventPollInterval = time.Second
+
+// SetConfirmEvents enables confirming every record mutation by polling the account
+// events until the corresponding domain record event has completed, which provides
+// stronger assurance than the HTTP response alone on accounts where the Linode backend
+// applies changes asynchronously.
+func (l *Linode) SetConfirmEvents(confirm bool) *Linode {
+	l.confirmEvents = confirm
+	return l
+}
+
+// Polls the account events for the action on the record since the mutation was
+// requested, returning ErrNotConfirmed if the event failed or did not complete before
+// the timeout of the operation.
+func (l *Linode) confirmEvent(zoneID, recordID int, action linodego.EventAction, since time.Time) (err error) {
+	if !l.confirmEvents {
+		return nil
+	}
+
+	ctx, cancel := l.context()
+	defer cancel()
+
+	// Event timestamps have a resolution of seconds so allow for truncation.
+	filter := linodego.Filter{Order: linodego.Descending, OrderBy: "created"}
+	filter.AddField(linodego.Eq, "action", action)
+	filter.AddField(linodego.Eq, "entity.type", linodego.EntityDomain)
+	filter.AddField(linodego.Eq, "entity.id", zoneID)
+	filter.AddField(linodego.Gte, "created", since.UTC().Add(-time.Second).Format("2006-01-02T15:04:05"))
+
+	var data []byte
+	if data, err = filter.MarshalJSON(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(EventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var events []linodego.Event
+		if events, err = l.client.ListEvents(ctx, linodego.NewListOptions(1, string(data))); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w: %v", ErrNotConfirmed, err)
+			}
+			return apiError(err)
+		}
+
+		for _, event := range events {
+			if event.Action != action || entityID(event.Entity) != zoneID || entityID(event.SecondaryEntity) != recordID {
+				continue
+			}
+
+			switch event.Status {
+			case linodego.EventFailed:
+				return fmt.Errorf("%w: %s event %d for record ID %d in zone ID %d failed", ErrNotConfirmed, action, event.ID, recordID, zoneID)
+			case linodego.EventFinished, linodego.EventNotification:
+				klog.V(2).Infof("confirmed %s event %d for record ID %d in zone ID %d", action, event.ID, recordID, zoneID)
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: no completed %s event for record ID %d in zone ID %d", ErrNotConfirmed, action, recordID, zoneID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Returns the integer ID of the event entity, which is decoded from JSON as a float.
+func entityID(entity *linodego.EventEntity) int {
+	if entity == nil {
+		return 0
+	}
+
+	switch id := entity.ID.(type) {
+	case float64:
+		return int(id)
+	case int:
+		return id
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,127 @@
+package acme
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature is the name of a capability that can be enabled or disabled with the
+// --feature-gates flag so that experimental behavior can be shipped disabled by
+// default and rolled out incrementally.
+type Feature string
+
+// FeatureStage describes the maturity of a feature: alpha features are disabled by
+// default and may change or be removed, beta features are enabled by default.
+type FeatureStage string
+
+const (
+	FeatureAlpha FeatureStage = "alpha"
+	FeatureBeta  FeatureStage = "beta"
+)
+
+// FeatureSpec is the default and stage of a feature.
+type FeatureSpec struct {
+	Default bool
+	Stage   FeatureStage
+}
+
+const (
+	// Apply the --settings-file runtime settings while the webhook is running.
+	FeatureRuntimeSettings Feature = "RuntimeSettings"
+
+	// Extend Linode API timeouts when recent requests are slow but completing.
+	FeatureAdaptiveTimeouts Feature = "AdaptiveTimeouts"
+
+	// Allow issuers to require a quorum of public resolvers to serve the record.
+	FeatureResolverQuorum Feature = "ResolverQuorum"
+)
+
+// The features that can be specified with --feature-gates.
+var knownFeatures = map[Feature]FeatureSpec{
+	FeatureRuntimeSettings:  {Default: true, Stage: FeatureBeta},
+	FeatureAdaptiveTimeouts: {Default: false, Stage: FeatureAlpha},
+	FeatureResolverQuorum:   {Default: false, Stage: FeatureAlpha},
+}
+
+// FeatureGates holds the features enabled or disabled with the --feature-gates flag,
+// e.g. --feature-gates=RuntimeSettings=false. It implements pflag.Value and the zero
+// value uses the default of every feature.
+type FeatureGates struct {
+	enabled map[Feature]bool
+}
+
+// Enabled returns true if the feature is enabled; unknown features are disabled.
+func (g *FeatureGates) Enabled(feature Feature) bool {
+	if enabled, ok := g.enabled[feature]; ok {
+		return enabled
+	}
+	return knownFeatures[feature].Default
+}
+
+// Features returns whether each known feature is enabled.
+func (g *FeatureGates) Features() map[Feature]bool {
+	features := make(map[Feature]bool, len(knownFeatures))
+	for feature := range knownFeatures {
+		features[feature] = g.Enabled(feature)
+	}
+	return features
+}
+
+// Set parses a comma separated list of Feature=bool pairs, rejecting unknown features.
+func (g *FeatureGates) Set(value string) error {
+	enabled := make(map[Feature]bool, len(g.enabled))
+	for feature, on := range g.enabled {
+		enabled[feature] = on
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		name, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%w: feature gate %q must be of the form Feature=true|false", ErrInvalidConfig, pair)
+		}
+
+		feature := Feature(strings.TrimSpace(name))
+		if _, known := knownFeatures[feature]; !known {
+			return fmt.Errorf("%w: unknown feature gate %q", ErrInvalidConfig, feature)
+		}
+
+		on, err := strconv.ParseBool(strings.TrimSpace(val))
+		if err != nil {
+			return fmt.Errorf("%w: could not parse feature gate %q: %w", ErrInvalidConfig, pair, err)
+		}
+		enabled[feature] = on
+	}
+
+	g.enabled = enabled
+	return nil
+}
+
+// String returns the features that were explicitly set, sorted by name.
+func (g *FeatureGates) String() string {
+	pairs := make([]string, 0, len(g.enabled))
+	for feature, on := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, on))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (g *FeatureGates) Type() string {
+	return "mapStringBool"
+}
+
+// Returns a description of every known feature and whether it is enabled for logs.
+func (g *FeatureGates) describe() string {
+	features := make([]string, 0, len(knownFeatures))
+	for feature, on := range g.Features() {
+		features = append(features, fmt.Sprintf("%s=%t (%s)", feature, on, knownFeatures[feature].Stage))
+	}
+	sort.Strings(features)
+	return strings.Join(features, ", ")
+}
@@ -0,0 +1,40 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFeatureGates(t *testing.T) {
+	gates := &FeatureGates{}
+	if !gates.Enabled(FeatureRuntimeSettings) {
+		t.Error("expected beta features to be enabled by default")
+	}
+
+	if gates.Enabled("Unknown") {
+		t.Error("expected unknown features to be disabled")
+	}
+
+	if err := gates.Set("RuntimeSettings=false"); err != nil {
+		t.Fatalf("could not set feature gates: %v", err)
+	}
+
+	if gates.Enabled(FeatureRuntimeSettings) {
+		t.Error("expected feature to be disabled")
+	}
+
+	if s := gates.String(); s != "RuntimeSettings=false" {
+		t.Errorf("unexpected feature gates string %q", s)
+	}
+
+	for _, value := range []string{"Unknown=true", "RuntimeSettings", "RuntimeSettings=maybe"} {
+		if err := gates.Set(value); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %q, got %v", value, err)
+		}
+	}
+
+	// Invalid values do not change the gates.
+	if gates.Enabled(FeatureRuntimeSettings) {
+		t.Error("expected feature to remain disabled")
+	}
+}
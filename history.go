@@ -0,0 +1,98 @@
+package acme
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/linode/linodego"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const DefaultErrorHistory = 50
+
+// The error history is a ring buffer of the most recent challenge, Linode API, and
+// Kubernetes API failures so that operators can see why challenges are failing without
+// raising the log verbosity and restarting the webhook. The zero value is ready to use
+// and holds DefaultErrorHistory entries.
+type errorHistory struct {
+	sync.Mutex
+	entries []ErrorEntry
+	next    int
+}
+
+// ErrorEntry describes a failed operation.
+type ErrorEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Zone      string    `json:"zone,omitempty"`
+	FQDN      string    `json:"fqdn,omitempty"`
+	Class     string    `json:"class"`
+	Error     string    `json:"error"`
+}
+
+// The classes of errors recorded in the error history.
+const (
+	ErrorClassLinodeAPI     = "linode-api"
+	ErrorClassLinodeNetwork = "linode-network"
+	ErrorClassKubernetes    = "kubernetes"
+	ErrorClassDNS           = "dns"
+	ErrorClassConfig        = "config"
+	ErrorClassNotFound      = "not-found"
+	ErrorClassOverloaded    = "overloaded"
+	ErrorClassOther         = "other"
+)
+
+func (h *errorHistory) record(op, zone, fqdn string, err error) {
+	h.Lock()
+	defer h.Unlock()
+
+	entry := ErrorEntry{Time: time.Now(), Operation: op, Zone: zone, FQDN: fqdn, Class: errorClass(err), Error: err.Error()}
+	if len(h.entries) < DefaultErrorHistory {
+		h.entries = append(h.entries, entry)
+		return
+	}
+
+	h.entries[h.next] = entry
+	h.next = (h.next + 1) % len(h.entries)
+}
+
+// Returns the recorded errors from the oldest to the most recent.
+func (h *errorHistory) Entries() []ErrorEntry {
+	h.Lock()
+	defer h.Unlock()
+
+	entries := make([]ErrorEntry, 0, len(h.entries))
+	entries = append(entries, h.entries[h.next:]...)
+	return append(entries, h.entries[:h.next]...)
+}
+
+// Classifies the error by the system that it originated from so that the history can
+// be scanned for patterns without parsing the error messages.
+func errorClass(err error) string {
+	var lerr *linodego.Error
+	if errors.As(err, &lerr) {
+		if lerr.Code < 100 {
+			return ErrorClassLinodeNetwork
+		}
+		return ErrorClassLinodeAPI
+	}
+
+	var status k8serrors.APIStatus
+	if errors.As(err, &status) {
+		return ErrorClassKubernetes
+	}
+
+	switch {
+	case errors.Is(err, ErrOverloaded):
+		return ErrorClassOverloaded
+	case errors.Is(err, ErrNotPropagated), errors.Is(err, ErrZoneDelegated), errors.Is(err, ErrNoNameservers), errors.Is(err, ErrCNAMEChain):
+		return ErrorClassDNS
+	case errors.Is(err, ErrInvalidConfig), errors.Is(err, ErrUnsupportedConfig), errors.Is(err, ErrUnsupportedChallenge), errors.Is(err, ErrInvalidSecretReference), errors.Is(err, ErrSecretRefNotAllowed), errors.Is(err, ErrAPIURLNotAllowed), errors.Is(err, ErrChildAccountNotAllowed), errors.Is(err, ErrSecretSelector), errors.Is(err, ErrAmbiguousZone), errors.Is(err, ErrSecondaryZone), errors.Is(err, ErrTokenScope):
+		return ErrorClassConfig
+	case errors.Is(err, ErrNoZone), errors.Is(err, ErrNoRecord):
+		return ErrorClassNotFound
+	default:
+		return ErrorClassOther
+	}
+}
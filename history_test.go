@@ -0,0 +1,53 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/linode/linodego"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestErrorHistory(t *testing.T) {
+	var history errorHistory
+	for i := range DefaultErrorHistory + 5 {
+		history.record("present", "example.com.", fmt.Sprintf("_acme-challenge.%d.example.com.", i), ErrNoZone)
+	}
+
+	entries := history.Entries()
+	if len(entries) != DefaultErrorHistory {
+		t.Fatalf("expected %d entries got %d", DefaultErrorHistory, len(entries))
+	}
+
+	// The oldest entries are overwritten first.
+	if first, last := entries[0].FQDN, entries[len(entries)-1].FQDN; first != "_acme-challenge.5.example.com." || last != "_acme-challenge.54.example.com." {
+		t.Errorf("unexpected ring order: first %s last %s", first, last)
+	}
+
+	if entries[0].Zone != "example.com." || entries[0].Class != ErrorClassNotFound {
+		t.Errorf("expected zone and class to be recorded, got %+v", entries[0])
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{&linodego.Error{Code: 500}, ErrorClassLinodeAPI},
+		{fmt.Errorf("wrapped: %w", &linodego.Error{Code: 1}), ErrorClassLinodeNetwork},
+		{fmt.Errorf("failed to get secret: %w", k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "linode")), ErrorClassKubernetes},
+		{fmt.Errorf("%w: timeout", ErrNotPropagated), ErrorClassDNS},
+		{ErrInvalidSecretReference, ErrorClassConfig},
+		{ErrOverloaded, ErrorClassOverloaded},
+		{errors.New("boom"), ErrorClassOther},
+	}
+
+	for _, tc := range tests {
+		if class := errorClass(tc.err); class != tc.expected {
+			t.Errorf("expected class %q for %v got %q", tc.expected, tc.err, class)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package acme
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// Zone hygiene checks count the records in a zone after a challenge is presented and
+// warn when the total number of records or the number of challenge TXT records
+// exceeds the configured thresholds. A growing number of challenge records usually
+// means that records are leaking (e.g. CleanUp is failing or never called), and a
+// large zone is approaching the Linode per-zone record limit, after which challenges
+// can no longer be presented. A warning event is emitted when a zone first exceeds a
+// threshold rather than on every challenge so that a leaking zone does not flood the
+// events; once the zone is back under the thresholds it will warn again.
+type zoneHygiene struct {
+	sync.Mutex
+	maxRecords    int
+	maxChallenges int
+	zones         *lru[int, *ZoneHygiene]
+}
+
+// ZoneHygiene reports the record counts of a zone at its last hygiene check.
+type ZoneHygiene struct {
+	Zone             string    `json:"zone"`
+	Records          int       `json:"records"`
+	ChallengeRecords int       `json:"challengeRecords"`
+	Exceeded         bool      `json:"exceeded"`
+	Warnings         uint64    `json:"warnings"`
+	LastChecked      time.Time `json:"lastChecked"`
+}
+
+// Returns nil if neither threshold is positive, disabling the checks.
+func newZoneHygiene(solver string, maxRecords, maxChallenges int) *zoneHygiene {
+	if maxRecords <= 0 && maxChallenges <= 0 {
+		return nil
+	}
+
+	return &zoneHygiene{
+		maxRecords:    maxRecords,
+		maxChallenges: maxChallenges,
+		zones:         newLRU[int, *ZoneHygiene](solver, "zoneHygiene", DefaultCacheSize, nil),
+	}
+}
+
+// Counts the records in the zone and returns the thresholds that are exceeded if the
+// zone was not already over them at its previous check; a nil checker is a no-op.
+func (h *zoneHygiene) check(zone *linodego.Domain, records []linodego.DomainRecord) (exceeded []string) {
+	if h == nil {
+		return nil
+	}
+
+	var challenges int
+	for _, record := range records {
+		if record.Type == linodego.RecordTypeTXT && isChallengeRecord(record.Name) {
+			challenges++
+		}
+	}
+
+	if h.maxRecords > 0 && len(records) > h.maxRecords {
+		exceeded = append(exceeded, fmt.Sprintf("%d records (threshold %d)", len(records), h.maxRecords))
+	}
+
+	if h.maxChallenges > 0 && challenges > h.maxChallenges {
+		exceeded = append(exceeded, fmt.Sprintf("%d %s TXT records (threshold %d)", challenges, AutomationLabel, h.maxChallenges))
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	stats, ok := h.zones.Get(zone.ID)
+	if !ok {
+		stats = &ZoneHygiene{}
+		h.zones.Add(zone.ID, stats)
+	}
+
+	previously := stats.Exceeded
+	stats.Zone = zone.Domain
+	stats.Records = len(records)
+	stats.ChallengeRecords = challenges
+	stats.Exceeded = len(exceeded) > 0
+	stats.LastChecked = time.Now()
+
+	if previously || !stats.Exceeded {
+		return nil
+	}
+
+	stats.Warnings++
+	return exceeded
+}
+
+// Stats returns the last hygiene check of every recently checked zone; a nil checker
+// returns nil.
+func (h *zoneHygiene) Stats() []ZoneHygiene {
+	if h == nil {
+		return nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	zones := h.zones.Values()
+	stats := make([]ZoneHygiene, 0, len(zones))
+	for _, zone := range zones {
+		stats = append(stats, *zone)
+	}
+	return stats
+}
+
+// Challenge records are named for the challenge label, either at the apex of the zone
+// or for a subdomain (e.g. _acme-challenge.www).
+func isChallengeRecord(name string) bool {
+	return name == AutomationLabel || strings.HasPrefix(name, AutomationLabel+".")
+}
+
+// Checks the hygiene of the zone after a challenge record was presented. Failures are
+// only logged since the challenge itself has been presented.
+func (s *LinodeDNSProviderSolver) checkZoneHygiene(linode *Linode, zone *linodego.Domain) {
+	if s.hygiene == nil {
+		return
+	}
+
+	records, err := linode.listZoneRecords(zone.ID)
+	if err != nil {
+		klog.Warningf("could not list records of zone %s to check its hygiene: %v", zone.Domain, err)
+		return
+	}
+
+	if exceeded := s.hygiene.check(zone, records); len(exceeded) > 0 {
+		message := fmt.Sprintf("Zone %s (ID %d) has %s; challenge records may be leaking or the zone is approaching the Linode record limit", zone.Domain, zone.ID, strings.Join(exceeded, " and "))
+		klog.Warning(message)
+		s.emitPodEvent("ZoneHygiene", message)
+	}
+}
@@ -0,0 +1,89 @@
+package acme
+
+import (
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+)
+
+func TestZoneHygiene(t *testing.T) {
+	t.Setenv("POD_NAME", "acme-linode-0")
+	solver, fake := newTestSolver(t)
+	solver.hygiene = newZoneHygiene("test", 0, 2)
+	defer caches.Delete(cacheKey{solver: "test", name: "zoneHygiene"})
+
+	zone := fake.AddDomain("example.com")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeA, Name: "www", Target: "192.0.2.1"})
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge.www", Target: "leaked"})
+
+	present := func(fqdn, key string) {
+		t.Helper()
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: fqdn, ResolvedZone: "example.com.", ResourceNamespace: "default", Key: key}
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	warnings := func() int {
+		t.Helper()
+		return podEvents(t, solver, "ZoneHygiene")
+	}
+
+	// Two challenge records are within the threshold.
+	present("_acme-challenge.example.com.", "key1")
+	if n := warnings(); n != 0 {
+		t.Fatalf("expected no hygiene warnings, got %d", n)
+	}
+
+	// The third challenge record exceeds the threshold and warns once.
+	present("_acme-challenge.api.example.com.", "key2")
+	present("_acme-challenge.app.example.com.", "key3")
+	if n := warnings(); n != 1 {
+		t.Fatalf("expected one hygiene warning, got %d", n)
+	}
+
+	stats := solver.hygiene.Stats()
+	if len(stats) != 1 || stats[0].Zone != "example.com" || stats[0].Records != 5 || stats[0].ChallengeRecords != 4 || !stats[0].Exceeded || stats[0].Warnings != 1 {
+		t.Errorf("unexpected zone hygiene %+v", stats)
+	}
+}
+
+func TestZoneHygieneThresholds(t *testing.T) {
+	if newZoneHygiene("test", 0, 0) != nil {
+		t.Error("expected hygiene checks to be disabled without thresholds")
+	}
+
+	hygiene := newZoneHygiene("test", 3, 0)
+	defer caches.Delete(cacheKey{solver: "test", name: "zoneHygiene"})
+
+	zone := &linodego.Domain{ID: 1, Domain: "example.com"}
+	records := []linodego.DomainRecord{
+		{Type: linodego.RecordTypeA, Name: "www"},
+		{Type: linodego.RecordTypeTXT, Name: "_acme-challenge"},
+		{Type: linodego.RecordTypeTXT, Name: "_acme-challengex"},
+	}
+
+	if exceeded := hygiene.check(zone, records); exceeded != nil {
+		t.Errorf("expected no thresholds to be exceeded, got %v", exceeded)
+	}
+
+	records = append(records, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "spf"})
+	if exceeded := hygiene.check(zone, records); len(exceeded) != 1 {
+		t.Errorf("expected the record threshold to be exceeded, got %v", exceeded)
+	}
+
+	// A zone that is still over the threshold does not warn again until it recovers.
+	if exceeded := hygiene.check(zone, records); exceeded != nil {
+		t.Errorf("expected no repeated warning, got %v", exceeded)
+	}
+
+	hygiene.check(zone, records[:2])
+	if exceeded := hygiene.check(zone, records); len(exceeded) != 1 {
+		t.Errorf("expected a warning after the zone recovered, got %v", exceeded)
+	}
+
+	if stats := hygiene.Stats(); len(stats) != 1 || stats[0].ChallengeRecords != 1 || stats[0].Warnings != 2 {
+		t.Errorf("unexpected zone hygiene %+v", stats)
+	}
+}
@@ -0,0 +1,29 @@
+package acme
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// The IDNA profile with which the names of zones and records are converted to their
+// ASCII form. Unlike the lookup profile of the idna package, it allows the underscores
+// of challenge names such as _acme-challenge.
+var idnaProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false), idna.Transitional(false))
+
+// Returns the lowercased ASCII (punycode) form of the domain name without a trailing
+// dot, e.g. xn--bcher-kva.example for bücher.example., so that the names of IDN zones
+// compare equal whether cert-manager, the issuer, or Linode spells them in Unicode or
+// in punycode. Names that are not valid IDNs are only lowercased.
+func asciiName(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	if ascii, err := idnaProfile.ToASCII(name); err == nil {
+		return ascii
+	}
+	return name
+}
+
+// Returns true if the domain names are the same name after IDNA conversion.
+func sameName(a, b string) bool {
+	return asciiName(a) == asciiName(b)
+}
@@ -0,0 +1,67 @@
+package acme
+
+import (
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestASCIIName(t *testing.T) {
+	testCases := map[string]string{
+		"example.com.":                   "example.com",
+		"Bücher.Example.":                "xn--bcher-kva.example",
+		"xn--bcher-kva.example":          "xn--bcher-kva.example",
+		"_acme-challenge.münchen.de.":    "_acme-challenge.xn--mnchen-3ya.de",
+		"_acme-challenge.xn--zz.example": "_acme-challenge.xn--zz.example",
+		"":                               "",
+	}
+
+	for name, expected := range testCases {
+		if actual := asciiName(name); actual != expected {
+			t.Errorf("expected the ascii name of %q to be %q, got %q", name, expected, actual)
+		}
+	}
+
+	entry, domain := DomainEntry("_acme-challenge.www.bücher.example.", "xn--bcher-kva.example.")
+	if entry != "_acme-challenge.www" || domain != "xn--bcher-kva.example" {
+		t.Errorf("unexpected entry %q and domain %q", entry, domain)
+	}
+}
+
+func TestIDNZone(t *testing.T) {
+	solver, fake := newTestSolver(t)
+
+	// Linode stores the names of IDN zones and records in their punycode form, while
+	// cert-manager may resolve either form.
+	zone := fake.AddDomain("xn--bcher-kva.example")
+
+	testCases := []struct {
+		fqdn, zone string
+	}{
+		{"_acme-challenge.bücher.example.", "bücher.example."},
+		{"_acme-challenge.www.xn--bcher-kva.example.", "bücher.example."},
+		{"_acme-challenge.shop.bücher.example.", ""},
+	}
+
+	for i, tc := range testCases {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: tc.fqdn, ResolvedZone: tc.zone, ResourceNamespace: "default", Key: "key1"}
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("%d: could not present challenge: %v", i, err)
+		}
+
+		entry, _ := DomainEntry(tc.fqdn, "bücher.example")
+		assertTargets(t, fake, zone.ID, entry, "key1")
+
+		// The record is found again to be cleaned up.
+		if err := solver.CleanUp(ch); err != nil {
+			t.Fatalf("%d: could not clean up challenge: %v", i, err)
+		}
+		assertTargets(t, fake, zone.ID, entry)
+	}
+
+	linode, fake := newTestLinode(t)
+	zone = fake.AddDomain("xn--bcher-kva.example")
+	if found, err := linode.FindZone("Bücher.example"); err != nil || found.ID != zone.ID {
+		t.Errorf("expected to find the zone by its unicode name, got %+v: %v", found, err)
+	}
+}
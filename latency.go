@@ -0,0 +1,108 @@
+package acme
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// The number of recent Linode API latencies used to adapt the timeouts.
+	latencyWindowSize = 64
+
+	// The minimum number of observed latencies before timeouts are adapted.
+	latencyMinSamples = 8
+
+	// Timeouts are extended to this multiple of the recent 95th percentile latency...
+	AdaptiveTimeoutFactor = 4
+
+	// ...but never beyond this multiple of the configured timeout.
+	AdaptiveTimeoutBudget = 3
+)
+
+// The latencies of recent Linode API requests across all clients, since a new client
+// is created for every challenge.
+var linodeLatency = &latencyWindow{}
+
+// SetAdaptiveTimeouts extends the timeout of each Linode API operation when recent
+// requests have been slow but are still completing, so that a degraded but working
+// API does not cause spurious deadline exceeded errors. The timeout is extended to a
+// multiple of the recent 95th percentile latency, at most AdaptiveTimeoutBudget times
+// the configured timeout, and is never shortened.
+func (l *Linode) SetAdaptiveTimeouts(enabled bool) *Linode {
+	if enabled && !l.adaptive {
+		l.client.OnAfterResponse(func(rep *linodego.Response) error {
+			// Server errors are not progress; rate limited requests are not slow.
+			if code := rep.StatusCode(); code < 500 && code != 429 {
+				linodeLatency.observe(rep.Time())
+			}
+			return nil
+		})
+	}
+
+	l.adaptive = enabled
+	return l
+}
+
+// Returns the timeout of the next Linode API operation with the configured timeout.
+func (l *Linode) operationTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	if l.adaptive {
+		if adapted := linodeLatency.timeout(timeout); adapted > timeout {
+			klog.V(2).Infof("linode API is slow: extending operation timeout from %s to %s", timeout, adapted)
+			return adapted
+		}
+	}
+	return timeout
+}
+
+// A fixed size ring of recent request latencies.
+type latencyWindow struct {
+	sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) observe(latency time.Duration) {
+	w.Lock()
+	defer w.Unlock()
+
+	if len(w.samples) < latencyWindowSize {
+		w.samples = append(w.samples, latency)
+		return
+	}
+
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+}
+
+// Returns the adapted timeout for the configured timeout, which is the configured
+// timeout unless enough latencies have been observed to extend it.
+func (w *latencyWindow) timeout(configured time.Duration) time.Duration {
+	w.Lock()
+	if len(w.samples) < latencyMinSamples {
+		w.Unlock()
+		return configured
+	}
+
+	sorted := slices.Clone(w.samples)
+	w.Unlock()
+
+	slices.Sort(sorted)
+	p95 := sorted[(len(sorted)*95-1)/100]
+
+	adapted := min(p95*AdaptiveTimeoutFactor, configured*AdaptiveTimeoutBudget)
+	return max(adapted, configured)
+}
+
+func (w *latencyWindow) reset() {
+	w.Lock()
+	defer w.Unlock()
+	w.samples, w.next = nil, 0
+}
@@ -0,0 +1,57 @@
+package acme
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeouts(t *testing.T) {
+	linodeLatency.reset()
+	defer linodeLatency.reset()
+
+	linode, fake := newTestLinode(t)
+	linode.SetTimeout(100 * time.Millisecond).SetAdaptiveTimeouts(true)
+	fake.AddDomain("example.com")
+
+	// Timeouts are not adapted until enough requests have been observed.
+	for range latencyMinSamples {
+		if timeout := linode.operationTimeout(linode.timeout); timeout != 100*time.Millisecond {
+			t.Fatalf("expected the configured timeout, got %s", timeout)
+		}
+
+		if _, err := linode.FindZone("example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fast requests do not change the timeout.
+	if n := len(linodeLatency.samples); n != latencyMinSamples {
+		t.Fatalf("expected %d observed latencies, got %d", latencyMinSamples, n)
+	}
+
+	if timeout := linode.operationTimeout(linode.timeout); timeout != 100*time.Millisecond {
+		t.Errorf("expected the configured timeout with fast requests, got %s", timeout)
+	}
+
+	// Slow requests extend the timeout up to the budget.
+	for range latencyWindowSize {
+		linodeLatency.observe(40 * time.Millisecond)
+	}
+
+	if timeout := linode.operationTimeout(linode.timeout); timeout != 160*time.Millisecond {
+		t.Errorf("expected the timeout to be extended to 160ms, got %s", timeout)
+	}
+
+	for range latencyWindowSize {
+		linodeLatency.observe(time.Second)
+	}
+
+	if timeout := linode.operationTimeout(linode.timeout); timeout != 300*time.Millisecond {
+		t.Errorf("expected the timeout to be limited to the budget, got %s", timeout)
+	}
+
+	// Timeouts are not adapted unless enabled.
+	if timeout := linode.SetAdaptiveTimeouts(false).operationTimeout(linode.timeout); timeout != 100*time.Millisecond {
+		t.Errorf("expected the configured timeout when disabled, got %s", timeout)
+	}
+}
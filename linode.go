@@ -2,138 +2,812 @@ package acme
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/linode/linodego"
 	"golang.org/x/oauth2"
+	"golang.org/x/time/rate"
 	"k8s.io/klog/v2"
 )
 
-const DefaultTimeout = 90 * time.Second
+const (
+	DefaultTimeout = 90 * time.Second
+	DefaultTTL     = 180
+)
+
+// The TTL values accepted by the Linode API; any other value is rounded up to the
+// nearest valid value when the record is stored.
+var validTTLs = []int{0, 30, 120, 300, 3600, 7200, 14400, 28800, 57600, 86400, 172800, 345600, 604800, 1209600, 2419200}
+
+// How many times, and how often, a created or updated record is read back before it
+// is considered missing or mismatched.
+var (
+	ConfirmRecordAttempts = 5
+	ConfirmRecordInterval = 500 * time.Millisecond
+)
 
 var (
-	Weight    int    = 1
-	Port      int    = 0
-	Priority  int    = 0
 	UserAgent string = fmt.Sprintf("go.rtnl.ai/acme-linode/%s github.com/linode/linodego/%s", Version(true), linodego.Version)
 )
 
 // Wraps the linode API client with DNS specific methods used by the solver.
 type Linode struct {
-	client linodego.Client
+	client        linodego.Client
+	ctx           context.Context
+	ttl           int
+	timeout       time.Duration
+	writeTimeout  time.Duration
+	confirmEvents bool
+	audit         *AuditLog
+	auditCaller   AuditCaller
+	auditZones    sync.Map
+	domainIDs     []int
+	domainTag     string
+	adaptive      bool
+	memo          *lookupMemo
+	account       string
+	domains       *domainCache
+	limiter       *rate.Limiter
+	zones         *zoneCache
+	baseURL       string
 }
 
 // Creates a new Linode API client using the provided API key.
 func NewLinode(apiKey string) *Linode {
-	lin := &Linode{
-		client: linodego.NewClient(&http.Client{
+	return &Linode{
+		client: newLinodeClient(&http.Client{
 			Transport: &oauth2.Transport{
 				Source: oauth2.StaticTokenSource(&oauth2.Token{
 					AccessToken: apiKey,
 				}),
 			},
 		}),
+		account: accountKey(apiKey),
+	}
+}
+
+// NewLinodeWithHTTPClient creates a new Linode API client that sends requests with the
+// provided http client, e.g. a client instrumented or made resilient by an organization's
+// standard libraries, authenticating them with the API key. If the client retries
+// requests itself, disable the built-in retries with SetMaxRetries(0). NewLinode is used
+// if the http client is nil.
+func NewLinodeWithHTTPClient(apiKey string, hc *http.Client) *Linode {
+	if hc == nil {
+		return NewLinode(apiKey)
 	}
 
-	lin.client.SetUserAgent(UserAgent)
+	lin := &Linode{client: newLinodeClient(hc), account: accountKey(apiKey)}
+	lin.client.SetToken(apiKey)
 	return lin
 }
 
+// NewLinodeWithClient creates a new Linode API client that sends requests with the
+// provided linodego client, e.g. a client with a mocked transport in tests or one
+// configured by a downstream consumer. The client is used as it is configured: its
+// token, base URL, and retries are not changed, and the additional retry conditions
+// and User-Agent of NewLinode are not added. The API key is only used, hashed, to
+// share state such as rate limits and cached zones between the clients of the same
+// token; it may be empty if the client is the only one of its account.
+func NewLinodeWithClient(client linodego.Client, apiKey string) *Linode {
+	lin := &Linode{client: client}
+	if apiKey != "" {
+		lin.account = accountKey(apiKey)
+	}
+	return lin
+}
+
+// Identifies the Linode account (strictly, the token) of a client without retaining
+// the token so that state can be shared between the clients for the same account.
+func accountKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SetBaseURL sets the base URL of the Linode API, e.g. for a regional or proxied
+// endpoint; if the url is empty then the default (or $LINODE_URL) is used.
+func (l *Linode) SetBaseURL(url string) *Linode {
+	if url != "" {
+		l.client.SetBaseURL(url)
+		l.baseURL = url
+	}
+	return l
+}
+
+// SetUserAgentSuffix appends an identifier of the deployment to the User-Agent of the
+// requests, e.g. to distinguish cert-manager installations in the Linode API logs; if
+// the suffix is empty then UserAgent is sent unchanged.
+func (l *Linode) SetUserAgentSuffix(suffix string) *Linode {
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		l.client.SetUserAgent(UserAgent + " " + suffix)
+	}
+	return l
+}
+
+// SetTTL sets the TTL of the TXT records that are created or updated; if the ttl
+// is not positive then DefaultTTL is used.
+func (l *Linode) SetTTL(ttl int) *Linode {
+	l.ttl = ttl
+	return l
+}
+
+// SetTimeout sets the timeout of each Linode API operation; if the timeout is not
+// positive then DefaultTimeout is used.
+func (l *Linode) SetTimeout(timeout time.Duration) *Linode {
+	l.timeout = timeout
+	return l
+}
+
+// SetWriteTimeout sets the timeout of the operations that create, update, or delete
+// records, e.g. if writes take longer than reads; if the timeout is not positive then
+// the timeout of SetTimeout is used.
+func (l *Linode) SetWriteTimeout(timeout time.Duration) *Linode {
+	l.writeTimeout = timeout
+	return l
+}
+
+// SetContext sets the parent context of each Linode API operation so that the
+// operations are canceled with it; by default operations are not canceled.
+func (l *Linode) SetContext(ctx context.Context) *Linode {
+	l.ctx = ctx
+	return l
+}
+
+// SetDomainSelector restricts the domains that challenges are solved in to the domains
+// with one of the IDs and the tag, so that the zone can be chosen when the account has
+// more than one domain with the same name; empty values do not restrict the domains.
+func (l *Linode) SetDomainSelector(ids []int, tag string) *Linode {
+	l.domainIDs, l.domainTag = ids, tag
+	return l
+}
+
+// SetMaxRetries sets the number of times a failed Linode API request is retried.
+func (l *Linode) SetMaxRetries(retries int) *Linode {
+	l.client.SetRetryCount(retries)
+	return l
+}
+
+// RetryPolicy customizes the built-in retries of failed Linode API requests. Zero
+// values keep the linodego defaults.
+type RetryPolicy struct {
+	// The number of times a failed request is retried; negative values disable retries.
+	MaxRetries int
+
+	// The minimum and maximum time to wait between retries.
+	MinWait time.Duration
+	MaxWait time.Duration
+
+	// Additional conditions under which a request is retried; requests that are rate
+	// limited or fail because Linode is unavailable are always retried.
+	Conditions []RetryCondition
+}
+
+// RetryCondition reports whether a failed request should be retried; the response is
+// nil if the request failed before a response was received.
+type RetryCondition func(rep *http.Response, err error) bool
+
+// SetRetryPolicy applies the retry policy to the client; a nil policy is ignored.
+func (l *Linode) SetRetryPolicy(policy *RetryPolicy) *Linode {
+	if policy == nil {
+		return l
+	}
+
+	switch {
+	case policy.MaxRetries < 0:
+		l.client.SetRetryCount(0)
+	case policy.MaxRetries > 0:
+		l.client.SetRetryCount(policy.MaxRetries)
+	}
+
+	if policy.MinWait > 0 {
+		l.client.SetRetryWaitTime(policy.MinWait)
+	}
+
+	if policy.MaxWait > 0 {
+		l.client.SetRetryMaxWaitTime(policy.MaxWait)
+	}
+
+	for _, condition := range policy.Conditions {
+		l.client.AddRetryCondition(func(rep *resty.Response, err error) bool {
+			if rep == nil {
+				return condition(nil, err)
+			}
+			return condition(rep.RawResponse, err)
+		})
+	}
+	return l
+}
+
 // Returns the Linode Zone object that matches the provided domain name.
 func (l *Linode) FindZone(domain string) (zone *linodego.Domain, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancel()
-
 	var zones []linodego.Domain
-	if zones, err = l.client.ListDomains(ctx, linodego.NewListOptions(0, "")); err != nil {
+	if zones, err = l.findDomains(asciiName(domain)); err != nil {
 		return nil, err
 	}
 
-	// Find the zone that matches the domain
-	for _, zone := range zones {
-		if zone.Domain == domain {
-			return &zone, nil
+	// Find the zone that matches the domain, comparing IDNs in their ASCII form
+	var matches []*linodego.Domain
+	for i := range zones {
+		if sameName(zones[i].Domain, domain) {
+			matches = append(matches, &zones[i])
+		}
+	}
+
+	if zone, err = l.selectDomain(domain, matches); err != nil || zone != nil {
+		return zone, err
+	}
+	return nil, fmt.Errorf("%w for domain %q", ErrNoZone, domain)
+}
+
+// FindZoneForFQDN returns the Linode Zone that is the closest parent of the fully
+// qualified domain name along with the entry (record name) of the fqdn in the zone.
+func (l *Linode) FindZoneForFQDN(fqdn string) (zone *linodego.Domain, entry string, err error) {
+	return l.FindCandidateZone(fqdn, "")
+}
+
+// FindCandidateZone returns the Linode Zone that hosts the fully qualified domain name
+// along with the entry (record name) of the fqdn in the zone. The preferred zone (e.g.
+// the zone resolved by cert-manager) is tried first if it is not empty, followed by each
+// parent domain of the fqdn in order of specificity, so that the record is created in
+// the zone that actually hosts it when the preferred zone is not in the Linode account.
+// If no candidate is hosted, the returned ErrNoZone reports the zones that were tried.
+func (l *Linode) FindCandidateZone(fqdn, preferred string) (zone *linodego.Domain, entry string, err error) {
+	name := asciiName(fqdn)
+	candidates := candidateZones(name, asciiName(preferred))
+
+	// All of the candidates are fetched with a single filtered request.
+	var zones []linodego.Domain
+	if zones, err = l.findDomains(candidates...); err != nil {
+		return nil, "", err
+	}
+
+	hosted := make(map[string][]*linodego.Domain, len(zones))
+	for i := range zones {
+		name := asciiName(zones[i].Domain)
+		hosted[name] = append(hosted[name], &zones[i])
+	}
+
+	tried := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		tried = append(tried, candidate)
+		if zone, err = l.selectDomain(candidate, hosted[candidate]); err != nil {
+			return nil, "", err
+		}
+
+		if zone != nil {
+			if len(tried) > 1 {
+				klog.Infof("found zone %s for fqdn %s after trying %s", zone.Domain, fqdn, strings.Join(tried[:len(tried)-1], ", "))
+			}
+
+			entry, _ = DomainEntry(name, candidate)
+			return zone, entry, nil
 		}
 	}
 
-	return nil, fmt.Errorf("no zone found for domain %q", domain)
+	return nil, "", fmt.Errorf("%w for fqdn %q (tried %s)", ErrNoZone, fqdn, strings.Join(tried, ", "))
+}
+
+// Returns the domain selected from the domains in the account with the same name, or
+// nil if none of them match the domain selector. Rather than silently choosing one,
+// ErrAmbiguousZone lists the candidates if more than one domain is selected, and
+// ErrSecondaryZone is returned if the selected domain is a secondary zone.
+func (l *Linode) selectDomain(name string, domains []*linodego.Domain) (*linodego.Domain, error) {
+	selected := make([]*linodego.Domain, 0, len(domains))
+	for _, domain := range domains {
+		if len(l.domainIDs) > 0 && !slices.Contains(l.domainIDs, domain.ID) {
+			continue
+		}
+
+		if l.domainTag != "" && !slices.Contains(domain.Tags, l.domainTag) {
+			continue
+		}
+		selected = append(selected, domain)
+	}
+
+	switch len(selected) {
+	case 0:
+		if len(domains) > 0 {
+			klog.V(2).Infof("none of the %d domains named %s match the domain selector", len(domains), name)
+		}
+		return nil, nil
+	case 1:
+		// Linode rejects records created in secondary zones, which are transferred from
+		// their primary nameservers, so retrying the challenge would never succeed.
+		if domain := selected[0]; domain.Type == linodego.DomainTypeSlave {
+			return nil, fmt.Errorf("%w: domain %q (id %d) is transferred from %s", ErrSecondaryZone, name, domain.ID, strings.Join(domain.MasterIPs, ", "))
+		}
+
+		// Remember the name of the zone for the audit events of its mutations.
+		l.auditZones.Store(selected[0].ID, selected[0].Domain)
+		return selected[0], nil
+	}
+
+	candidates := make([]string, 0, len(selected))
+	for _, domain := range selected {
+		candidates = append(candidates, fmt.Sprintf("id %d tags [%s]", domain.ID, strings.Join(domain.Tags, ", ")))
+	}
+	return nil, fmt.Errorf("%w: found %d domains named %q (%s); select one with domainIDs or domainTag", ErrAmbiguousZone, len(selected), name, strings.Join(candidates, "; "))
+}
+
+// Returns the preferred zone, if it contains the name, followed by the name and each of
+// its parent domains from the most to the least specific.
+func candidateZones(name, preferred string) (candidates []string) {
+	if preferred != "" && (name == preferred || strings.HasSuffix(name, "."+preferred)) {
+		candidates = append(candidates, preferred)
+	}
+
+	for domain := name; domain != ""; {
+		if domain != preferred {
+			candidates = append(candidates, domain)
+		}
+
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return candidates
 }
 
-// Returns the Linode DNS Record object that matches the provided parameters.
+// Returns the first TXT record in the Linode Zone whose name matches the entry. Use
+// FindRecords to reason about every challenge record of the entry, since there may be
+// a record for each in-flight challenge of the name.
 func (l *Linode) FindRecord(zoneID int, entry string) (record *linodego.DomainRecord, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancel()
+	var records []linodego.DomainRecord
+	if records, err = l.FindRecords(zoneID, entry); err != nil {
+		return nil, err
+	}
 
+	if len(records) == 0 {
+		return nil, ErrNoRecord
+	}
+	return &records[0], nil
+}
+
+// FindRecordByValue returns the first TXT record in the Linode Zone whose name matches
+// the entry and whose target is the value, or ErrNoRecord if there is none.
+func (l *Linode) FindRecordByValue(zoneID int, entry, value string) (record *linodego.DomainRecord, err error) {
 	var records []linodego.DomainRecord
-	if records, err = l.client.ListDomainRecords(ctx, zoneID, linodego.NewListOptions(0, "")); err != nil {
+	if records, err = l.FindRecords(zoneID, entry); err != nil {
 		return nil, err
 	}
 
-	// Find the record that matches the entry
+	for i := range records {
+		if records[i].Target == value {
+			return &records[i], nil
+		}
+	}
+	return nil, ErrNoRecord
+}
+
+// FindRecords returns all of the TXT records in the Linode Zone whose name matches the
+// entry; if there are none the slice is empty and the error is nil. Internationalized
+// entries match the records named with their ASCII form.
+func (l *Linode) FindRecords(zoneID int, entry string) (matches []linodego.DomainRecord, err error) {
+	var records []linodego.DomainRecord
+	if records, err = l.listEntryRecords(zoneID, recordEntry(entry)); err != nil {
+		return nil, err
+	}
+
+	// Find the records that match the entry, which the API may not have filtered
 	for _, record := range records {
-		if record.Name == entry && record.Type == "TXT" {
-			return &record, nil
+		if sameEntry(record.Name, entry) && record.Type == linodego.RecordTypeTXT {
+			matches = append(matches, record)
 		}
 	}
+	return matches, nil
+}
 
-	return nil, ErrNoRecord
+// Linode names the records at the apex of a zone, e.g. for a challenge name that is a
+// zone of its own, with an empty name, which zone files and other clients spell "@".
+func isApex(entry string) bool {
+	entry = strings.TrimSpace(entry)
+	return entry == "" || entry == "@"
+}
+
+// Returns the name of the entry as Linode stores it: empty at the apex and otherwise
+// the lowercased ASCII form of the entry.
+func recordEntry(entry string) string {
+	if isApex(entry) {
+		return ""
+	}
+	return asciiName(entry)
+}
+
+// Returns true if the record names are the same entry of a zone.
+func sameEntry(a, b string) bool {
+	return recordEntry(a) == recordEntry(b)
+}
+
+// EnsureTXT ensures that a TXT record for the entry with the specified value exists
+// in the Linode Zone, creating the record if there is none or updating the existing
+// record otherwise. At the apex of the zone, which usually holds other TXT records
+// (e.g. SPF or site verification records), existing records are never updated and a
+// record is created for the value instead. Duplicate records with the same name and
+// value (e.g. left behind by an earlier race) are removed. Calls for the same zone
+// are serialized so that concurrent challenges cannot interleave their lookups and
+// mutations. The record that holds the value is returned so that its ID can be
+// tracked by the caller.
+func (l *Linode) EnsureTXT(zoneID int, entry, value string) (record *linodego.DomainRecord, err error) {
+	unlock := lockZone(zoneID)
+	defer unlock()
+
+	var records []linodego.DomainRecord
+	if records, err = l.FindRecords(zoneID, entry); err != nil {
+		klog.Errorf("failed to find record %q in linode zone ID %d: %v", entry, zoneID, err)
+		return nil, err
+	}
+
+	// Record does not exist, create it
+	if len(records) == 0 {
+		return l.CreateRecord(zoneID, entry, value)
+	}
+
+	// If a record already has the value, keep the first one and remove any duplicates.
+	for i := range records {
+		if records[i].Target != value {
+			continue
+		}
+
+		if record == nil {
+			record = &records[i]
+			continue
+		}
+
+		klog.Infof("removing duplicate TXT record %s (ID %d) in zone ID %d", entry, records[i].ID, zoneID)
+		if err = l.DeleteRecord(zoneID, records[i].ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The record is not updated, even if its TTL differs, so repeated presents of the
+	// challenge do not write to the Linode API.
+	if record != nil {
+		klog.V(2).Infof("TXT record %s (ID %d) in zone ID %d already has the value, skipping the update", entry, record.ID, zoneID)
+		return record, nil
+	}
+
+	if isApex(entry) {
+		return l.CreateRecord(zoneID, entry, value)
+	}
+
+	// Otherwise update the existing record with the new value
+	return l.UpdateRecord(zoneID, records[0].ID, records[0].Name, value)
+}
+
+// AppendTXT returns the TXT record for the entry with the value, creating a new record
+// if there is none. Unlike EnsureTXT, records for the entry with other values are
+// never updated or deleted.
+func (l *Linode) AppendTXT(zoneID int, entry, value string) (record *linodego.DomainRecord, err error) {
+	unlock := lockZone(zoneID)
+	defer unlock()
+
+	if record, err = l.FindRecordByValue(zoneID, entry, value); !errors.Is(err, ErrNoRecord) {
+		if err != nil {
+			klog.Errorf("failed to find record %q in linode zone ID %d: %v", entry, zoneID, err)
+		}
+		return record, err
+	}
+	return l.CreateRecord(zoneID, entry, value)
+}
+
+// DeleteTXT deletes the TXT records for the entry whose value matches, leaving any
+// records with other values (e.g. for other in-flight challenges) untouched. If there
+// are no matching records then ErrNoRecord is returned. Unlike EnsureTXT, DeleteTXT
+// does not take the zone lock, which its callers in this package hold so that the
+// lookup and the deletes cannot interleave with other mutations of the zone.
+func (l *Linode) DeleteTXT(zoneID int, entry, value string) (err error) {
+	var records []linodego.DomainRecord
+	if records, err = l.FindRecords(zoneID, entry); err != nil {
+		return err
+	}
+
+	var deleted int
+	for _, record := range records {
+		if record.Target != value {
+			continue
+		}
+
+		if err = l.DeleteRecord(zoneID, record.ID); err != nil {
+			return err
+		}
+		deleted++
+	}
+
+	if deleted == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Returns the Linode DNS Record object with the specified ID in the Linode Zone.
+func (l *Linode) GetRecord(zoneID, recordID int) (record *linodego.DomainRecord, err error) {
+	ctx, cancel := l.context()
+	defer cancel()
+
+	if record, err = l.client.GetDomainRecord(ctx, zoneID, recordID); err != nil {
+		if linodego.IsNotFound(err) {
+			return nil, ErrNoRecord
+		}
+		return nil, apiError(err)
+	}
+	return record, nil
 }
 
 // Creates a new TXT DNS Record in the specified Linode Zone.
-func (l *Linode) CreateRecord(zoneID int, entry, value string) error {
+func (l *Linode) CreateRecord(zoneID int, entry, value string) (_ *linodego.DomainRecord, err error) {
 	klog.Infof("creating TXT record %s in zone ID %d", entry, zoneID)
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	ctx, cancel := l.writeContext()
 	defer cancel()
+	defer l.memo.invalidate(zoneID)
 
-	_, err := l.client.CreateDomainRecord(ctx, zoneID, linodego.DomainRecordCreateOptions{
-		Type:     linodego.RecordTypeTXT,
-		Name:     entry,
-		Target:   value,
-		Priority: &Priority,
-		Weight:   &Weight,
-		Port:     &Port,
-		TTLSec:   180,
-	})
+	var record *linodego.DomainRecord
+	defer func() {
+		event := l.auditEvent(AuditCreateRecord, zoneID)
+		event.Entry, event.Value = entry, value
+		if record != nil {
+			event.RecordID = record.ID
+		}
+		l.audit.Record(event, err)
+	}()
+
+	since := time.Now()
+	opts := l.RecordOptions()
+	record, err = l.client.CreateDomainRecord(ctx, zoneID, opts.createOptions(entry, value))
 
 	if err != nil {
 		klog.Errorf("failed to create TXT record %q in linode zone ID %d: %v", entry, zoneID, err)
+		return nil, l.recordError(zoneID, err)
 	}
-	return err
+
+	// Read the record back to ensure that it was stored as requested.
+	if err = l.confirmEvent(zoneID, record.ID, EventDomainRecordCreate, since); err == nil {
+		err = l.confirmRecord(zoneID, record.ID, entry, value, opts.TTL)
+	}
+
+	if err != nil {
+		klog.Errorf("failed to confirm TXT record %q (ID %d) in linode zone ID %d: %v", entry, record.ID, zoneID, err)
+
+		// Remove the bad record so that it is not left behind when the challenge is retried;
+		// the confirmation may have used up the timeout of the create.
+		dctx, dcancel := l.writeContext()
+		defer dcancel()
+		if derr := l.client.DeleteDomainRecord(dctx, zoneID, record.ID); derr != nil {
+			klog.Errorf("failed to delete unconfirmed TXT record ID %d in linode zone ID %d: %v", record.ID, zoneID, derr)
+		}
+		return nil, err
+	}
+	return record, nil
 }
 
 // Updates an existing TXT DNS Record in the specified Linode Zone.
-func (l *Linode) UpdateRecord(zoneID, recordID int, entry, value string) error {
+func (l *Linode) UpdateRecord(zoneID, recordID int, entry, value string) (_ *linodego.DomainRecord, err error) {
 	klog.Infof("updating TXT record %s (ID %d) in zone ID %d", entry, recordID, zoneID)
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	ctx, cancel := l.writeContext()
 	defer cancel()
+	defer l.memo.invalidate(zoneID)
 
-	_, err := l.client.UpdateDomainRecord(ctx, zoneID, recordID, linodego.DomainRecordUpdateOptions{
-		Type:     linodego.RecordTypeTXT,
-		Name:     entry,
-		Target:   value,
-		Priority: &Priority,
-		Weight:   &Weight,
-		Port:     &Port,
-		TTLSec:   180,
-	})
+	defer func() {
+		event := l.auditEvent(AuditUpdateRecord, zoneID)
+		event.RecordID, event.Entry, event.Value = recordID, entry, value
+		l.audit.Record(event, err)
+	}()
+
+	since := time.Now()
+	opts := l.RecordOptions()
+	record, err := l.client.UpdateDomainRecord(ctx, zoneID, recordID, opts.updateOptions(entry, value))
 
 	if err != nil {
 		klog.Errorf("failed to update TXT record %q (ID %d) in linode zone ID %d: %v", entry, recordID, zoneID, err)
+		return nil, apiError(err)
+	}
+
+	// Read the record back so that Present does not return before it is visible.
+	if err = l.confirmEvent(zoneID, recordID, EventDomainRecordUpdate, since); err == nil {
+		err = l.confirmRecord(zoneID, recordID, entry, value, opts.TTL)
+	}
+
+	if err != nil {
+		klog.Errorf("failed to confirm TXT record %q (ID %d) in linode zone ID %d: %v", entry, recordID, zoneID, err)
+		return nil, err
 	}
-	return err
+	return record, nil
 }
 
 // Deletes the specified TXT DNS Record from the Linode Zone.
-func (l *Linode) DeleteRecord(zoneID, recordID int) error {
+func (l *Linode) DeleteRecord(zoneID, recordID int) (err error) {
 	klog.Infof("deleting TXT record ID %d in zone ID %d", recordID, zoneID)
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	ctx, cancel := l.writeContext()
 	defer cancel()
+	defer l.memo.invalidate(zoneID)
 
-	err := l.client.DeleteDomainRecord(ctx, zoneID, recordID)
-	if err != nil {
+	defer func() {
+		event := l.auditEvent(AuditDeleteRecord, zoneID)
+		event.RecordID = recordID
+		l.audit.Record(event, err)
+	}()
+
+	since := time.Now()
+	if err = l.client.DeleteDomainRecord(ctx, zoneID, recordID); err != nil {
 		klog.Errorf("failed to delete TXT record ID %d in linode zone ID %d: %v", recordID, zoneID, err)
+		return apiError(err)
+	}
+	return l.confirmEvent(zoneID, recordID, EventDomainRecordDelete, since)
+}
+
+func (l *Linode) context() (context.Context, context.CancelFunc) {
+	parent := l.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	return context.WithTimeout(parent, l.operationTimeout(l.timeout))
+}
+
+// Returns the context of an operation that creates, updates, or deletes a record.
+func (l *Linode) writeContext() (context.Context, context.CancelFunc) {
+	if l.writeTimeout <= 0 {
+		return l.context()
+	}
+
+	parent := l.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	return context.WithTimeout(parent, l.operationTimeout(l.writeTimeout))
+}
+
+func (l *Linode) recordTTL() int {
+	if l.ttl > 0 {
+		return l.ttl
+	}
+	return DefaultTTL
+}
+
+// RecordOptions are the fields of the TXT records created or updated by the Linode
+// client other than their name and value. The options are copied into the request of
+// every call, so that concurrent operations never share the fields that linodego
+// takes by pointer.
+type RecordOptions struct {
+	TTL      int
+	Priority int
+	Weight   int
+	Port     int
+}
+
+// RecordOptions returns the options of the records created or updated by the client.
+func (l *Linode) RecordOptions() RecordOptions {
+	return RecordOptions{TTL: l.recordTTL(), Weight: 1}
+}
+
+func (o RecordOptions) createOptions(entry, value string) linodego.DomainRecordCreateOptions {
+	return linodego.DomainRecordCreateOptions{
+		Type:     linodego.RecordTypeTXT,
+		Name:     recordEntry(entry),
+		Target:   value,
+		Priority: &o.Priority,
+		Weight:   &o.Weight,
+		Port:     &o.Port,
+		TTLSec:   o.TTL,
+	}
+}
+
+func (o RecordOptions) updateOptions(entry, value string) linodego.DomainRecordUpdateOptions {
+	return linodego.DomainRecordUpdateOptions{
+		Type:     linodego.RecordTypeTXT,
+		Name:     recordEntry(entry),
+		Target:   value,
+		Priority: &o.Priority,
+		Weight:   &o.Weight,
+		Port:     &o.Port,
+		TTLSec:   o.TTL,
+	}
+}
+
+// RecordAge returns how long ago the record was last created or updated, or false
+// if the Linode API did not return the record's timestamps.
+func RecordAge(record *linodego.DomainRecord) (time.Duration, bool) {
+	switch {
+	case record.Updated != nil:
+		return time.Since(*record.Updated), true
+	case record.Created != nil:
+		return time.Since(*record.Created), true
+	default:
+		return 0, false
+	}
+}
+
+// Zone locks serialize mutations to a single Linode Zone across all clients since a
+// new client is created for every challenge request. The locks are held in a bounded
+// cache so that serving thousands of zones does not grow memory without bound; locks
+// that are held or awaited are never evicted.
+var zoneLocks = newLRU[int]("", "zoneLocks", DefaultCacheSize, func(zl *zoneLock) bool {
+	return zl.refs > 0
+})
+
+type zoneLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Acquires the lock for the specified zone, returning the function to release it.
+func lockZone(zoneID int) func() {
+	// The reference count is guarded by the cache lock so the zone lock cannot be
+	// evicted between being looked up and being acquired.
+	zoneLocks.Lock()
+	zl, ok := zoneLocks.get(zoneID)
+	if !ok {
+		zl = &zoneLock{}
+		zoneLocks.add(zoneID, zl)
+	}
+	zl.refs++
+	zoneLocks.Unlock()
+
+	zl.Lock()
+	return func() {
+		zl.Unlock()
+		zoneLocks.Lock()
+		zl.refs--
+		zoneLocks.Unlock()
+	}
+}
+
+// Fetches the record by ID and verifies that the name, target, and TTL match what
+// was requested, catching silent truncation or inconsistencies in the Linode API. The
+// Linode API is eventually consistent, so a record that is not found or does not
+// match yet is read again up to ConfirmRecordAttempts times.
+func (l *Linode) confirmRecord(zoneID, recordID int, entry, value string, ttl int) (err error) {
+	parent := l.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err = l.checkRecord(zoneID, recordID, entry, value, ttl); err == nil {
+			return nil
+		}
+
+		if attempt >= ConfirmRecordAttempts || (!errors.Is(err, ErrNoRecord) && !errors.Is(err, ErrRecordMismatch)) {
+			return err
+		}
+
+		klog.V(2).Infof("TXT record ID %d in zone ID %d is not visible yet (attempt %d): %v", recordID, zoneID, attempt, err)
+		select {
+		case <-parent.Done():
+			return err
+		case <-time.After(ConfirmRecordInterval):
+		}
+	}
+}
+
+func (l *Linode) checkRecord(zoneID, recordID int, entry, value string, ttl int) (err error) {
+	var record *linodego.DomainRecord
+	if record, err = l.GetRecord(zoneID, recordID); err != nil {
+		return err
+	}
+
+	switch {
+	case !sameEntry(record.Name, entry):
+		return fmt.Errorf("%w: expected name %q got %q", ErrRecordMismatch, entry, record.Name)
+	case record.Target != value:
+		return fmt.Errorf("%w: expected target %q got %q", ErrRecordMismatch, value, record.Target)
+	case ttl > 0 && record.TTLSec != linodeTTL(ttl):
+		return fmt.Errorf("%w: expected ttl %d got %d", ErrRecordMismatch, linodeTTL(ttl), record.TTLSec)
+	}
+	return nil
+}
+
+// Returns the TTL that Linode will store for the requested TTL.
+func linodeTTL(ttl int) int {
+	for _, valid := range validTTLs {
+		if ttl <= valid {
+			return valid
+		}
 	}
-	return err
+	return validTTLs[len(validTTLs)-1]
 }
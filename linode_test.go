@@ -0,0 +1,844 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestFindZone(t *testing.T) {
+	linode, fake := newTestLinode(t)
+
+	// Create enough domains that the zone is on the third page of results.
+	for i := range 250 {
+		fake.AddDomain(fmt.Sprintf("example%03d.com", i))
+	}
+	expected := fake.AddDomain("example.com")
+
+	zone, err := linode.FindZone("example.com")
+	if err != nil {
+		t.Fatalf("could not find zone: %v", err)
+	}
+
+	if zone.ID != expected.ID {
+		t.Errorf("expected zone ID %d got %d", expected.ID, zone.ID)
+	}
+
+	// The zone is fetched with a filter rather than by listing every domain.
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected 1 filtered list request, got %d", calls)
+	}
+
+	if _, err = linode.FindZone("example.org"); err == nil {
+		t.Error("expected an error when the zone does not exist")
+	}
+
+	// If the filter is rejected then every domain is listed instead.
+	fake.Fail("GET /v4/domains", linodetest.Fault{Status: http.StatusBadRequest})
+	if zone, err = linode.FindZone("example.com"); err != nil || zone.ID != expected.ID {
+		t.Fatalf("could not find zone without a filter: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 6 {
+		t.Errorf("expected 1 rejected and 3 paginated list requests, got %d", calls-2)
+	}
+}
+
+func TestFindCandidateZone(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	parent := fake.AddDomain("example.com")
+	fake.AddDomain("other.example.com")
+
+	// The resolved zone is not hosted so the closest parent zone is used instead.
+	zone, entry, err := linode.FindCandidateZone("_acme-challenge.www.sub.example.com.", "sub.example.com.")
+	if err != nil {
+		t.Fatalf("could not find candidate zone: %v", err)
+	}
+
+	if zone.ID != parent.ID || entry != "_acme-challenge.www.sub" {
+		t.Errorf("expected entry _acme-challenge.www.sub in zone ID %d got %q in zone ID %d", parent.ID, entry, zone.ID)
+	}
+
+	// The error reports every zone that was tried.
+	_, _, err = linode.FindCandidateZone("_acme-challenge.example.org.", "example.org.")
+	if !errors.Is(err, ErrNoZone) {
+		t.Fatalf("expected ErrNoZone got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "tried example.org, _acme-challenge.example.org, org") {
+		t.Errorf("expected the tried zones to be reported: %v", err)
+	}
+}
+
+func TestDuplicateDomains(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	fake.AddDomain("example.com", "staging")
+	prod := fake.AddDomain("example.com", "production")
+
+	// Duplicate domains are reported rather than silently choosing one.
+	_, _, err := linode.FindCandidateZone("_acme-challenge.example.com.", "example.com.")
+	if !errors.Is(err, ErrAmbiguousZone) {
+		t.Fatalf("expected ErrAmbiguousZone got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), fmt.Sprintf("id %d tags [production]", prod.ID)) {
+		t.Errorf("expected the candidates to be reported: %v", err)
+	}
+
+	// The domain can be selected by tag or ID.
+	for _, selector := range []struct {
+		ids []int
+		tag string
+	}{{nil, "production"}, {[]int{prod.ID}, ""}} {
+		zone, _, err := linode.SetDomainSelector(selector.ids, selector.tag).FindCandidateZone("_acme-challenge.example.com.", "example.com.")
+		if err != nil {
+			t.Fatalf("could not find candidate zone: %v", err)
+		}
+
+		if zone.ID != prod.ID {
+			t.Errorf("expected zone ID %d got %d", prod.ID, zone.ID)
+		}
+	}
+
+	// Domains that are not selected are not used.
+	if _, _, err = linode.SetDomainSelector(nil, "development").FindCandidateZone("_acme-challenge.example.com.", "example.com."); !errors.Is(err, ErrNoZone) {
+		t.Errorf("expected ErrNoZone got %v", err)
+	}
+}
+
+func TestSecondaryZone(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	fake.AddSecondaryDomain("example.com", "192.0.2.1")
+
+	// Secondary zones cannot be changed in Linode, so they are reported rather than
+	// failing when the record is created.
+	_, _, err := linode.FindCandidateZone("_acme-challenge.www.example.com.", "example.com.")
+	if !errors.Is(err, ErrSecondaryZone) {
+		t.Fatalf("expected ErrSecondaryZone got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "192.0.2.1") {
+		t.Errorf("expected the primary nameservers to be reported: %v", err)
+	}
+
+	if _, err = linode.FindZone("example.com"); !errors.Is(err, ErrSecondaryZone) {
+		t.Errorf("expected ErrSecondaryZone got %v", err)
+	}
+
+	if !shouldFallback(err) {
+		t.Error("expected challenges in secondary zones to be delegated to the fallback provider")
+	}
+
+	if class := errorClass(err); class != ErrorClassConfig {
+		t.Errorf("expected the config error class, got %s", class)
+	}
+}
+
+func TestDomainID(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	fake.AddDomain("example.com", "staging")
+	prod := fake.AddDomain("example.com", "production")
+
+	challenge := func(fqdn, zone string) *v1alpha1.ChallengeRequest {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: fqdn, ResolvedZone: zone, ResourceNamespace: "default", Key: "key1"}
+		ch.Config = &extapi.JSON{Raw: []byte(fmt.Sprintf(`{"domainID": %d}`, prod.ID))}
+		return ch
+	}
+
+	// The domain is used as the resolved zone without listing the domains.
+	ch := challenge("_acme-challenge.www.example.com.", "example.com.")
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	assertTargets(t, fake, prod.ID, "_acme-challenge.www", "key1")
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	assertTargets(t, fake, prod.ID, "_acme-challenge.www")
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 0 {
+		t.Errorf("expected the zone not to be looked up, got %d domain listings", calls)
+	}
+
+	// The challenge must be in the zone resolved by cert-manager.
+	if err := solver.Present(challenge("_acme-challenge.example.org.", "example.com.")); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected an invalid config error, got %v", err)
+	}
+
+	for _, config := range []string{`{"domainID": -1}`, `{"domainID": 1234, "domainTag": "production"}`, `{"domainID": 1234, "createZoneIfMissing": true}`} {
+		if _, err := LoadConfig(&extapi.JSON{Raw: []byte(config)}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected %s to be invalid, got %v", config, err)
+		}
+	}
+}
+
+func TestZoneName(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	parent := fake.AddDomain("example.com")
+	acme := fake.AddDomain("acme.example.com")
+
+	// cert-manager resolves the parent zone, which is preferred unless overridden.
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.www.acme.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	ch.Config = &extapi.JSON{Raw: []byte(`{"zoneName": "acme.example.com"}`)}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	assertTargets(t, fake, acme.ID, "_acme-challenge.www", "key1")
+	assertTargets(t, fake, parent.ID, "_acme-challenge.www.acme")
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	assertTargets(t, fake, acme.ID, "_acme-challenge.www")
+
+	// The zone must exist and contain the challenge; its parents are not tried.
+	for zoneName, expected := range map[string]error{"www.acme.example.com": ErrNoZone, "other.example.com": ErrInvalidConfig} {
+		ch.Config = &extapi.JSON{Raw: []byte(`{"zoneName": "` + zoneName + `"}`)}
+		if err := solver.Present(ch); !errors.Is(err, expected) {
+			t.Errorf("expected the challenge in zone %s to fail with %v, got %v", zoneName, expected, err)
+		}
+	}
+
+	if _, err := LoadConfig(&extapi.JSON{Raw: []byte(`{"zoneName": "*.example.com"}`)}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected an invalid zone name to be rejected, got %v", err)
+	}
+}
+
+func TestFindRecord(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	for i := range 210 {
+		fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeA, Name: fmt.Sprintf("host%03d", i), Target: "127.0.0.1"})
+	}
+
+	// The filters should skip non-TXT records and TXT records with other names.
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeCNAME, Name: "_acme-challenge", Target: "example.net"})
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge.www", Target: "key0"})
+	expected := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+
+	record, err := linode.FindRecord(zone.ID, "_acme-challenge")
+	if err != nil {
+		t.Fatalf("could not find record: %v", err)
+	}
+
+	if record.ID != expected.ID || record.Target != "key1" {
+		t.Errorf("expected record %d with target key1, got %d with target %q", expected.ID, record.ID, record.Target)
+	}
+
+	// Only the candidate records are fetched rather than every record in the zone.
+	if calls := fake.Calls("GET /v4/domains/{domainID}/records"); calls != 1 {
+		t.Errorf("expected 1 filtered list request, got %d", calls)
+	}
+
+	if _, err = linode.FindRecord(zone.ID, "_acme-challenge.api"); !errors.Is(err, ErrNoRecord) {
+		t.Errorf("expected ErrNoRecord got %v", err)
+	}
+
+	// If the filter is rejected then every record in the zone is listed instead.
+	fake.Fail("GET /v4/domains/{domainID}/records", linodetest.Fault{Status: http.StatusBadRequest})
+	if record, err = linode.FindRecord(zone.ID, "_acme-challenge"); err != nil || record.ID != expected.ID {
+		t.Fatalf("could not find record without a filter: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains/{domainID}/records"); calls != 6 {
+		t.Errorf("expected 1 rejected and 3 paginated list requests, got %d", calls-2)
+	}
+}
+
+func TestFindRecords(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge.www", Target: "key0"})
+	for _, key := range []string{"key1", "key2", "key3"} {
+		fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: key})
+	}
+
+	// Every challenge record at the name is returned.
+	records, err := linode.FindRecords(zone.ID, "_acme-challenge")
+	if err != nil {
+		t.Fatalf("could not find records: %v", err)
+	}
+
+	var targets []string
+	for _, record := range records {
+		targets = append(targets, record.Target)
+	}
+
+	if !slices.Equal(targets, []string{"key1", "key2", "key3"}) {
+		t.Errorf("unexpected records %v", targets)
+	}
+
+	// The record with the value is found among them.
+	record, err := linode.FindRecordByValue(zone.ID, "_acme-challenge", "key2")
+	if err != nil {
+		t.Fatalf("could not find record by value: %v", err)
+	}
+
+	if record.ID != records[1].ID {
+		t.Errorf("expected record %d with target key2, got %+v", records[1].ID, record)
+	}
+
+	if _, err = linode.FindRecordByValue(zone.ID, "_acme-challenge", "key0"); !errors.Is(err, ErrNoRecord) {
+		t.Errorf("expected ErrNoRecord for a value at another name, got %v", err)
+	}
+
+	if records, err = linode.FindRecords(zone.ID, "_acme-challenge.api"); err != nil || len(records) != 0 {
+		t.Errorf("expected no records and no error, got %d records and %v", len(records), err)
+	}
+}
+
+func TestGetRecord(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+	expected := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1", TTLSec: 180})
+
+	record, err := linode.GetRecord(zone.ID, expected.ID)
+	if err != nil {
+		t.Fatalf("could not get record: %v", err)
+	}
+
+	if record.Name != expected.Name || record.Target != expected.Target || record.TTLSec != 300 {
+		t.Errorf("unexpected record returned: %+v", record)
+	}
+
+	if record.Created == nil || record.Updated == nil {
+		t.Error("expected record timestamps to be parsed")
+	}
+
+	if _, err = linode.GetRecord(zone.ID, expected.ID+1); !errors.Is(err, ErrNoRecord) {
+		t.Errorf("expected ErrNoRecord got %v", err)
+	}
+}
+
+func TestCreateRecord(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	interval := ConfirmRecordInterval
+	ConfirmRecordInterval = time.Millisecond
+	defer func() { ConfirmRecordInterval = interval }()
+
+	if _, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not create record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	record := fake.Records(zone.ID)[0]
+	if record.TTLSec != linodeTTL(DefaultTTL) {
+		t.Errorf("expected ttl %d got %d", linodeTTL(DefaultTTL), record.TTLSec)
+	}
+
+	// Records that are not visible yet are read again until they are.
+	fake.Fail("GET /v4/domains/{domainID}/records/{recordID}", linodetest.Fault{Status: http.StatusNotFound}, linodetest.Fault{Status: http.StatusNotFound})
+	if _, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key2"); err != nil {
+		t.Fatalf("expected the record to be confirmed once visible: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1", "key2")
+
+	// If the record cannot be confirmed it should be removed and an error returned.
+	faults := make([]linodetest.Fault, ConfirmRecordAttempts)
+	for i := range faults {
+		faults[i] = linodetest.Fault{Status: http.StatusNotFound}
+	}
+
+	fake.Fail("GET /v4/domains/{domainID}/records/{recordID}", faults...)
+	if _, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key3"); !errors.Is(err, ErrNoRecord) {
+		t.Errorf("expected ErrNoRecord got %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1", "key2")
+
+	// The record is removed even if the confirmation outlasts the write timeout.
+	ConfirmRecordInterval = 50 * time.Millisecond
+	linode.SetWriteTimeout(100 * time.Millisecond)
+
+	fake.Fail("GET /v4/domains/{domainID}/records/{recordID}", faults...)
+	if _, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key4"); !errors.Is(err, ErrNoRecord) {
+		t.Errorf("expected ErrNoRecord got %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1", "key2")
+}
+
+func TestUpdateRecord(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+	record := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+
+	if _, err := linode.UpdateRecord(zone.ID, record.ID, record.Name, "key2"); err != nil {
+		t.Fatalf("could not update record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+
+	// Updated records are read back before the update returns.
+	if calls := fake.Calls("GET /v4/domains/{domainID}/records/{recordID}"); calls != 1 {
+		t.Errorf("expected the updated record to be read back once, got %d", calls)
+	}
+
+	if _, err := linode.UpdateRecord(zone.ID, record.ID+1, record.Name, "key3"); !linodego.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestRecordOptions(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	if opts := linode.RecordOptions(); opts != (RecordOptions{TTL: DefaultTTL, Weight: 1}) {
+		t.Errorf("unexpected default record options %+v", opts)
+	}
+
+	// Clients with different options create records concurrently without sharing them.
+	ttls := []int{30, 120, 300, 3600}
+	var wg sync.WaitGroup
+	for i, ttl := range ttls {
+		client := &Linode{client: linode.client, ttl: ttl}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.CreateRecord(zone.ID, "_acme-challenge", strconv.Itoa(i)); err != nil {
+				t.Errorf("could not create record: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, record := range fake.Records(zone.ID) {
+		i, _ := strconv.Atoi(record.Target)
+		if record.TTLSec != ttls[i] || record.Weight != 1 || record.Priority != 0 || record.Port != 0 {
+			t.Errorf("unexpected options of record %s: %+v", record.Target, record)
+		}
+	}
+}
+
+func TestDeleteRecord(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+	record := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+
+	if err := linode.DeleteRecord(zone.ID, record.ID); err != nil {
+		t.Fatalf("could not delete record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+
+	if err := linode.DeleteRecord(zone.ID, record.ID); !linodego.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	fake.AddDomain("example.com")
+
+	// Rate limits and transient unavailability should be retried.
+	fake.Fail("GET /v4/domains",
+		linodetest.Fault{Status: http.StatusTooManyRequests, RetryAfter: 1},
+		linodetest.Fault{Status: http.StatusServiceUnavailable},
+		linodetest.Fault{Status: http.StatusTooManyRequests},
+	)
+
+	if _, err := linode.FindZone("example.com"); err != nil {
+		t.Fatalf("expected transient errors to be retried: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 4 {
+		t.Errorf("expected 4 requests, got %d", calls)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	tests := []struct {
+		status int
+		reason string
+		kind   error
+	}{
+		{http.StatusUnauthorized, "Invalid Token", ErrUnauthorized},
+		{http.StatusForbidden, "Unauthorized", ErrForbidden},
+		{http.StatusInternalServerError, "Internal Server Error", ErrLinodeUnavailable},
+	}
+
+	for _, tc := range tests {
+		fake.Fail("GET /v4/domains/{domainID}/records", linodetest.Fault{Status: tc.status, Reason: tc.reason})
+
+		_, err := linode.FindRecord(zone.ID, "_acme-challenge")
+		if !linodego.ErrHasStatus(err, tc.status) {
+			t.Errorf("expected error with status %d, got %v", tc.status, err)
+		}
+
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("expected error with status %d to wrap %q, got %v", tc.status, tc.kind, err)
+		}
+
+		if errors.Is(err, ErrNoRecord) {
+			t.Errorf("expected API error to not be mapped to ErrNoRecord")
+		}
+	}
+
+	// Errors without a status are only classified if they are linodego errors.
+	if err := apiError(&linodego.Error{Code: http.StatusTooManyRequests}); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+
+	if err := apiError(&linodego.Error{Message: "connection refused"}); !errors.Is(err, ErrLinodeUnavailable) {
+		t.Errorf("expected unavailable error, got %v", err)
+	}
+
+	if err := apiError(&linodego.Error{Message: `Get "http://localhost/v4/domains": context canceled`}); !errors.Is(err, context.Canceled) || errors.Is(err, ErrLinodeUnavailable) {
+		t.Errorf("expected canceled error, got %v", err)
+	}
+
+	if err := apiError(&linodego.Error{Code: http.StatusNotFound}); errors.Is(err, ErrLinodeUnavailable) || !linodego.IsNotFound(err) {
+		t.Errorf("expected not found error to be returned unchanged, got %v", err)
+	}
+
+	if err := apiError(ErrNoZone); err != ErrNoZone {
+		t.Errorf("expected other errors to be returned unchanged, got %v", err)
+	}
+}
+
+func TestEnsureTXT(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	// Should create the record if it does not exist
+	if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not ensure TXT record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	// Should be idempotent if the record already has the value
+	if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not ensure TXT record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	if calls := fake.Calls("POST /v4/domains/{domainID}/records"); calls != 1 {
+		t.Errorf("expected 1 create call, got %d", calls)
+	}
+
+	// Should update the existing record with a new value
+	if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key2"); err != nil {
+		t.Fatalf("could not ensure TXT record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+
+	// Records that already have the value are never updated.
+	for range 3 {
+		if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key2"); err != nil {
+			t.Fatalf("could not ensure TXT record: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("PUT /v4/domains/{domainID}/records/{recordID}"); calls != 1 {
+		t.Errorf("expected 1 update call, got %d", calls)
+	}
+}
+
+func TestEnsureTXTDuplicates(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	for range 3 {
+		fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+	}
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeA, Name: "_acme-challenge", Target: "127.0.0.1"})
+
+	if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not ensure TXT record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	if n := len(fake.Records(zone.ID)); n != 2 {
+		t.Errorf("expected the A record to be untouched, %d records remain:\n%s", n, fake)
+	}
+}
+
+func TestApexEntry(t *testing.T) {
+	solver, fake := newTestSolver(t)
+
+	// The challenge name is a validation zone of its own, whose apex holds an SPF record.
+	zone := fake.AddDomain("_acme-challenge.example.com")
+	spf := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "", Target: "v=spf1 -all"})
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "www", Target: "key1"})
+
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "_acme-challenge.example.com.", ResourceNamespace: "default", Key: "key1"}
+	if entry, _ := DomainEntry(ch.ResolvedFQDN, ch.ResolvedZone); entry != "" {
+		t.Fatalf("expected the apex entry to be empty, got %q", entry)
+	}
+
+	// Records at the apex are never updated, so each key gets its own record.
+	for _, key := range []string{"key1", "key2", "key2"} {
+		ch.Key = key
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge %s: %v", key, err)
+		}
+	}
+	assertTargets(t, fake, zone.ID, "", "v=spf1 -all", "key1", "key2")
+	assertTargets(t, fake, zone.ID, "www", "key1")
+
+	for _, key := range []string{"key1", "key2"} {
+		ch.Key = key
+		if err := solver.CleanUp(ch); err != nil {
+			t.Fatalf("could not clean up challenge %s: %v", key, err)
+		}
+	}
+	assertTargets(t, fake, zone.ID, "", "v=spf1 -all")
+	assertTargets(t, fake, zone.ID, "www", "key1")
+
+	if records := fake.Records(zone.ID); records[0].ID != spf.ID {
+		t.Errorf("expected the SPF record to be untouched, got %+v", records[0])
+	}
+
+	// Zone files spell the apex "@".
+	if !sameEntry("@", "") || sameEntry("@", "www") {
+		t.Error("expected @ to be the apex entry")
+	}
+}
+
+func TestEnsureTXTConcurrent(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+				t.Errorf("could not ensure TXT record: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+}
+
+func TestCustomHTTPClient(t *testing.T) {
+	fake := linodetest.New()
+	t.Cleanup(fake.Close)
+	zone := fake.AddDomain("example.com")
+
+	// An instrumented client that records the requests it sends.
+	var requests atomic.Int64
+	var authorization atomic.Value
+	transport := fake.Client().Transport
+	hc := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		authorization.Store(req.Header.Get("Authorization"))
+		return transport.RoundTrip(req)
+	})}
+
+	// A retry policy that also retries teapots, which linodego does not retry by default.
+	teapot := func(rep *http.Response, _ error) bool { return rep != nil && rep.StatusCode == http.StatusTeapot }
+	linode := NewLinodeWithHTTPClient("token", hc).SetRetryPolicy(&RetryPolicy{MaxRetries: 2, MinWait: time.Millisecond, MaxWait: 10 * time.Millisecond, Conditions: []RetryCondition{teapot}})
+	linode.SetBaseURL(fake.URL())
+
+	fake.Fail("GET /v4/domains", linodetest.Fault{Status: http.StatusTeapot})
+	if _, err := linode.FindZone("example.com"); err != nil {
+		t.Fatalf("expected the request to be retried: %v", err)
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests with the custom client, got %d", n)
+	}
+
+	if auth := authorization.Load(); auth != "Bearer token" {
+		t.Errorf("expected the token to be sent, got %q", auth)
+	}
+
+	// Retries can be disabled if the custom client retries requests itself.
+	linode.SetRetryPolicy(&RetryPolicy{MaxRetries: -1})
+	fake.Fail("POST /v4/domains/{domainID}/records", linodetest.Fault{Status: http.StatusServiceUnavailable})
+	if _, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key1"); !linodego.ErrHasStatus(err, http.StatusServiceUnavailable) {
+		t.Errorf("expected the request not to be retried, got %v", err)
+	}
+}
+
+func TestNewLinodeWithClient(t *testing.T) {
+	fake := linodetest.New()
+	t.Cleanup(fake.Close)
+	fake.AddDomain("example.com")
+
+	// A mocked transport that records the requests of the injected client.
+	var authorization, userAgent atomic.Value
+	transport := fake.Client().Transport
+	client := linodego.NewClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		authorization.Store(req.Header.Get("Authorization"))
+		userAgent.Store(req.Header.Get("User-Agent"))
+		return transport.RoundTrip(req)
+	})})
+	client.SetBaseURL(fake.URL())
+	client.SetToken("injected")
+	client.SetUserAgent("downstream/1.0")
+
+	linode := NewLinodeWithClient(client, "injected")
+	if linode.account != accountKey("injected") {
+		t.Errorf("expected the account of the token, got %q", linode.account)
+	}
+
+	if _, err := linode.FindZone("example.com"); err != nil {
+		t.Fatalf("could not find zone with the injected client: %v", err)
+	}
+
+	// The client is used as it was configured.
+	if auth := authorization.Load(); auth != "Bearer injected" {
+		t.Errorf("expected the token of the injected client, got %q", auth)
+	}
+
+	if ua := userAgent.Load(); ua != "downstream/1.0" {
+		t.Errorf("expected the User-Agent of the injected client, got %q", ua)
+	}
+}
+
+func TestUserAgentSuffix(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.userAgentSuffix = "cluster=prod-east"
+	fake.AddDomain("example.com")
+
+	var userAgent atomic.Value
+	transport := fake.Client().Transport
+	solver.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		userAgent.Store(req.Header.Get("User-Agent"))
+		return transport.RoundTrip(req)
+	})})
+
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if ua := userAgent.Load(); ua != UserAgent+" cluster=prod-east" {
+		t.Errorf("expected the User-Agent with the deployment suffix, got %q", ua)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	fake := linodetest.New()
+	t.Cleanup(fake.Close)
+	zone := fake.AddDomain("example.com")
+
+	// Records the remaining time until the deadline of the requests of each method.
+	var mu sync.Mutex
+	deadlines := make(map[string]time.Duration)
+	transport := fake.Client().Transport
+	hc := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if deadline, ok := req.Context().Deadline(); ok {
+			mu.Lock()
+			deadlines[req.Method] = time.Until(deadline)
+			mu.Unlock()
+		}
+		return transport.RoundTrip(req)
+	})}
+
+	linode := NewLinodeWithHTTPClient("token", hc).SetTimeout(time.Minute).SetWriteTimeout(5 * time.Minute)
+	linode.SetBaseURL(fake.URL())
+
+	if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not ensure TXT record: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if get := deadlines[http.MethodGet]; get <= 0 || get > time.Minute {
+		t.Errorf("expected reads to use the timeout, got a deadline in %s", get)
+	}
+
+	if post := deadlines[http.MethodPost]; post <= time.Minute || post > 5*time.Minute {
+		t.Errorf("expected writes to use the write timeout, got a deadline in %s", post)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestLinode(t *testing.T) (*Linode, *linodetest.Server) {
+	t.Helper()
+	fake := linodetest.New()
+	t.Cleanup(fake.Close)
+
+	lin := NewLinodeWithClient(newLinodeClient(fake.Client()), "")
+	lin.SetBaseURL(fake.URL())
+
+	// Keep backoff between retries short so that the tests run quickly.
+	lin.client.SetRetryWaitTime(10 * time.Millisecond)
+	lin.client.SetRetryMaxWaitTime(100 * time.Millisecond)
+	return lin, fake
+}
+
+// Asserts that the TXT records with the specified name have exactly the targets.
+func assertTargets(t *testing.T, fake *linodetest.Server, zoneID int, name string, targets ...string) {
+	t.Helper()
+
+	var actual []string
+	for _, record := range fake.Records(zoneID) {
+		if record.Type == linodego.RecordTypeTXT && record.Name == name {
+			actual = append(actual, record.Target)
+		}
+	}
+
+	if len(actual) != len(targets) {
+		t.Fatalf("expected TXT records %q with targets %v, got %v:\n%s", name, targets, actual, fake)
+	}
+
+	for i, target := range targets {
+		if actual[i] != target {
+			t.Fatalf("expected TXT records %q with targets %v, got %v:\n%s", name, targets, actual, fake)
+		}
+	}
+}
+
+func TestConfirmEvents(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	linode.SetConfirmEvents(true)
+	zone := fake.AddDomain("example.com")
+
+	interval := EventPollInterval
+	EventPollInterval = 10 * time.Millisecond
+	defer func() { EventPollInterval = interval }()
+
+	record, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key1")
+	if err != nil {
+		t.Fatalf("could not create record: %v", err)
+	}
+
+	if _, err = linode.UpdateRecord(zone.ID, record.ID, "_acme-challenge", "key2"); err != nil {
+		t.Fatalf("could not update record: %v", err)
+	}
+
+	if err = linode.DeleteRecord(zone.ID, record.ID); err != nil {
+		t.Fatalf("could not delete record: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/account/events"); calls != 3 {
+		t.Errorf("expected 3 event list requests, got %d", calls)
+	}
+
+	// Mutations without a completed event are not confirmed.
+	linode.SetTimeout(50 * time.Millisecond)
+	if err = linode.confirmEvent(zone.ID, record.ID+1, EventDomainRecordCreate, time.Now()); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed got %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package acme
+
+import (
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+)
+
+// LinodeAPI is the subset of the Linode client that the solver uses to present and
+// clean up challenges. *Linode implements it; programs embedding the solver can
+// provide another implementation with SetLinodeFactory, e.g. to unit test the solver
+// with an in-memory fake instead of the Linode API.
+type LinodeAPI interface {
+	FindZone(domain string) (*linodego.Domain, error)
+	FindCandidateZone(fqdn, preferred string) (*linodego.Domain, string, error)
+	FindRecord(zoneID int, entry string) (*linodego.DomainRecord, error)
+	FindRecords(zoneID int, entry string) ([]linodego.DomainRecord, error)
+	GetRecord(zoneID, recordID int) (*linodego.DomainRecord, error)
+	CreateRecord(zoneID int, entry, value string) (*linodego.DomainRecord, error)
+	UpdateRecord(zoneID, recordID int, entry, value string) (*linodego.DomainRecord, error)
+	DeleteRecord(zoneID, recordID int) error
+	EnsureTXT(zoneID int, entry, value string) (*linodego.DomainRecord, error)
+	AppendTXT(zoneID int, entry, value string) (*linodego.DomainRecord, error)
+	DeleteTXT(zoneID int, entry, value string) error
+}
+
+var _ LinodeAPI = (*Linode)(nil)
+
+// LinodeFactory creates the Linode API client for a challenge from the API key and
+// the solver configuration of the challenge, with the issuer's tuning and the
+// defaults of the solver applied.
+type LinodeFactory func(apiKey string, cfg LinodeDNSProviderConfig) (LinodeAPI, error)
+
+// SetLinodeFactory sets the factory that creates the Linode API client of every
+// challenge the solver presents or cleans up. It must be called before the solver is
+// initialized; nil uses a *Linode configured by the solver.
+func (s *LinodeDNSProviderSolver) SetLinodeFactory(factory LinodeFactory) *LinodeDNSProviderSolver {
+	s.linodeFactory = factory
+	return s
+}
+
+// Returns the Linode API client for the challenge from the factory of the solver, or
+// the *Linode configured by the solver if there is no factory.
+func (s *LinodeDNSProviderSolver) linodeAPI(ch *v1alpha1.ChallengeRequest) (_ LinodeAPI, cfg LinodeDNSProviderConfig, err error) {
+	if s.linodeFactory == nil {
+		var linode *Linode
+		if linode, cfg, err = s.linodeClient(ch); err != nil {
+			return nil, cfg, err
+		}
+
+		if s.audit != nil {
+			linode.SetAuditCaller(s.auditCaller(ch))
+		}
+		return linode, cfg, nil
+	}
+
+	var apiKey string
+	if apiKey, cfg, err = s.linodeConfig(ch); err != nil {
+		return nil, cfg, err
+	}
+
+	var linode LinodeAPI
+	if linode, err = s.linodeFactory(apiKey, cfg); err != nil {
+		return nil, cfg, err
+	}
+	return linode, cfg, nil
+}
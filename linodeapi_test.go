@@ -0,0 +1,153 @@
+package acme
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+)
+
+// fakeLinodeAPI is an in-memory LinodeAPI with a single zone.
+type fakeLinodeAPI struct {
+	zone    linodego.Domain
+	records []linodego.DomainRecord
+	nextID  int
+}
+
+func (f *fakeLinodeAPI) FindZone(domain string) (*linodego.Domain, error) {
+	if normalizeZone(domain) != f.zone.Domain {
+		return nil, ErrNoZone
+	}
+	return &f.zone, nil
+}
+
+func (f *fakeLinodeAPI) FindCandidateZone(fqdn, preferred string) (*linodego.Domain, string, error) {
+	name := normalizeZone(fqdn)
+	if !strings.HasSuffix(name, "."+f.zone.Domain) {
+		return nil, "", ErrNoZone
+	}
+	return &f.zone, strings.TrimSuffix(name, "."+f.zone.Domain), nil
+}
+
+func (f *fakeLinodeAPI) FindRecord(zoneID int, entry string) (*linodego.DomainRecord, error) {
+	records, _ := f.FindRecords(zoneID, entry)
+	if len(records) == 0 {
+		return nil, ErrNoRecord
+	}
+	return &records[0], nil
+}
+
+func (f *fakeLinodeAPI) FindRecords(zoneID int, entry string) (matches []linodego.DomainRecord, _ error) {
+	for _, record := range f.records {
+		if record.Name == entry {
+			matches = append(matches, record)
+		}
+	}
+	return matches, nil
+}
+
+func (f *fakeLinodeAPI) GetRecord(zoneID, recordID int) (*linodego.DomainRecord, error) {
+	for _, record := range f.records {
+		if record.ID == recordID {
+			return &record, nil
+		}
+	}
+	return nil, ErrNoRecord
+}
+
+func (f *fakeLinodeAPI) CreateRecord(zoneID int, entry, value string) (*linodego.DomainRecord, error) {
+	f.nextID++
+	f.records = append(f.records, linodego.DomainRecord{ID: f.nextID, Type: linodego.RecordTypeTXT, Name: entry, Target: value})
+	return &f.records[len(f.records)-1], nil
+}
+
+func (f *fakeLinodeAPI) UpdateRecord(zoneID, recordID int, entry, value string) (*linodego.DomainRecord, error) {
+	for i := range f.records {
+		if f.records[i].ID == recordID {
+			f.records[i].Name, f.records[i].Target = entry, value
+			return &f.records[i], nil
+		}
+	}
+	return nil, ErrNoRecord
+}
+
+func (f *fakeLinodeAPI) DeleteRecord(zoneID, recordID int) error {
+	for i, record := range f.records {
+		if record.ID == recordID {
+			f.records = append(f.records[:i], f.records[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNoRecord
+}
+
+func (f *fakeLinodeAPI) EnsureTXT(zoneID int, entry, value string) (*linodego.DomainRecord, error) {
+	if record, err := f.FindRecord(zoneID, entry); err == nil {
+		return f.UpdateRecord(zoneID, record.ID, entry, value)
+	}
+	return f.CreateRecord(zoneID, entry, value)
+}
+
+func (f *fakeLinodeAPI) AppendTXT(zoneID int, entry, value string) (*linodego.DomainRecord, error) {
+	return f.CreateRecord(zoneID, entry, value)
+}
+
+func (f *fakeLinodeAPI) DeleteTXT(zoneID int, entry, value string) error {
+	records, _ := f.FindRecords(zoneID, entry)
+	for _, record := range records {
+		if record.Target == value {
+			if err := f.DeleteRecord(zoneID, record.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestLinodeFactory(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	linode := &fakeLinodeAPI{zone: linodego.Domain{ID: 42, Domain: "example.com"}}
+
+	var apiKey string
+	solver.SetLinodeFactory(func(key string, cfg LinodeDNSProviderConfig) (LinodeAPI, error) {
+		apiKey = key
+		return linode, nil
+	})
+
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.www.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if apiKey != "linodetest" {
+		t.Errorf("expected the factory to be called with the token of the secret, got %q", apiKey)
+	}
+
+	if len(linode.records) != 1 || linode.records[0].Name != "_acme-challenge.www" || linode.records[0].Target != "key1" {
+		t.Fatalf("unexpected records %+v", linode.records)
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if len(linode.records) != 0 {
+		t.Errorf("expected the record to be cleaned up, got %+v", linode.records)
+	}
+
+	// The factory replaces the Linode API entirely.
+	if calls := fake.AllCalls(); len(calls) != 0 {
+		t.Errorf("expected no calls to the linode API, got %v", calls)
+	}
+
+	// Errors from the factory are returned by the solver.
+	solver.SetLinodeFactory(func(string, LinodeDNSProviderConfig) (LinodeAPI, error) {
+		return nil, fmt.Errorf("%w: no client", ErrInvalidConfig)
+	})
+
+	if err := solver.Present(ch); err == nil || !strings.Contains(err.Error(), "no client") {
+		t.Errorf("expected the factory error, got %v", err)
+	}
+}
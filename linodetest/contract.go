@@ -0,0 +1,265 @@
+package linodetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"sigs.k8s.io/yaml"
+)
+
+// Contract validates requests against the Linode OpenAPI specification so that tests
+// against the fake catch requests that the real API would reject, e.g. when linodego or
+// the API evolves. The paths, query parameters, and JSON bodies of requests are checked.
+type Contract struct {
+	root   map[string]any
+	prefix string
+	paths  map[string]map[string]any
+}
+
+// LoadContract loads a JSON or YAML OpenAPI 3 specification from the path.
+func LoadContract(path string) (_ *Contract, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+	return ParseContract(data)
+}
+
+// ParseContract parses a JSON OpenAPI 3 specification.
+func ParseContract(data []byte) (_ *Contract, err error) {
+	c := &Contract{paths: make(map[string]map[string]any)}
+	if err = json.Unmarshal(data, &c.root); err != nil {
+		return nil, fmt.Errorf("could not parse openapi specification: %w", err)
+	}
+
+	paths, ok := c.root["paths"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("openapi specification has no paths")
+	}
+
+	for path, item := range paths {
+		if item, ok := item.(map[string]any); ok {
+			c.paths[path] = item
+		}
+	}
+
+	// The paths are relative to the path of the first server (e.g. /v4).
+	if servers, ok := c.root["servers"].([]any); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]any); ok {
+			if u, err := url.Parse(fmt.Sprint(server["url"])); err == nil {
+				c.prefix = strings.TrimSuffix(u.Path, "/")
+			}
+		}
+	}
+	return c, nil
+}
+
+// Validate returns an error describing every way in which the request and its JSON
+// body do not conform to the specification.
+func (c *Contract) Validate(method, path string, query url.Values, body []byte) error {
+	template, item := c.match(strings.TrimPrefix(path, c.prefix))
+	if item == nil {
+		return fmt.Errorf("%s %s: path is not in the specification", method, path)
+	}
+
+	op, ok := c.resolve(item[strings.ToLower(method)]).(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s: method is not allowed on %s", method, path, template)
+	}
+
+	var problems []string
+
+	// Every query parameter must be declared by the operation or its path.
+	declared := c.parameters(item["parameters"], op["parameters"])
+	for key := range query {
+		if !slices.Contains(declared, key) {
+			problems = append(problems, fmt.Sprintf("query parameter %q is not declared", key))
+		}
+	}
+
+	// The JSON body must match the schema of the request body.
+	if schema := c.bodySchema(op); schema != nil && len(bytes.TrimSpace(body)) > 0 {
+		var value any
+		if err := json.Unmarshal(body, &value); err != nil {
+			problems = append(problems, fmt.Sprintf("body is not valid JSON: %v", err))
+		} else {
+			result := validate.NewSchemaValidator(schema, nil, "body", strfmt.Default).Validate(value)
+			for _, err := range result.Errors {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s %s (%s): %s", method, path, template, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Returns the path template, and its item, whose segments match the path.
+func (c *Contract) match(path string) (string, map[string]any) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for template, item := range c.paths {
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+
+		matches := true
+		for i, part := range parts {
+			if part != segments[i] && !(strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return template, item
+		}
+	}
+	return "", nil
+}
+
+// Returns the names of the query parameters in the parameter lists.
+func (c *Contract) parameters(lists ...any) (names []string) {
+	for _, list := range lists {
+		params, _ := list.([]any)
+		for _, param := range params {
+			if param, ok := c.resolve(param).(map[string]any); ok && param["in"] == "query" {
+				names = append(names, fmt.Sprint(param["name"]))
+			}
+		}
+	}
+	return names
+}
+
+// Returns the fully resolved JSON schema of the operation's request body, if any.
+func (c *Contract) bodySchema(op map[string]any) *spec.Schema {
+	body, ok := c.resolve(op["requestBody"]).(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	content, _ := body["content"].(map[string]any)
+	media, ok := content["application/json"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(c.expand(media["schema"], nil))
+	if err != nil {
+		return nil
+	}
+
+	schema := &spec.Schema{}
+	if err = json.Unmarshal(data, schema); err != nil {
+		return nil
+	}
+	return schema
+}
+
+// Returns the object that the value refers to if it is a local $ref.
+func (c *Contract) resolve(v any) any {
+	for range 16 {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+
+		ref, ok := obj["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+
+		var cur any = c.root
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			m, _ := cur.(map[string]any)
+			cur = m[token]
+		}
+		v = cur
+	}
+	return v
+}
+
+// Returns a deep copy of the schema with every local $ref replaced by its target since
+// the schema validator does not resolve references; recursive references are dropped.
+func (c *Contract) expand(v any, seen []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if slices.Contains(seen, ref) {
+				return map[string]any{}
+			}
+			return c.expand(c.resolve(v), append(seen, ref))
+		}
+
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			out[key] = c.expand(val, seen)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = c.expand(val, seen)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// SetContract validates every request to the fake against the contract; the requests
+// that do not conform are reported by Violations and are still handled.
+func (s *Server) SetContract(c *Contract) {
+	s.Lock()
+	defer s.Unlock()
+	s.contract = c
+}
+
+// Violations returns the contract violations of the requests made to the fake.
+func (s *Server) Violations() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return slices.Clone(s.violations)
+}
+
+func (s *Server) checkContract(r *http.Request) {
+	s.RLock()
+	contract := s.contract
+	s.RUnlock()
+
+	if contract == nil {
+		return
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if err := contract.Validate(r.Method, r.URL.Path, r.URL.Query(), body); err != nil {
+		s.Lock()
+		s.violations = append(s.violations, err.Error())
+		s.Unlock()
+	}
+}
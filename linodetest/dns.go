@@ -0,0 +1,180 @@
+package linodetest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/linode/linodego"
+	"github.com/miekg/dns"
+)
+
+// DNSServer is an authoritative DNS server for the domains in a fake Linode API
+// server, answering queries directly from the fake's records so that the cert-manager
+// conformance fixture can verify record propagation without a real Linode account.
+type DNSServer struct {
+	fake    *Server
+	srv     *dns.Server
+	queries atomic.Int64
+}
+
+// NewDNSServer starts a DNS server on a random local UDP port that serves the
+// records stored in the specified fake Linode API server; the caller must Close it.
+func NewDNSServer(fake *Server) (_ *DNSServer, err error) {
+	var conn net.PacketConn
+	if conn, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+		return nil, err
+	}
+
+	d := &DNSServer{fake: fake}
+	started := make(chan struct{})
+
+	d.srv = &dns.Server{
+		PacketConn:        conn,
+		Handler:           dns.HandlerFunc(d.serveDNS),
+		NotifyStartedFunc: func() { close(started) },
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- d.srv.ActivateAndServe()
+	}()
+
+	// Wait for the server to start or fail to start.
+	select {
+	case <-started:
+		return d, nil
+	case err = <-errc:
+		return nil, fmt.Errorf("could not start dns server: %w", err)
+	}
+}
+
+// Addr returns the host:port address of the DNS server.
+func (d *DNSServer) Addr() string {
+	return d.srv.PacketConn.LocalAddr().String()
+}
+
+// Queries returns the number of queries that the DNS server has received.
+func (d *DNSServer) Queries() int {
+	return int(d.queries.Load())
+}
+
+// Close shuts down the DNS server.
+func (d *DNSServer) Close() error {
+	return d.srv.Shutdown()
+}
+
+func (d *DNSServer) serveDNS(w dns.ResponseWriter, req *dns.Msg) {
+	d.queries.Add(1)
+	rep := new(dns.Msg)
+	rep.SetReply(req)
+
+	if len(req.Question) != 1 {
+		rep.SetRcode(req, dns.RcodeFormatError)
+		w.WriteMsg(rep)
+		return
+	}
+
+	q := req.Question[0]
+	zone, answers, exists := d.fake.lookup(q.Name, q.Qtype)
+
+	switch {
+	case zone == nil:
+		// Only answer for the domains hosted in the fake Linode account.
+		rep.SetRcode(req, dns.RcodeRefused)
+	case !exists:
+		rep.Authoritative = true
+		rep.SetRcode(req, dns.RcodeNameError)
+		rep.Ns = append(rep.Ns, soa(zone))
+	default:
+		rep.Authoritative = true
+		rep.Answer = answers
+		if len(answers) == 0 {
+			rep.Ns = append(rep.Ns, soa(zone))
+		}
+	}
+
+	w.WriteMsg(rep)
+}
+
+// Returns the domain that hosts the fqdn, the resource records that match the query,
+// and if any records at all exist at the name so NXDOMAIN can be distinguished.
+func (s *Server) lookup(fqdn string, qtype uint16) (zone *linodego.Domain, answers []dns.RR, exists bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	for _, domain := range s.domains {
+		if name == domain.Domain || strings.HasSuffix(name, "."+domain.Domain) {
+			// Prefer the most specific domain if domains are nested.
+			if zone == nil || len(domain.Domain) > len(zone.Domain) {
+				zone = domain
+			}
+		}
+	}
+
+	if zone == nil {
+		return nil, nil, false
+	}
+
+	entry := strings.TrimSuffix(strings.TrimSuffix(name, zone.Domain), ".")
+	exists = entry == ""
+
+	for _, record := range s.records[zone.ID] {
+		if !strings.EqualFold(record.Name, entry) {
+			continue
+		}
+
+		exists = true
+		if rr := resourceRecord(fqdn, record); rr != nil && (rr.Header().Rrtype == qtype || qtype == dns.TypeANY) {
+			answers = append(answers, rr)
+		}
+	}
+	// Linode serves its own nameservers for the apex of every zone unless the zone
+	// has custom NS records at the apex.
+	if entry == "" && qtype == dns.TypeNS && len(answers) == 0 {
+		for i := 1; i <= 5; i++ {
+			hdr := dns.RR_Header{Name: dns.Fqdn(fqdn), Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 86400}
+			answers = append(answers, &dns.NS{Hdr: hdr, Ns: fmt.Sprintf("ns%d.linode.com.", i)})
+		}
+	}
+	return zone, answers, exists
+}
+
+func resourceRecord(fqdn string, record *linodego.DomainRecord) dns.RR {
+	hdr := dns.RR_Header{Name: dns.Fqdn(fqdn), Class: dns.ClassINET, Ttl: uint32(record.TTLSec)}
+
+	switch record.Type {
+	case linodego.RecordTypeTXT:
+		hdr.Rrtype = dns.TypeTXT
+		return &dns.TXT{Hdr: hdr, Txt: []string{record.Target}}
+	case linodego.RecordTypeCNAME:
+		hdr.Rrtype = dns.TypeCNAME
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(record.Target)}
+	case linodego.RecordTypeNS:
+		hdr.Rrtype = dns.TypeNS
+		return &dns.NS{Hdr: hdr, Ns: dns.Fqdn(record.Target)}
+	case linodego.RecordTypeA:
+		hdr.Rrtype = dns.TypeA
+		return &dns.A{Hdr: hdr, A: net.ParseIP(record.Target)}
+	case linodego.RecordTypeAAAA:
+		hdr.Rrtype = dns.TypeAAAA
+		return &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP(record.Target)}
+	default:
+		return nil
+	}
+}
+
+func soa(zone *linodego.Domain) dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: dns.Fqdn(zone.Domain), Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 30},
+		Ns:      "ns1.linode.com.",
+		Mbox:    dns.Fqdn(strings.Replace(zone.SOAEmail, "@", ".", 1)),
+		Serial:  1,
+		Refresh: 14400,
+		Retry:   3600,
+		Expire:  604800,
+		Minttl:  30,
+	}
+}
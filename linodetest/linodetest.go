@@ -0,0 +1,734 @@
+// Package linodetest provides an in-memory fake of the Linode DNS Manager API for
+// testing the acme-linode webhook without a real Linode account.
+package linodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linode/linodego"
+)
+
+const (
+	DefaultPageSize = 100
+	timeLayout      = "2006-01-02T15:04:05"
+)
+
+// Server is a fake Linode API server that implements the domain and domain record
+// endpoints used by the webhook, storing all domains and records in memory.
+type Server struct {
+	sync.RWMutex
+	srv     *httptest.Server
+	nextID  int
+	domains []*linodego.Domain
+	records map[int][]*linodego.DomainRecord
+	events  []map[string]any
+	tokens  []linodego.Token
+	euuids  map[string]bool
+	calls   map[string]int
+	faults  map[string][]Fault
+
+	contract   *Contract
+	violations []string
+}
+
+// Fault is an error response returned by the fake instead of handling a request.
+type Fault struct {
+	Status     int
+	RetryAfter int
+	Reason     string
+}
+
+// New creates and starts a fake Linode API server; the caller must Close it.
+func New() *Server {
+	s := &Server{
+		nextID:  1000,
+		records: make(map[int][]*linodego.DomainRecord),
+		euuids:  make(map[string]bool),
+		calls:   make(map[string]int),
+		faults:  make(map[string][]Fault),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v4/domains", s.intercept(s.listDomains))
+	mux.HandleFunc("POST /v4/domains", s.intercept(s.createDomain))
+	mux.HandleFunc("GET /v4/domains/{domainID}", s.intercept(s.getDomain))
+	mux.HandleFunc("GET /v4/domains/{domainID}/records", s.intercept(s.listRecords))
+	mux.HandleFunc("POST /v4/domains/{domainID}/records", s.intercept(s.createRecord))
+	mux.HandleFunc("GET /v4/domains/{domainID}/records/{recordID}", s.intercept(s.getRecord))
+	mux.HandleFunc("PUT /v4/domains/{domainID}/records/{recordID}", s.intercept(s.updateRecord))
+	mux.HandleFunc("DELETE /v4/domains/{domainID}/records/{recordID}", s.intercept(s.deleteRecord))
+	mux.HandleFunc("GET /v4/account/events", s.intercept(s.listEvents))
+	mux.HandleFunc("GET /v4/profile", s.intercept(s.getProfile))
+	mux.HandleFunc("GET /v4/profile/tokens", s.intercept(s.listTokens))
+	mux.HandleFunc("POST /v4/account/child-accounts/{euuid}/token", s.intercept(s.createChildAccountToken))
+
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the base URL of the fake server to configure the linodego client with.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Client returns an HTTP client that can make requests to the fake server.
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// Close shuts down the fake server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// AddDomain adds a master domain with the specified name and tags to the fake account.
+func (s *Server) AddDomain(name string, tags ...string) linodego.Domain {
+	s.Lock()
+	defer s.Unlock()
+
+	s.nextID++
+	domain := &linodego.Domain{
+		ID:       s.nextID,
+		Domain:   name,
+		Type:     linodego.DomainTypeMaster,
+		Status:   linodego.DomainStatusActive,
+		SOAEmail: "admin@" + name,
+		Tags:     tags,
+	}
+
+	s.domains = append(s.domains, domain)
+	return *domain
+}
+
+// AddSecondaryDomain adds a slave domain with the specified name that is transferred
+// from the master nameservers to the fake account. Records can be added to it with
+// AddRecord, but the API rejects records created in it as the real API does.
+func (s *Server) AddSecondaryDomain(name string, masterIPs ...string) linodego.Domain {
+	s.Lock()
+	defer s.Unlock()
+
+	s.nextID++
+	domain := &linodego.Domain{
+		ID:        s.nextID,
+		Domain:    name,
+		Type:      linodego.DomainTypeSlave,
+		Status:    linodego.DomainStatusActive,
+		MasterIPs: masterIPs,
+	}
+
+	s.domains = append(s.domains, domain)
+	return *domain
+}
+
+// AddRecord adds a record to the specified domain, assigning it a new ID. The record is
+// created and updated now unless its timestamps are set, e.g. to add stale records.
+func (s *Server) AddRecord(domainID int, record linodego.DomainRecord) linodego.DomainRecord {
+	s.Lock()
+	defer s.Unlock()
+	return *s.addRecord(domainID, record)
+}
+
+// AddToken adds a personal access token to the profile of the fake account, e.g. with
+// an expiry; only the first 16 characters of the token are listed, as the real API does.
+func (s *Server) AddToken(token linodego.Token) linodego.Token {
+	s.Lock()
+	defer s.Unlock()
+
+	s.nextID++
+	token.ID = s.nextID
+	s.tokens = append(s.tokens, token)
+	return token
+}
+
+// RemoveToken removes the personal access token with the ID from the profile of the
+// fake account, e.g. to replace it with a token with other scopes.
+func (s *Server) RemoveToken(id int) {
+	s.Lock()
+	defer s.Unlock()
+	s.tokens = slices.DeleteFunc(s.tokens, func(token linodego.Token) bool { return token.ID == id })
+}
+
+// AddChildAccount adds a child account with the EUUID to the fake account, so that
+// proxy tokens can be created for it. Requests made with the proxy tokens operate on
+// the domains of the fake account.
+func (s *Server) AddChildAccount(euuid string) {
+	s.Lock()
+	defer s.Unlock()
+	s.euuids[euuid] = true
+}
+
+// Records returns a copy of all of the records in the specified domain.
+func (s *Server) Records(domainID int) []linodego.DomainRecord {
+	s.RLock()
+	defer s.RUnlock()
+
+	records := make([]linodego.DomainRecord, 0, len(s.records[domainID]))
+	for _, record := range s.records[domainID] {
+		records = append(records, *record)
+	}
+	return records
+}
+
+// Fail queues faults to be returned, in order, for the next requests to the route
+// (e.g. "GET /v4/domains") before requests are handled normally again.
+func (s *Server) Fail(route string, faults ...Fault) {
+	s.Lock()
+	defer s.Unlock()
+	s.faults[route] = append(s.faults[route], faults...)
+}
+
+// Calls returns the number of requests made to the specified route, e.g.
+// "GET /v4/domains" or "POST /v4/domains/{domainID}/records".
+func (s *Server) Calls(route string) int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.calls[route]
+}
+
+// String returns a description of the records in the fake for test failure messages.
+func (s *Server) String() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	var sb strings.Builder
+	for _, domain := range s.domains {
+		fmt.Fprintf(&sb, "%s (%d)\n", domain.Domain, domain.ID)
+		for _, record := range s.records[domain.ID] {
+			fmt.Fprintf(&sb, "  %d %s %s %q ttl=%d\n", record.ID, record.Type, record.Name, record.Target, record.TTLSec)
+		}
+	}
+	return sb.String()
+}
+
+// AllCalls returns a copy of the number of requests made to every route.
+func (s *Server) AllCalls() map[string]int {
+	s.RLock()
+	defer s.RUnlock()
+	return maps.Clone(s.calls)
+}
+
+//===========================================================================
+// Handlers
+//===========================================================================
+
+// Counts every request to the route and returns any queued faults for it.
+func (s *Server) intercept(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.checkContract(r)
+
+		s.Lock()
+		s.calls[r.Pattern]++
+
+		var fault *Fault
+		if faults := s.faults[r.Pattern]; len(faults) > 0 {
+			fault, s.faults[r.Pattern] = &faults[0], faults[1:]
+		}
+		s.Unlock()
+
+		if fault != nil {
+			if fault.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(fault.RetryAfter))
+			}
+
+			reason := fault.Reason
+			if reason == "" {
+				reason = http.StatusText(fault.Status)
+			}
+			writeError(w, fault.Status, reason)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// Lists the domains, filtered by name if the X-Filter header is a domain filter of the
+// form {"domain": "example.com"} or {"+or": [{"domain": "example.com"}, ...]}; other
+// filters are rejected as the real API would reject unknown fields.
+func (s *Server) listDomains(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	names, ok := domainFilter(r.Header.Get("X-Filter"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid X-Filter")
+		return
+	}
+
+	domains := make([]any, 0, len(s.domains))
+	for _, domain := range s.domains {
+		if names == nil || slices.Contains(names, domain.Domain) {
+			domains = append(domains, domain)
+		}
+	}
+	s.paginate(w, r, domains)
+}
+
+// Returns the names matched by a domain X-Filter or nil if there is no filter.
+func domainFilter(header string) (names []string, ok bool) {
+	if header == "" {
+		return nil, true
+	}
+
+	var filter struct {
+		Domain *string             `json:"domain"`
+		Or     []map[string]string `json:"+or"`
+	}
+
+	dec := json.NewDecoder(strings.NewReader(header))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&filter); err != nil {
+		return nil, false
+	}
+
+	names = []string{}
+	if filter.Domain != nil {
+		names = append(names, *filter.Domain)
+	}
+
+	for _, clause := range filter.Or {
+		name, ok := clause["domain"]
+		if !ok || len(clause) != 1 {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, true
+}
+
+// Creates a domain; as the real API does, domain names are unique and master domains
+// require an SOA email.
+func (s *Server) createDomain(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	var opts linodego.DomainCreateOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "could not parse request body")
+		return
+	}
+
+	if opts.Domain == "" || opts.Type == "" {
+		writeError(w, http.StatusBadRequest, "domain and type are required")
+		return
+	}
+
+	if opts.Type == linodego.DomainTypeMaster && opts.SOAEmail == "" {
+		writeError(w, http.StatusBadRequest, "soa_email is required for master domains")
+		return
+	}
+
+	for _, domain := range s.domains {
+		if strings.EqualFold(domain.Domain, opts.Domain) {
+			writeError(w, http.StatusBadRequest, "Domain already exists")
+			return
+		}
+	}
+
+	s.nextID++
+	domain := &linodego.Domain{
+		ID:          s.nextID,
+		Domain:      strings.ToLower(opts.Domain),
+		Type:        opts.Type,
+		Status:      linodego.DomainStatusActive,
+		Description: opts.Description,
+		SOAEmail:    opts.SOAEmail,
+		MasterIPs:   opts.MasterIPs,
+		Tags:        opts.Tags,
+	}
+
+	s.domains = append(s.domains, domain)
+	writeJSON(w, http.StatusOK, domain)
+}
+
+func (s *Server) getDomain(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	domain, ok := s.domain(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, domain)
+}
+
+func (s *Server) listRecords(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	domain, ok := s.domain(w, r)
+	if !ok {
+		return
+	}
+
+	filter, ok := recordFilter(r.Header.Get("X-Filter"))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid X-Filter")
+		return
+	}
+
+	records := make([]any, 0, len(s.records[domain.ID]))
+	for _, record := range s.records[domain.ID] {
+		if filter(record) {
+			records = append(records, wireRecord(record))
+		}
+	}
+	s.paginate(w, r, records)
+}
+
+// Returns a predicate for a records X-Filter that matches the name, type, and target
+// fields exactly, e.g. {"name": "_acme-challenge", "type": "TXT"}.
+func recordFilter(header string) (_ func(*linodego.DomainRecord) bool, ok bool) {
+	if header == "" {
+		return func(*linodego.DomainRecord) bool { return true }, true
+	}
+
+	var filter map[string]string
+	if err := json.Unmarshal([]byte(header), &filter); err != nil {
+		return nil, false
+	}
+
+	for field := range filter {
+		if field != "name" && field != "type" && field != "target" {
+			return nil, false
+		}
+	}
+
+	return func(record *linodego.DomainRecord) bool {
+		fields := map[string]string{"name": record.Name, "type": string(record.Type), "target": record.Target}
+		for field, value := range filter {
+			if fields[field] != value {
+				return false
+			}
+		}
+		return true
+	}, true
+}
+
+func (s *Server) createRecord(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	domain, ok := s.domain(w, r)
+	if !ok {
+		return
+	}
+
+	var opts linodego.DomainRecordCreateOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "could not parse request body")
+		return
+	}
+
+	if opts.Type == "" {
+		writeError(w, http.StatusBadRequest, "type is required")
+		return
+	}
+
+	if domain.Type == linodego.DomainTypeSlave {
+		writeError(w, http.StatusBadRequest, "Records cannot be added to a slave domain")
+		return
+	}
+
+	record := s.addRecord(domain.ID, linodego.DomainRecord{
+		Type:     opts.Type,
+		Name:     opts.Name,
+		Target:   opts.Target,
+		Priority: deref(opts.Priority),
+		Weight:   deref(opts.Weight),
+		Port:     deref(opts.Port),
+		TTLSec:   opts.TTLSec,
+	})
+	s.addEvent("domain_record_create", domain, record)
+	writeJSON(w, http.StatusOK, wireRecord(record))
+}
+
+func (s *Server) getRecord(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.record(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, wireRecord(record))
+}
+
+func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.record(w, r)
+	if !ok {
+		return
+	}
+
+	var opts linodego.DomainRecordUpdateOptions
+	if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+		writeError(w, http.StatusBadRequest, "could not parse request body")
+		return
+	}
+
+	if opts.Name != "" {
+		record.Name = opts.Name
+	}
+	if opts.Target != "" {
+		record.Target = opts.Target
+	}
+	if opts.TTLSec != 0 {
+		record.TTLSec = ttl(opts.TTLSec)
+	}
+
+	now := time.Now().UTC()
+	record.Updated = &now
+
+	domain, _ := s.domain(w, r)
+	s.addEvent("domain_record_update", domain, record)
+	writeJSON(w, http.StatusOK, wireRecord(record))
+}
+
+func (s *Server) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.record(w, r)
+	if !ok {
+		return
+	}
+
+	domain, _ := s.domain(w, r)
+	s.records[domain.ID] = slices.DeleteFunc(s.records[domain.ID], func(rec *linodego.DomainRecord) bool {
+		return rec.ID == record.ID
+	})
+
+	s.addEvent("domain_record_delete", domain, record)
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// Lists the account events from newest to oldest; the X-Filter header is ignored so
+// clients must match the events that they are looking for themselves.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	events := make([]any, 0, len(s.events))
+	for i := len(s.events) - 1; i >= 0; i-- {
+		events = append(events, s.events[i])
+	}
+	s.paginate(w, r, events)
+}
+
+//===========================================================================
+// Helpers
+//===========================================================================
+
+// Must be called with the lock held.
+func (s *Server) getProfile(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"username": "linodetest", "email": "linodetest@example.com"})
+}
+
+func (s *Server) listTokens(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	tokens := make([]any, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, wireToken(token, false))
+	}
+	s.paginate(w, r, tokens)
+}
+
+// Creates a proxy token of a child account that expires in 15 minutes, as the real API
+// does.
+func (s *Server) createChildAccountToken(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	euuid := r.PathValue("euuid")
+	if !s.euuids[euuid] {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	s.nextID++
+	created := time.Now().UTC()
+	expiry := created.Add(15 * time.Minute)
+	token := linodego.Token{
+		ID:      s.nextID,
+		Label:   euuid + "_proxy",
+		Scopes:  "*",
+		Token:   fmt.Sprintf("proxy-%s-%d", euuid, s.nextID),
+		Created: &created,
+		Expiry:  &expiry,
+	}
+	writeJSON(w, http.StatusOK, wireToken(token, true))
+}
+
+func (s *Server) addRecord(domainID int, record linodego.DomainRecord) *linodego.DomainRecord {
+	s.nextID++
+	now := time.Now().UTC()
+
+	record.ID = s.nextID
+	record.TTLSec = ttl(record.TTLSec)
+	if record.Created == nil {
+		record.Created = &now
+	}
+
+	if record.Updated == nil {
+		record.Updated = record.Created
+	}
+
+	s.records[domainID] = append(s.records[domainID], &record)
+	return &record
+}
+
+// Must be called with the lock held; records a completed event for a record mutation in
+// the format returned by the account events API.
+func (s *Server) addEvent(action string, domain *linodego.Domain, record *linodego.DomainRecord) {
+	s.nextID++
+	s.events = append(s.events, map[string]any{
+		"id":               s.nextID,
+		"action":           action,
+		"created":          time.Now().UTC().Format(timeLayout),
+		"status":           "notification",
+		"entity":           map[string]any{"id": domain.ID, "type": "domain", "label": domain.Domain},
+		"secondary_entity": map[string]any{"id": record.ID, "type": "domain_record", "label": record.Name},
+	})
+}
+
+// Must be called with the lock held; writes a 404 if the domain does not exist.
+func (s *Server) domain(w http.ResponseWriter, r *http.Request) (*linodego.Domain, bool) {
+	domainID, err := strconv.Atoi(r.PathValue("domainID"))
+	if err == nil {
+		for _, domain := range s.domains {
+			if domain.ID == domainID {
+				return domain, true
+			}
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Not found")
+	return nil, false
+}
+
+// Must be called with the lock held; writes a 404 if the record does not exist.
+func (s *Server) record(w http.ResponseWriter, r *http.Request) (*linodego.DomainRecord, bool) {
+	domain, ok := s.domain(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	recordID, err := strconv.Atoi(r.PathValue("recordID"))
+	if err == nil {
+		for _, record := range s.records[domain.ID] {
+			if record.ID == recordID {
+				return record, true
+			}
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "Not found")
+	return nil, false
+}
+
+// Writes the requested page of results in the Linode paginated response format.
+func (s *Server) paginate(w http.ResponseWriter, r *http.Request, results []any) {
+	page, pageSize := 1, DefaultPageSize
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if ps, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil && ps > 0 {
+		pageSize = ps
+	}
+
+	pages := max(1, (len(results)+pageSize-1)/pageSize)
+	start := min(len(results), (page-1)*pageSize)
+	end := min(len(results), start+pageSize)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"data":    results[start:end],
+		"page":    page,
+		"pages":   pages,
+		"results": len(results),
+	})
+}
+
+// Serializes records with timestamps in the format returned by the Linode API.
+func wireRecord(record *linodego.DomainRecord) any {
+	type Mask linodego.DomainRecord
+	rec := struct {
+		*Mask
+		Created string `json:"created"`
+		Updated string `json:"updated"`
+	}{Mask: (*Mask)(record)}
+
+	if record.Created != nil {
+		rec.Created = record.Created.Format(timeLayout)
+	}
+	if record.Updated != nil {
+		rec.Updated = record.Updated.Format(timeLayout)
+	}
+	return rec
+}
+
+// Only the prefix of the token is listed unless the token is being created.
+func wireToken(token linodego.Token, secret bool) any {
+	type Mask linodego.Token
+	tok := struct {
+		*Mask
+		Created *string `json:"created"`
+		Expiry  *string `json:"expiry"`
+	}{Mask: (*Mask)(&token)}
+
+	if !secret && len(token.Token) > 16 {
+		token.Token = token.Token[:16]
+	}
+
+	if token.Created != nil {
+		created := token.Created.Format(timeLayout)
+		tok.Created = &created
+	}
+	if token.Expiry != nil {
+		expiry := token.Expiry.Format(timeLayout)
+		tok.Expiry = &expiry
+	}
+	return tok
+}
+
+// Rounds the TTL up to the nearest value accepted by Linode, as the real API does.
+func ttl(sec int) int {
+	for _, valid := range []int{0, 30, 120, 300, 3600, 7200, 14400, 28800, 57600, 86400, 172800, 345600, 604800, 1209600, 2419200} {
+		if sec <= valid {
+			return valid
+		}
+	}
+	return 2419200
+}
+
+func deref(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, reason string) {
+	writeJSON(w, code, map[string]any{
+		"errors": []map[string]string{{"reason": reason}},
+	})
+}
@@ -0,0 +1,131 @@
+package linodetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linode/linodego"
+)
+
+// ZoneSnapshot holds the records of a Linode domain so that destructive tests, the
+// e2e suite, or the soak and diagnostic tools can be run against a real zone that is
+// shared with other records and the zone can be put back as it was afterwards. Only
+// the records are restored; the settings of the domain itself are not modified.
+type ZoneSnapshot struct {
+	Domain  linodego.Domain
+	Records []linodego.DomainRecord
+	Taken   time.Time
+}
+
+// Snapshot records the current records of the domain with the specified name (with
+// or without a trailing dot) in the Linode account of the client.
+func Snapshot(ctx context.Context, client *linodego.Client, domain string) (_ *ZoneSnapshot, err error) {
+	var zone *linodego.Domain
+	if zone, err = findDomain(ctx, client, domain); err != nil {
+		return nil, err
+	}
+
+	snapshot := &ZoneSnapshot{Domain: *zone, Taken: time.Now()}
+	if snapshot.Records, err = client.ListDomainRecords(ctx, zone.ID, nil); err != nil {
+		return nil, fmt.Errorf("could not list records of domain %s: %w", zone.Domain, err)
+	}
+	return snapshot, nil
+}
+
+// Restore puts the records of the domain back to the snapshot: records created since
+// the snapshot are deleted, records that were modified are updated, and records that
+// were deleted are recreated (with new IDs). Restore attempts every change even if
+// some fail and returns all of the errors.
+func (s *ZoneSnapshot) Restore(ctx context.Context, client *linodego.Client) (err error) {
+	var current []linodego.DomainRecord
+	if current, err = client.ListDomainRecords(ctx, s.Domain.ID, nil); err != nil {
+		return fmt.Errorf("could not list records of domain %s: %w", s.Domain.Domain, err)
+	}
+
+	snapshot := make(map[int]linodego.DomainRecord, len(s.Records))
+	for _, record := range s.Records {
+		snapshot[record.ID] = record
+	}
+
+	var errs []error
+	for _, record := range current {
+		original, ok := snapshot[record.ID]
+		delete(snapshot, record.ID)
+
+		if !ok {
+			if err = client.DeleteDomainRecord(ctx, s.Domain.ID, record.ID); err != nil && !linodego.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("could not delete %s record %q (ID %d): %w", record.Type, record.Name, record.ID, err))
+			}
+			continue
+		}
+
+		if opts := original.GetUpdateOptions(); !reflect.DeepEqual(opts, record.GetUpdateOptions()) {
+			if _, err = client.UpdateDomainRecord(ctx, s.Domain.ID, record.ID, opts); err != nil {
+				errs = append(errs, fmt.Errorf("could not restore %s record %q (ID %d): %w", record.Type, original.Name, record.ID, err))
+			}
+		}
+	}
+
+	// Recreate the records that are no longer in the zone, in their original order.
+	for _, record := range s.Records {
+		if _, ok := snapshot[record.ID]; !ok {
+			continue
+		}
+
+		opts := record.GetUpdateOptions()
+		if _, err = client.CreateDomainRecord(ctx, s.Domain.ID, linodego.DomainRecordCreateOptions{
+			Type:     opts.Type,
+			Name:     opts.Name,
+			Target:   opts.Target,
+			Priority: opts.Priority,
+			Weight:   opts.Weight,
+			Port:     opts.Port,
+			Service:  opts.Service,
+			Protocol: opts.Protocol,
+			TTLSec:   opts.TTLSec,
+			Tag:      opts.Tag,
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("could not recreate %s record %q: %w", record.Type, record.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SnapshotZone snapshots the records of the domain before a test and restores them
+// when the test and its subtests complete, failing the test if either step fails.
+func SnapshotZone(t testing.TB, client *linodego.Client, domain string) *ZoneSnapshot {
+	t.Helper()
+
+	snapshot, err := Snapshot(context.Background(), client, domain)
+	if err != nil {
+		t.Fatalf("could not snapshot zone %s: %v", domain, err)
+	}
+
+	t.Cleanup(func() {
+		if err := snapshot.Restore(context.Background(), client); err != nil {
+			t.Errorf("could not restore zone %s from snapshot taken at %s: %v", snapshot.Domain.Domain, snapshot.Taken.Format(time.RFC3339), err)
+		}
+	})
+	return snapshot
+}
+
+func findDomain(ctx context.Context, client *linodego.Client, name string) (_ *linodego.Domain, err error) {
+	name = strings.TrimSuffix(name, ".")
+
+	var domains []linodego.Domain
+	if domains, err = client.ListDomains(ctx, nil); err != nil {
+		return nil, fmt.Errorf("could not list domains: %w", err)
+	}
+
+	for i := range domains {
+		if domains[i].Domain == name {
+			return &domains[i], nil
+		}
+	}
+	return nil, fmt.Errorf("domain %s not found in the linode account", name)
+}
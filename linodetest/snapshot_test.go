@@ -0,0 +1,59 @@
+package linodetest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestZoneSnapshot(t *testing.T) {
+	fake := linodetest.New()
+	defer fake.Close()
+
+	zone := fake.AddDomain("example.com")
+	www := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeA, Name: "www", Target: "192.0.2.1", TTLSec: 300})
+	spf := fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "", Target: "v=spf1 -all", TTLSec: 300})
+
+	client := linodego.NewClient(fake.Client())
+	client.SetBaseURL(fake.URL())
+	ctx := context.Background()
+
+	// Run a destructive subtest that modifies, deletes, and creates records.
+	t.Run("Destructive", func(t *testing.T) {
+		snapshot := linodetest.SnapshotZone(t, &client, "example.com.")
+		if len(snapshot.Records) != 2 {
+			t.Fatalf("expected 2 records in snapshot, got %d", len(snapshot.Records))
+		}
+
+		if _, err := client.UpdateDomainRecord(ctx, zone.ID, www.ID, linodego.DomainRecordUpdateOptions{Target: "192.0.2.2"}); err != nil {
+			t.Fatalf("could not update record: %v", err)
+		}
+
+		if err := client.DeleteDomainRecord(ctx, zone.ID, spf.ID); err != nil {
+			t.Fatalf("could not delete record: %v", err)
+		}
+
+		if _, err := client.CreateDomainRecord(ctx, zone.ID, linodego.DomainRecordCreateOptions{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key"}); err != nil {
+			t.Fatalf("could not create record: %v", err)
+		}
+	})
+
+	records := fake.Records(zone.ID)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records after restore, got %d:\n%s", len(records), fake)
+	}
+
+	if records[0].ID != www.ID || records[0].Target != "192.0.2.1" {
+		t.Errorf("expected modified record to be restored, got %+v", records[0])
+	}
+
+	if records[1].Type != linodego.RecordTypeTXT || records[1].Target != "v=spf1 -all" || records[1].TTLSec != 300 {
+		t.Errorf("expected deleted record to be recreated, got %+v", records[1])
+	}
+
+	if _, err := linodetest.Snapshot(ctx, &client, "example.org"); err == nil {
+		t.Error("expected an error snapshotting a domain that is not in the account")
+	}
+}
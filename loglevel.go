@@ -0,0 +1,133 @@
+package acme
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// The maximum klog verbosity that can be set with the log level endpoint.
+const MaxLogLevel = 10
+
+// LogLevelRequest changes the klog verbosity of the webhook. If a duration is given
+// then the previous verbosity is restored once it elapses, so that verbose logging
+// enabled to reproduce an intermittent failure is not left on by accident.
+//
+//	{"level": 6, "duration": "15m"}
+type LogLevelRequest struct {
+	Level    *int                `json:"level"`
+	Duration *k8smetav1.Duration `json:"duration,omitempty"`
+}
+
+// LogLevelResponse reports the current klog verbosity and when it will be restored.
+type LogLevelResponse struct {
+	Level    int        `json:"level"`
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Tracks a temporary verbosity set by the log level endpoint so that it can be
+// restored; later requests replace the pending restore. The zero value is ready to use.
+type logLevelOverride struct {
+	sync.Mutex
+	previous klog.Level
+	revertAt time.Time
+	timer    *time.Timer
+}
+
+func (a *AdminServer) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.logLevel.response())
+}
+
+// Sets the klog verbosity on the fly, e.g. to reproduce intermittent challenge
+// failures without a manifest edit and a pod restart. The verbosity is process
+// wide, so it also replaces the logLevel of the runtime settings until the settings
+// file next changes.
+func (a *AdminServer) putLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, LogLevelResponse{Level: int(verbosity()), Error: "could not parse request body: " + err.Error()})
+		return
+	}
+
+	if req.Level == nil || *req.Level < 0 || *req.Level > MaxLogLevel {
+		writeJSON(w, http.StatusBadRequest, LogLevelResponse{Level: int(verbosity()), Error: fmt.Sprintf("level must be between 0 and %d", MaxLogLevel)})
+		return
+	}
+
+	var duration time.Duration
+	if req.Duration != nil {
+		if duration = req.Duration.Duration; duration < 0 {
+			writeJSON(w, http.StatusBadRequest, LogLevelResponse{Level: int(verbosity()), Error: "duration must not be negative"})
+			return
+		}
+	}
+
+	if err := a.logLevel.set(klog.Level(*req.Level), duration); err != nil {
+		writeJSON(w, http.StatusInternalServerError, LogLevelResponse{Level: int(verbosity()), Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, a.logLevel.response())
+}
+
+// Sets the verbosity, restoring the verbosity from before the first pending override
+// after the duration if it is positive.
+func (o *logLevelOverride) set(level klog.Level, duration time.Duration) (err error) {
+	o.Lock()
+	defer o.Unlock()
+
+	previous := verbosity()
+	if o.timer != nil {
+		// Restore to the level from before the override that is being replaced.
+		o.timer.Stop()
+		o.timer = nil
+		o.revertAt = time.Time{}
+		previous = o.previous
+	}
+
+	if err = setVerbosity(level); err != nil {
+		return err
+	}
+	klog.Infof("log level set to %d by the admin endpoint", level)
+
+	if duration > 0 {
+		o.previous = previous
+		o.revertAt = time.Now().Add(duration)
+		o.timer = time.AfterFunc(duration, o.revert)
+	}
+	return nil
+}
+
+func (o *logLevelOverride) revert() {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.timer == nil {
+		return
+	}
+
+	o.timer = nil
+	o.revertAt = time.Time{}
+	if err := setVerbosity(o.previous); err != nil {
+		klog.Warningf("could not restore log level to %d: %v", o.previous, err)
+		return
+	}
+	klog.Infof("log level restored to %d", o.previous)
+}
+
+func (o *logLevelOverride) response() LogLevelResponse {
+	o.Lock()
+	defer o.Unlock()
+
+	rep := LogLevelResponse{Level: int(verbosity())}
+	if o.timer != nil {
+		revertAt := o.revertAt
+		rep.RevertAt = &revertAt
+	}
+	return rep
+}
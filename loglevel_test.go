@@ -0,0 +1,68 @@
+package acme
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	level := verbosity()
+	t.Cleanup(func() { setVerbosity(level) })
+
+	admin := &AdminServer{solver: &LinodeDNSProviderSolver{}, token: "supersecret"}
+	srv := httptest.NewServer(admin.routes())
+	defer srv.Close()
+
+	do := func(method, token, body string) (int, LogLevelResponse) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+"/loglevel", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rep, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer rep.Body.Close()
+
+		var out LogLevelResponse
+		if err := json.NewDecoder(rep.Body).Decode(&out); err != nil && rep.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		return rep.StatusCode, out
+	}
+
+	if code, _ := do(http.MethodPut, "wrong", `{"level": 6}`); code != http.StatusUnauthorized {
+		t.Errorf("expected unauthenticated requests to be rejected, got %d", code)
+	}
+
+	for _, body := range []string{`{}`, `{"level": -1}`, `{"level": 11}`, `{"level": 2, "duration": "-1m"}`, `level=6`} {
+		if code, _ := do(http.MethodPut, "supersecret", body); code != http.StatusBadRequest {
+			t.Errorf("expected %s to be rejected, got %d", body, code)
+		}
+	}
+
+	if code, rep := do(http.MethodPut, "supersecret", `{"level": 6}`); code != http.StatusOK || rep.Level != 6 || rep.RevertAt != nil {
+		t.Fatalf("could not set log level: %d %+v", code, rep)
+	}
+
+	if v := verbosity(); v != 6 {
+		t.Errorf("expected verbosity 6, got %d", v)
+	}
+
+	// Temporary levels are reverted to the level from before the first override.
+	if code, rep := do(http.MethodPut, "supersecret", `{"level": 8, "duration": "1h"}`); code != http.StatusOK || rep.RevertAt == nil {
+		t.Fatalf("could not set temporary log level: %d %+v", code, rep)
+	}
+
+	if code, rep := do(http.MethodPut, "supersecret", `{"level": 9, "duration": "50ms"}`); code != http.StatusOK || rep.Level != 9 {
+		t.Fatalf("could not replace temporary log level: %d %+v", code, rep)
+	}
+
+	eventually(t, func() bool { return verbosity() == 6 })
+	if code, rep := do(http.MethodGet, "supersecret", ""); code != http.StatusOK || rep.Level != 6 || rep.RevertAt != nil {
+		t.Errorf("unexpected log level after revert: %d %+v", code, rep)
+	}
+}
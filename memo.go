@@ -0,0 +1,279 @@
+package acme
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/linode/linodego"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/klog/v2"
+)
+
+// A request-scoped memo of the domain and record lists fetched while handling a single
+// Present or CleanUp, so that the steps of the request (e.g. the record age check and
+// the delete in CleanUp) do not repeat the same list requests. The memoized records of
+// a zone are discarded whenever the client mutates a record in it.
+type lookupMemo struct {
+	sync.Mutex
+	domains []linodego.Domain
+	zones   map[string][]linodego.Domain
+	records map[int][]linodego.DomainRecord
+	entries map[int]map[string][]linodego.DomainRecord
+}
+
+// Memoize enables memoization of the domain and record lookups made by the client so
+// that repeated lookups do not make additional API requests. Changes made outside of
+// the client are not observed, so a memoized client should only be used for a single
+// logical operation such as one challenge request.
+func (l *Linode) Memoize() *Linode {
+	l.memo = &lookupMemo{
+		zones:   make(map[string][]linodego.Domain),
+		records: make(map[int][]linodego.DomainRecord),
+		entries: make(map[int]map[string][]linodego.DomainRecord),
+	}
+	return l
+}
+
+// Returns all of the domains in the account, memoized if enabled.
+func (l *Linode) listDomains() (domains []linodego.Domain, err error) {
+	if l.memo != nil {
+		l.memo.Lock()
+		defer l.memo.Unlock()
+
+		if l.memo.domains != nil {
+			return l.memo.domains, nil
+		}
+	}
+
+	var ok bool
+	if domains, ok = l.domains.get(l.account); ok {
+		klog.V(4).Info("using the pre-warmed linode domains")
+		if l.memo != nil {
+			l.memo.domains = domains
+		}
+		return domains, nil
+	}
+
+	if domains, err = l.fetchDomains(""); err != nil {
+		return nil, err
+	}
+
+	if l.memo != nil {
+		l.memo.domains = domains
+	}
+	return domains, nil
+}
+
+// Returns the domains in the account with one of the names, memoized if enabled. The
+// domains are fetched with an X-Filter query so that accounts with thousands of zones
+// are not listed in full; the complete listing is only used if it is already memoized
+// or pre-warmed, or if the API rejects the filter (e.g. an alternative endpoint).
+func (l *Linode) findDomains(names ...string) (domains []linodego.Domain, err error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	match := func(domains []linodego.Domain) (matches []linodego.Domain) {
+		for _, domain := range domains {
+			if slices.Contains(names, asciiName(domain.Domain)) {
+				matches = append(matches, domain)
+			}
+		}
+		return matches
+	}
+
+	if l.memo != nil {
+		l.memo.Lock()
+		domains = l.memo.domains
+		l.memo.Unlock()
+
+		if domains != nil {
+			return match(domains), nil
+		}
+	}
+
+	var ok bool
+	if domains, ok = l.domains.get(l.account); ok {
+		klog.V(4).Info("using the pre-warmed linode domains")
+		return match(domains), nil
+	}
+
+	// The first name is the most specific candidate (e.g. the zone resolved by
+	// cert-manager), so if it is a cached zone the other names need not be looked up.
+	if domains, ok = l.zones.get(l.account, names[0]); ok {
+		klog.V(4).Infof("using the cached linode domain of zone %s", names[0])
+		return domains, nil
+	}
+
+	filter := domainFilter(names)
+	if l.memo != nil {
+		l.memo.Lock()
+		defer l.memo.Unlock()
+
+		if domains, ok = l.memo.zones[filter]; ok {
+			return domains, nil
+		}
+	}
+
+	if domains, err = l.fetchDomains(filter); err != nil {
+		return nil, err
+	}
+
+	// The filter is matched case sensitively, so the names are matched again.
+	domains = match(domains)
+	l.zones.add(l.account, domains)
+	if l.memo != nil {
+		l.memo.zones[filter] = domains
+	}
+	return domains, nil
+}
+
+// Concurrent lookups of the same domains in the same account are coalesced, since a
+// burst of challenges (e.g. many certificates renewing at once) would otherwise make
+// identical list requests at the same time.
+var domainLookups singleflight.Group
+
+// Lists the domains in the account that match the filter, or every domain if the
+// filter is empty or rejected by the API. Concurrent calls with the same filter by
+// clients for the same account and endpoint share a single request, which is made
+// with the context and timeout of the first caller; the returned domains are shared
+// by the callers and must not be modified.
+func (l *Linode) fetchDomains(filter string) ([]linodego.Domain, error) {
+	key := l.account + "|" + l.baseURL + "|" + filter
+	domains, err, shared := domainLookups.Do(key, func() (_ any, err error) {
+		ctx, cancel := l.context()
+		defer cancel()
+
+		var domains []linodego.Domain
+		if domains, err = l.client.ListDomains(ctx, linodego.NewListOptions(0, filter)); err != nil {
+			if filter == "" || !linodego.ErrHasStatus(err, http.StatusBadRequest) {
+				return nil, apiError(err)
+			}
+
+			klog.V(2).Infof("linode rejected the domain filter, listing every domain: %v", err)
+			if domains, err = l.client.ListDomains(ctx, linodego.NewListOptions(0, "")); err != nil {
+				return nil, apiError(err)
+			}
+		}
+		return domains, nil
+	})
+
+	if shared {
+		klog.V(4).Info("shared the linode domain lookup with concurrent challenges")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return domains.([]linodego.Domain), nil
+}
+
+// Returns the X-Filter that matches domains with any of the names.
+func domainFilter(names []string) string {
+	clauses := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		clauses = append(clauses, map[string]string{"domain": name})
+	}
+
+	var filter any = clauses[0]
+	if len(clauses) > 1 {
+		filter = map[string]any{"+or": clauses}
+	}
+
+	data, _ := json.Marshal(filter)
+	return string(data)
+}
+
+// Returns all of the records in the zone, memoized if enabled.
+func (l *Linode) listZoneRecords(zoneID int) (records []linodego.DomainRecord, err error) {
+	if l.memo != nil {
+		l.memo.Lock()
+		defer l.memo.Unlock()
+
+		var ok bool
+		if records, ok = l.memo.records[zoneID]; ok {
+			return records, nil
+		}
+	}
+
+	ctx, cancel := l.context()
+	defer cancel()
+
+	if records, err = l.client.ListDomainRecords(ctx, zoneID, linodego.NewListOptions(0, "")); err != nil {
+		return nil, l.recordError(zoneID, err)
+	}
+
+	if l.memo != nil {
+		l.memo.records[zoneID] = records
+	}
+	return records, nil
+}
+
+// Returns the TXT records in the zone with the name of the entry, memoized if enabled.
+// The records are fetched with an X-Filter query on the name and type so that large
+// zones are not listed in full, unless all of the records of the zone are memoized or
+// the API rejects the filter.
+func (l *Linode) listEntryRecords(zoneID int, entry string) (records []linodego.DomainRecord, err error) {
+	if l.memo != nil {
+		l.memo.Lock()
+		defer l.memo.Unlock()
+
+		var ok bool
+		if records, ok = l.memo.records[zoneID]; ok {
+			return records, nil
+		}
+
+		if records, ok = l.memo.entries[zoneID][entry]; ok {
+			return records, nil
+		}
+	}
+
+	ctx, cancel := l.context()
+	defer cancel()
+
+	// The records at the apex are only filtered by type, since the API does not match
+	// their empty name with a name filter; FindRecords matches the names instead.
+	filter := map[string]string{"name": entry, "type": string(linodego.RecordTypeTXT)}
+	if entry == "" {
+		delete(filter, "name")
+	}
+
+	data, _ := json.Marshal(filter)
+	if records, err = l.client.ListDomainRecords(ctx, zoneID, linodego.NewListOptions(0, string(data))); err != nil {
+		if !linodego.ErrHasStatus(err, http.StatusBadRequest) {
+			return nil, l.recordError(zoneID, err)
+		}
+
+		klog.V(2).Infof("linode rejected the record filter, listing every record in zone ID %d: %v", zoneID, err)
+		if records, err = l.client.ListDomainRecords(ctx, zoneID, linodego.NewListOptions(0, "")); err != nil {
+			return nil, l.recordError(zoneID, err)
+		}
+
+		if l.memo != nil {
+			l.memo.records[zoneID] = records
+		}
+		return records, nil
+	}
+
+	if l.memo != nil {
+		if l.memo.entries[zoneID] == nil {
+			l.memo.entries[zoneID] = make(map[string][]linodego.DomainRecord)
+		}
+		l.memo.entries[zoneID][entry] = records
+	}
+	return records, nil
+}
+
+// Discards the memoized records of the zone after a mutation.
+func (m *lookupMemo) invalidate(zoneID int) {
+	if m == nil {
+		return
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	delete(m.records, zoneID)
+	delete(m.entries, zoneID)
+}
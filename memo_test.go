@@ -0,0 +1,86 @@
+package acme
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linode/linodego"
+)
+
+func TestMemoize(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	linode.Memoize()
+	zone := fake.AddDomain("example.com")
+
+	// Zone lookups are only fetched once.
+	for range 3 {
+		if _, _, err := linode.FindCandidateZone("_acme-challenge.example.com.", "example.com."); err != nil {
+			t.Fatalf("could not find zone: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected 1 list domains request, got %d", calls)
+	}
+
+	// Record lookups are fetched again after the client mutates the zone.
+	if _, err := linode.EnsureTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not ensure record: %v", err)
+	}
+
+	for range 3 {
+		if _, err := linode.FindRecord(zone.ID, "_acme-challenge"); err != nil {
+			t.Fatalf("could not find record: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("GET /v4/domains/{domainID}/records"); calls != 2 {
+		t.Errorf("expected 2 list records requests, got %d", calls)
+	}
+
+	if err := linode.DeleteTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not delete record: %v", err)
+	}
+
+	if _, err := linode.FindRecord(zone.ID, "_acme-challenge"); err == nil {
+		t.Error("expected the deleted record not to be memoized")
+	}
+}
+
+func TestConcurrentDomainLookups(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	fake.AddDomain("example.com")
+
+	// Hold the first lookup until every challenge is waiting for it.
+	const challenges = 10
+	release := make(chan struct{})
+	linode.client.OnBeforeRequest(func(*linodego.Request) error {
+		<-release
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, challenges)
+	for range challenges {
+		wg.Go(func() {
+			_, _, err := linode.FindCandidateZone("_acme-challenge.example.com.", "example.com.")
+			errs <- err
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("could not find zone: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected concurrent lookups to share 1 request, got %d", calls)
+	}
+}
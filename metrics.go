@@ -0,0 +1,152 @@
+package acme
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The registry of the webhook's Prometheus metrics, which are served by the /metrics
+// admin endpoint.
+var metrics = prometheus.NewRegistry()
+
+var (
+	linodeRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "acme_linode",
+		Name:      "linode_api_requests_total",
+		Help:      "Number of Linode API requests by operation and result code.",
+	}, []string{"operation", "code"})
+
+	linodeRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "acme_linode",
+		Name:      "linode_api_request_duration_seconds",
+		Help:      "Latency of Linode API requests by operation and result code.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"operation", "code"})
+)
+
+func init() {
+	metrics.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		linodeRequests,
+		linodeRequestDuration,
+		cacheCollector{},
+	)
+}
+
+var (
+	cacheSizeDesc      = prometheus.NewDesc("acme_linode_cache_size", "Number of entries held by each in-memory cache.", []string{"solver", "cache"}, nil)
+	cacheCapacityDesc  = prometheus.NewDesc("acme_linode_cache_capacity", "Maximum number of entries of each in-memory cache.", []string{"solver", "cache"}, nil)
+	cacheEvictionsDesc = prometheus.NewDesc("acme_linode_cache_evictions_total", "Number of entries evicted from each in-memory cache.", []string{"solver", "cache"}, nil)
+)
+
+// Reports the size, capacity, and evictions of every in-memory cache, by solver and
+// name, from the same stats as the /status admin endpoint. Caches are created and discarded as
+// the webhook runs, so the metrics are collected from the stats on every scrape.
+type cacheCollector struct{}
+
+func (cacheCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- cacheSizeDesc
+	descs <- cacheCapacityDesc
+	descs <- cacheEvictionsDesc
+}
+
+func (cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range cacheStats() {
+		ch <- prometheus.MustNewConstMetric(cacheSizeDesc, prometheus.GaugeValue, float64(stats.Size), stats.Solver, stats.Name)
+		ch <- prometheus.MustNewConstMetric(cacheCapacityDesc, prometheus.GaugeValue, float64(stats.Capacity), stats.Solver, stats.Name)
+		ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions), stats.Solver, stats.Name)
+	}
+}
+
+// Returns the handler of the /metrics admin endpoint.
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metrics, promhttp.HandlerOpts{})
+}
+
+// The operations of the Linode API requests made by the webhook, keyed by the method
+// and the path of the request with its IDs replaced by {id}.
+var linodeOperations = map[string]string{
+	"GET domains":                            "list_domains",
+	"POST domains":                           "create_domain",
+	"GET domains/{id}":                       "get_domain",
+	"GET domains/{id}/records":               "list_records",
+	"POST domains/{id}/records":              "create_record",
+	"GET domains/{id}/records/{id}":          "get_record",
+	"PUT domains/{id}/records/{id}":          "update_record",
+	"DELETE domains/{id}/records/{id}":       "delete_record",
+	"GET account/events":                     "list_events",
+	"POST account/child-accounts/{id}/token": "create_child_account_token",
+	"GET profile":                            "get_profile",
+	"GET profile/tokens":                     "list_tokens",
+}
+
+// The path segments that are followed by the ID of a resource.
+var linodeCollections = map[string]bool{"domains": true, "records": true, "child-accounts": true}
+
+// Returns the operation label of a Linode API request. The API version (and any path
+// prefix of a proxied base URL) is ignored, and requests the webhook does not make are
+// labeled "other" so that the cardinality of the metrics is bounded.
+func linodeOperation(method, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "domains" || segment == "account" || segment == "profile" {
+			segments = segments[i:]
+			break
+		}
+	}
+
+	for i := 1; i < len(segments); i++ {
+		if linodeCollections[segments[i-1]] {
+			segments[i] = "{id}"
+		}
+	}
+
+	if operation, ok := linodeOperations[method+" "+strings.Join(segments, "/")]; ok {
+		return operation
+	}
+	return "other"
+}
+
+// Records the count and latency of every Linode API request sent with the transport,
+// including each retry. Requests that fail without a response have the code "error".
+type instrumentedTransport struct {
+	transport http.RoundTripper
+}
+
+// Returns a copy of the http client whose requests are recorded in the Linode API
+// metrics; the provided client is not modified.
+func instrumentClient(hc *http.Client) *http.Client {
+	if _, ok := hc.Transport.(*instrumentedTransport); ok {
+		return hc
+	}
+
+	instrumented := *hc
+	instrumented.Transport = &instrumentedTransport{transport: hc.Transport}
+	return &instrumented
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (rep *http.Response, err error) {
+	transport := t.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	start := time.Now()
+	rep, err = transport.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(rep.StatusCode)
+	}
+
+	operation := linodeOperation(req.Method, req.URL.Path)
+	linodeRequests.WithLabelValues(operation, code).Inc()
+	linodeRequestDuration.WithLabelValues(operation, code).Observe(time.Since(start).Seconds())
+	return rep, err
+}
@@ -0,0 +1,139 @@
+package acme
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLinodeOperation(t *testing.T) {
+	testCases := []struct {
+		method, path, operation string
+	}{
+		{"GET", "/v4/domains", "list_domains"},
+		{"GET", "/v4beta/domains/1234", "get_domain"},
+		{"GET", "/v4/domains/1234/records", "list_records"},
+		{"POST", "/v4/domains/1234/records", "create_record"},
+		{"GET", "/v4/domains/1234/records/5678", "get_record"},
+		{"PUT", "/v4/domains/1234/records/5678", "update_record"},
+		{"DELETE", "/v4/domains/1234/records/5678", "delete_record"},
+		{"GET", "/v4/account/events", "list_events"},
+		{"POST", "/v4/account/child-accounts/A1BC2DEF-34GH/token", "create_child_account_token"},
+		{"GET", "/v4/profile", "get_profile"},
+		{"GET", "/v4/profile/tokens", "list_tokens"},
+		{"GET", "/proxy/linode/v4/domains/1234/records", "list_records"},
+		{"DELETE", "/v4/domains/1234", "other"},
+		{"GET", "/v4/linode/instances", "other"},
+	}
+
+	for _, tc := range testCases {
+		if operation := linodeOperation(tc.method, tc.path); operation != tc.operation {
+			t.Errorf("expected %s %s to be %s, got %s", tc.method, tc.path, tc.operation, operation)
+		}
+	}
+}
+
+func TestLinodeMetrics(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	fake.AddDomain("example.com")
+
+	created := testutil.ToFloat64(linodeRequests.WithLabelValues("create_record", "200"))
+	deleted := testutil.ToFloat64(linodeRequests.WithLabelValues("delete_record", "200"))
+
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if count := testutil.ToFloat64(linodeRequests.WithLabelValues("create_record", "200")) - created; count != 1 {
+		t.Errorf("expected 1 create_record request, got %v", count)
+	}
+
+	if count := testutil.ToFloat64(linodeRequests.WithLabelValues("delete_record", "200")) - deleted; count != 1 {
+		t.Errorf("expected 1 delete_record request, got %v", count)
+	}
+
+	// The metrics are served in the Prometheus text format.
+	rec := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `acme_linode_linode_api_request_duration_seconds_count{code="200",operation="create_record"}`) {
+		t.Errorf("expected the latency histogram to be served, got %s", body)
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	cache := newLRU[int, int]("test", "metricsTest", 2, nil)
+	defer caches.Delete(cacheKey{solver: "test", name: "metricsTest"})
+
+	for i := range 3 {
+		cache.Add(i, i)
+	}
+
+	rec := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, metric := range []string{
+		`acme_linode_cache_size{cache="metricsTest",solver="test"} 2`,
+		`acme_linode_cache_capacity{cache="metricsTest",solver="test"} 2`,
+		`acme_linode_cache_evictions_total{cache="metricsTest",solver="test"} 1`,
+	} {
+		if !strings.Contains(body, metric) {
+			t.Errorf("expected %s to be served, got %s", metric, body)
+		}
+	}
+}
+
+func TestSolverCacheMetrics(t *testing.T) {
+	// Solver instances have caches of the same name, which are reported separately.
+	for i, name := range []string{"linode-a", "linode-b"} {
+		solver := SolverInstance{Name: name}.NewSolver()
+		solver.zones = newZoneCache(solver.Name(), time.Minute)
+		defer caches.Delete(cacheKey{solver: name, name: "zoneCache"})
+
+		for j := range i + 2 {
+			solver.zones.add("account", []linodego.Domain{{ID: j + 1, Domain: fmt.Sprintf("example%d.com", j)}})
+		}
+	}
+
+	var sizes []string
+	for _, stats := range cacheStats() {
+		if stats.Name == "zoneCache" {
+			sizes = append(sizes, fmt.Sprintf("%s=%d", stats.Solver, stats.Size))
+		}
+	}
+
+	if !slices.Equal(sizes, []string{"linode-a=2", "linode-b=3"}) {
+		t.Errorf("expected the zone caches of both solvers, got %v", sizes)
+	}
+
+	// Both caches are resized with the --cache-size of the webhook.
+	resizeCaches(1)
+	defer resizeCaches(DefaultCacheSize)
+
+	rec := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, metric := range []string{
+		`acme_linode_cache_size{cache="zoneCache",solver="linode-a"} 1`,
+		`acme_linode_cache_size{cache="zoneCache",solver="linode-b"} 1`,
+		`acme_linode_cache_evictions_total{cache="zoneCache",solver="linode-a"} 1`,
+		`acme_linode_cache_evictions_total{cache="zoneCache",solver="linode-b"} 2`,
+	} {
+		if !strings.Contains(body, metric) {
+			t.Errorf("expected %s to be served, got %s", metric, body)
+		}
+	}
+}
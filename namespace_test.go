@@ -0,0 +1,43 @@
+package acme
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPodNamespace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "namespace")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		flag     string
+		env      string
+		file     string
+		expected string
+	}{
+		{"from-flag", "from-env", path, "from-flag"},
+		{"", "from-env", path, "from-env"},
+		{"", "", path, "from-file"},
+		{"", "", filepath.Join(t.TempDir(), "missing"), ""},
+	}
+
+	for _, tc := range tests {
+		t.Setenv("POD_NAMESPACE", tc.env)
+		solver := &LinodeDNSProviderSolver{namespace: tc.flag, namespaceFile: tc.file}
+
+		// The service account file may exist when the tests run in a pod.
+		expected := tc.expected
+		if expected == "" {
+			if expected, _ = solver.detectNamespace(); expected == "" {
+				expected = "default"
+			}
+		}
+
+		if ns := solver.PodNamespace(); ns != expected {
+			t.Errorf("expected namespace %q got %q", expected, ns)
+		}
+	}
+}
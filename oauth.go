@@ -0,0 +1,148 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	k8sapiv1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The keys of the OAuth client secret that hold the client ID and secret by default.
+const (
+	DefaultOAuthClientIDKey     = "client_id"
+	DefaultOAuthClientSecretKey = "client_secret"
+)
+
+// OAuthClientCredentials obtains short-lived Linode API access tokens with the OAuth
+// client credentials grant instead of using a long-lived personal access token, e.g.
+// from an organization's token broker, so that a leaked credential can be revoked in
+// one place and the tokens sent to the Linode API expire quickly. The access tokens
+// are cached and refreshed shortly before they expire.
+type OAuthClientCredentials struct {
+	// The token endpoint of the OAuth authorization server.
+	TokenURL string `json:"tokenURL"`
+
+	// The secret that holds the client ID and secret, in the namespace that the secret
+	// of the apiKeySecretRef would be read from.
+	SecretRef       cmmeta.LocalObjectReference `json:"secretRef"`
+	ClientIDKey     string                      `json:"clientIDKey,omitempty"`
+	ClientSecretKey string                      `json:"clientSecretKey,omitempty"`
+
+	// Optional scopes to request, e.g. domains:read_write.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Validate the token URL and the secret reference of the client credentials.
+func (o *OAuthClientCredentials) Validate() error {
+	if u, err := url.Parse(o.TokenURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: oauth.tokenURL must be an absolute http or https URL", ErrInvalidConfig)
+	}
+
+	if o.SecretRef.Name == "" {
+		return fmt.Errorf("%w: oauth.secretRef must specify the name of a secret", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Caches the token sources of the OAuth clients so that an access token is reused
+// until shortly before it expires, keyed by the secret, the token URL, and the
+// account key of the client credentials so that rotated credentials get new tokens.
+type oauthTokenCache struct {
+	sources *lru[string, oauth2.TokenSource]
+}
+
+func newOAuthTokenCache(solver string) *oauthTokenCache {
+	return &oauthTokenCache{sources: newLRU[string, oauth2.TokenSource](solver, "oauthTokenSources", DefaultCacheSize, nil)}
+}
+
+// Returns the cached token source for the key, creating it if it is missing; a nil
+// cache creates a new token source every time.
+func (c *oauthTokenCache) get(key string, create func() oauth2.TokenSource) oauth2.TokenSource {
+	if c == nil {
+		return create()
+	}
+	return c.sources.GetOrAdd(key, create)
+}
+
+// Forgets the cached access tokens, e.g. if the Linode API rejected one of them; a
+// nil cache is a no-op.
+func (c *oauthTokenCache) reset() {
+	if c == nil {
+		return
+	}
+	c.sources.RemoveFunc(func(string, oauth2.TokenSource) bool { return true })
+}
+
+// Returns an access token obtained with the OAuth client credentials in the secret in
+// the namespace. The client ID and secret are never retained: the secret is read for
+// every challenge and only the account key of the credentials is cached.
+func (s *LinodeDNSProviderSolver) oauthToken(creds *OAuthClientCredentials, namespace string) (_ string, err error) {
+	if s.singleNamespace {
+		namespace = s.PodNamespace()
+	}
+
+	var secret *k8sapiv1.Secret
+	if secret, err = s.k8s.CoreV1().Secrets(namespace).Get(s.ctx, creds.SecretRef.Name, k8smetav1.GetOptions{}); err != nil {
+		return "", fmt.Errorf("failed to get secret %q in namespace %q: %w", creds.SecretRef.Name, namespace, err)
+	}
+
+	idKey, secretKey := creds.ClientIDKey, creds.ClientSecretKey
+	if idKey == "" {
+		idKey = DefaultOAuthClientIDKey
+	}
+	if secretKey == "" {
+		secretKey = DefaultOAuthClientSecretKey
+	}
+
+	clientID, clientSecret := string(secret.Data[idKey]), string(secret.Data[secretKey])
+	if clientID == "" || clientSecret == "" {
+		return "", fmt.Errorf("keys %q and %q must be set in secret %s/%s", idKey, secretKey, namespace, creds.SecretRef.Name)
+	}
+
+	config := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     creds.TokenURL,
+		Scopes:       creds.Scopes,
+	}
+
+	key := fmt.Sprintf("%s/%s@%s#%s", namespace, creds.SecretRef.Name, creds.TokenURL, accountKey(clientID+"\x00"+clientSecret))
+	source := s.oauthTokens.get(key, func() oauth2.TokenSource {
+		// The token source fetches every token with the context, so it must outlive
+		// the challenge; the http client bounds each request instead.
+		ctx := context.WithValue(s.ctx, oauth2.HTTPClient, s.oauthHTTPClient())
+		return config.TokenSource(ctx)
+	})
+
+	var token *oauth2.Token
+	if token, err = source.Token(); err != nil {
+		var rerr *oauth2.RetrieveError
+		if errors.As(err, &rerr) && rerr.Response != nil && rerr.Response.StatusCode < http.StatusInternalServerError {
+			return "", fmt.Errorf("%w: the OAuth authorization server rejected the client credentials in secret %s/%s: %v", ErrUnauthorized, namespace, creds.SecretRef.Name, err)
+		}
+		return "", fmt.Errorf("could not obtain an OAuth access token from %s: %w", creds.TokenURL, err)
+	}
+	return token.AccessToken, nil
+}
+
+// Returns the http client of the OAuth token requests, which is the provided http
+// client if set or else a client with the transport of the Linode API clients, so
+// that the requests use the same proxy and CA certificates.
+func (s *LinodeDNSProviderSolver) oauthHTTPClient() *http.Client {
+	if s.httpClient != nil {
+		return s.httpClient
+	}
+
+	hc := &http.Client{Timeout: DefaultTimeout}
+	if s.clients != nil {
+		hc.Transport = s.clients.transport
+	}
+	return hc
+}
@@ -0,0 +1,120 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	k8sapiv1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOAuthClientCredentials(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.oauthTokens = newOAuthTokenCache("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "oauthTokenSources"})
+
+	zone := fake.AddDomain("example.com")
+
+	// The authorization server issues a new access token for every token request.
+	var issued int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "webhook" || secret != "hunter2" || r.FormValue("grant_type") != "client_credentials" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+
+		issued++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "oauth-%d", "token_type": "bearer", "expires_in": 3600}`, issued)
+	}))
+	defer server.Close()
+
+	secret := &k8sapiv1.Secret{
+		ObjectMeta: k8smetav1.ObjectMeta{Name: "linode-oauth", Namespace: "default"},
+		Data:       map[string][]byte{"client_id": []byte("webhook"), "client_secret": []byte("hunter2")},
+	}
+	if _, err := solver.k8s.CoreV1().Secrets("default").Create(solver.ctx, secret, k8smetav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Record the token that authenticates each Linode API request.
+	var (
+		mu     sync.Mutex
+		tokens []string
+	)
+	solver.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasPrefix(req.URL.String(), fake.URL()) {
+			mu.Lock()
+			tokens = append(tokens, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "))
+			mu.Unlock()
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})})
+
+	present := func(key string) error {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge." + key + ".example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: key}
+		ch.Config = &extapi.JSON{Raw: []byte(`{"oauth": {"tokenURL": "` + server.URL + `", "secretRef": {"name": "linode-oauth"}}}`)}
+		return solver.Present(ch)
+	}
+
+	for _, key := range []string{"key1", "key2"} {
+		if err := present(key); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	// The access token is reused until it expires.
+	if issued != 1 {
+		t.Errorf("expected 1 access token to be issued, got %d", issued)
+	}
+
+	for _, token := range tokens {
+		if token != "oauth-1" {
+			t.Fatalf("expected every request to use the access token, got %q", token)
+		}
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 2 {
+		t.Errorf("expected 2 records, got %+v", records)
+	}
+
+	// Rotated client credentials obtain a new token, and rejected credentials are
+	// reported as unauthorized.
+	secret.Data["client_secret"] = []byte("wrong")
+	if _, err := solver.k8s.CoreV1().Secrets("default").Update(solver.ctx, secret, k8smetav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := present("key3"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("expected the rejected client credentials to be unauthorized, got %v", err)
+	}
+}
+
+func TestOAuthConfig(t *testing.T) {
+	testCases := map[string]string{
+		"secret ref": `{"apiKeySecretRef": {"name": "linode", "key": "token"}, "oauth": {"tokenURL": "https://auth.example.com/token", "secretRef": {"name": "oauth"}}}`,
+		"token url":  `{"oauth": {"tokenURL": "/token", "secretRef": {"name": "oauth"}}}`,
+		"secret":     `{"oauth": {"tokenURL": "https://auth.example.com/token"}}`,
+	}
+
+	for name, config := range testCases {
+		if _, err := LoadConfig(&extapi.JSON{Raw: []byte(config)}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: expected an invalid config error, got %v", name, err)
+		}
+	}
+
+	// Issuers may not read OAuth client secrets in single namespace mode.
+	solver := &LinodeDNSProviderSolver{singleNamespace: true}
+	if _, err := solver.loadConfig(&extapi.JSON{Raw: []byte(`{"oauth": {"tokenURL": "https://auth.example.com/token", "secretRef": {"name": "oauth"}}}`)}); !errors.Is(err, ErrSecretRefNotAllowed) {
+		t.Errorf("expected ErrSecretRefNotAllowed, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package acme
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+const (
+	DefaultKubeQPS   float32 = 0
+	DefaultKubeBurst int     = 0
+)
+
+// AddFlags registers the solver specific command line flags on the webhook server's
+// flag set so that they can be specified alongside the cert-manager server flags.
+// The default value of each flag is read from its environment variable if set.
+func (s *LinodeDNSProviderSolver) AddFlags(fs *pflag.FlagSet) {
+	if err := s.features.Set(envString("FEATURE_GATES", "")); err != nil {
+		klog.Warningf("could not parse FEATURE_GATES, using default feature gates: %v", err)
+	}
+
+	fs.Float32Var(&s.kubeQPS, "kube-api-qps", envFloat32("KUBE_API_QPS", DefaultKubeQPS), "maximum queries per second to the Kubernetes API when reading secrets (0 for the client-go default, negative to disable client-side rate limiting)")
+	fs.IntVar(&s.kubeBurst, "kube-api-burst", envInt("KUBE_API_BURST", DefaultKubeBurst), "maximum burst of queries to the Kubernetes API above the QPS limit (0 for the client-go default)")
+	fs.StringVar(&s.linodeAPIURL, "linode-api-url", envString("LINODE_API_URL", ""), "base URL of the linode API, e.g. a mock server or an internal API proxy (defaults to $LINODE_URL or the linode API)")
+	fs.StringSliceVar(&s.allowedAPIURLs, "linode-allowed-api-urls", envStrings("LINODE_ALLOWED_API_URLS", nil), "base URLs of the linode API that issuers may set as their apiURL (issuers may not set one if empty)")
+	fs.StringVar(&s.transportOptions.caFile, "linode-ca-file", envString("LINODE_CA_FILE", ""), "path to a PEM bundle of CA certificates trusted for the linode API in addition to the system roots, e.g. of a TLS-intercepting egress proxy")
+	fs.IntVar(&s.transportOptions.maxIdleConns, "linode-max-idle-conns", envInt("LINODE_MAX_IDLE_CONNS", 0), "maximum number of idle connections to the linode API kept by the client of each API token (0 for 100)")
+	fs.IntVar(&s.transportOptions.maxIdleConnsPerHost, "linode-max-idle-conns-per-host", envInt("LINODE_MAX_IDLE_CONNS_PER_HOST", 0), "maximum number of idle connections to each linode API host kept by the client of each API token (0 for 2)")
+	fs.DurationVar(&s.transportOptions.idleConnTimeout, "linode-idle-conn-timeout", envDuration("LINODE_IDLE_CONN_TIMEOUT", 0), "how long an idle connection to the linode API is kept open (0 for 90s)")
+	fs.DurationVar(&s.transportOptions.dialTimeout, "linode-dial-timeout", envDuration("LINODE_DIAL_TIMEOUT", 0), "timeout of establishing a connection to the linode API (0 for 30s)")
+	fs.StringVar(&s.transportOptions.ipFamily, "linode-ip-family", envString("LINODE_IP_FAMILY", ""), "only connect to the linode API over ipv4 or ipv6, e.g. if the egress of the other family is broken (empty for both)")
+	fs.StringVar(&s.transportOptions.tlsMinVersion, "linode-tls-min-version", envString("LINODE_TLS_MIN_VERSION", ""), "minimum TLS version of connections to the linode API, 1.2 or 1.3 (empty for 1.2)")
+	fs.BoolVar(&s.verifyTokenScope, "linode-verify-token-scope", envBool("LINODE_VERIFY_TOKEN_SCOPE", false), "verify that every linode API token has the domains:read_write scope before it is used to solve a challenge")
+	fs.StringVar(&s.userAgentSuffix, "user-agent-suffix", envString("USER_AGENT_SUFFIX", ""), "identifier of the deployment appended to the User-Agent of linode API requests, e.g. to distinguish cert-manager installations in the linode API logs")
+	fs.BoolVar(&s.linodeDebug, "linode-debug", envBool("LINODE_DEBUG", false), "log every linode API request and response for troubleshooting, with the API tokens redacted")
+	fs.Float32Var(&s.linodeQPS, "linode-api-qps", envFloat32("LINODE_API_QPS", 0), "maximum requests per second to the linode API with each API token, shared by all challenges solved with the token (0 for unlimited)")
+	fs.IntVar(&s.linodeBurst, "linode-api-burst", envInt("LINODE_API_BURST", 0), "maximum burst of requests to the linode API with each API token above the QPS limit (0 for the QPS rounded up)")
+	fs.DurationVar(&s.linodeTimeout, "linode-timeout", envDuration("LINODE_TIMEOUT", DefaultTimeout), "timeout of each linode API operation and of the propagation check of issuers that do not set timeoutSeconds")
+	fs.DurationVar(&s.linodeReadTimeout, "linode-read-timeout", envDuration("LINODE_READ_TIMEOUT", 0), "timeout of the linode API operations that list or read domains and records of issuers that do not set readTimeoutSeconds (0 for --linode-timeout)")
+	fs.DurationVar(&s.linodeWriteTimeout, "linode-write-timeout", envDuration("LINODE_WRITE_TIMEOUT", 0), "timeout of the linode API operations that create, update, or delete records of issuers that do not set writeTimeoutSeconds (0 for --linode-timeout)")
+	fs.IntVar(&s.recordTTL, "record-ttl", envInt("RECORD_TTL", 0), "TTL in seconds of the challenge TXT records of issuers that do not set ttlSeconds, rounded up to a TTL supported by linode (0 for 180)")
+	fs.IntVar(&s.maxInflight, "max-inflight", envInt("MAX_INFLIGHT", 0), "maximum number of concurrent present and cleanup operations (0 for unlimited)")
+	fs.IntVar(&s.maxQueue, "max-queue", envInt("MAX_QUEUE", -1), "maximum number of operations waiting for an in-flight slot before requests are shed (-1 for the value of --max-inflight, 0 to shed immediately)")
+	fs.DurationVar(&s.maxQueueWait, "max-queue-wait", envDuration("MAX_QUEUE_WAIT", DefaultMaxQueueWait), "maximum time an operation waits for an in-flight slot before it is shed")
+	fs.DurationVar(&s.presentCacheTTL, "present-cache-ttl", envDuration("PRESENT_CACHE_TTL", DefaultPresentCacheTTL), "how long a successful present is remembered so that repeated presents of the same challenge skip the linode API (0 to disable)")
+	fs.DurationVar(&s.zoneCacheTTL, "zone-cache-ttl", envDuration("ZONE_CACHE_TTL", DefaultZoneCacheTTL), "how long the linode domains of zones are cached so that challenges in the same zone do not look up the zone again (0 to disable)")
+	fs.IntVar(&s.cacheSize, "cache-size", envInt("CACHE_SIZE", DefaultCacheSize), "maximum number of entries held by each in-memory cache before the least recently used entries are evicted")
+	fs.StringVar((*string)(&s.recordMode), "record-mode", envString("RECORD_MODE", string(RecordModeUpsert)), "how challenge records are managed: upsert updates the existing record for the name, create always creates a record per key and deletes exactly that record, append creates a record per distinct key without updating other records")
+	fs.DurationVar(&s.minRecordAge, "min-record-age", envDuration("MIN_RECORD_AGE", 0), "refuse to delete challenge records that were created or updated more recently than this unless the issuer sets forceCleanup (0 to disable)")
+	fs.StringSliceVar(&s.quorumResolvers, "quorum-resolvers", envStrings("QUORUM_RESOLVERS", nil), "recursive resolvers (host:port) queried by the quorum propagation check (defaults to well known public resolvers)")
+	fs.IntVar(&s.quorum, "quorum", envInt("QUORUM", 0), "number of resolvers that must serve the record for the quorum propagation check (0 for a majority)")
+	fs.BoolVar(&s.confirmEvents, "confirm-events", envBool("CONFIRM_EVENTS", false), "confirm every record mutation by polling the linode account events until the domain record event has completed")
+	fs.StringVar(&s.auditFile, "audit-file", envString("AUDIT_FILE", ""), "path to a JSON-lines audit log of every challenge operation and DNS mutation, or - for stdout (disabled if empty)")
+	fs.IntVar(&s.auditMaxSize, "audit-max-size", envInt("AUDIT_MAX_SIZE", DefaultAuditMaxSize), "maximum size in megabytes of the audit log before it is rotated")
+	fs.IntVar(&s.auditMaxBackups, "audit-max-backups", envInt("AUDIT_MAX_BACKUPS", DefaultAuditMaxBackups), "maximum number of rotated audit logs to keep")
+	fs.BoolVar(&s.auditIssuers, "audit-issuers", envBool("AUDIT_ISSUERS", false), "read the issuer of each challenge resource for the audit events of its DNS mutations (requires permission to read challenges)")
+	fs.StringVar(&s.cloudEventsSink, "cloudevents-sink", envString("CLOUDEVENTS_SINK", ""), "URL of an HTTP endpoint that challenge lifecycle CloudEvents are posted to (disabled if empty)")
+	fs.StringVar(&s.cloudEventsSource, "cloudevents-source", envString("CLOUDEVENTS_SOURCE", DefaultCloudEventsSource), "source attribute of the CloudEvents posted to the sink")
+	fs.StringVar(&s.settingsFile, "settings-file", envString("SETTINGS_FILE", ""), "path to a YAML or JSON file, e.g. a mounted ConfigMap, of operational settings that are reloaded and applied while the webhook is running")
+	fs.Var(&s.features, "feature-gates", "comma separated list of Feature=true|false pairs that enable or disable experimental features")
+	fs.BoolVar(&s.trackRecords, "track-records", envBool("TRACK_RECORDS", false), "keep a journal of presented TXT records so that drift from the records in Linode can be reconciled")
+	fs.DurationVar(&s.reconcileEvery, "reconcile-interval", envDuration("RECONCILE_INTERVAL", DefaultReconcileInterval), "how often tracked records are reconciled against Linode when record tracking is enabled (0 to disable)")
+	fs.BoolVar(&s.dedupeRecords, "dedupe-records", envBool("DEDUPE_RECORDS", false), "collapse TXT records with identical names and values in the zones of tracked records into one on every reconcile")
+	fs.StringSliceVar(&s.purgePatterns, "purge-patterns", envStrings("PURGE_PATTERNS", nil), "glob patterns of the names (within the zone) of TXT records left by other ACME tooling that are reported on reconcile, e.g. _acme-challenge*")
+	fs.DurationVar(&s.purgeMinAge, "purge-min-age", envDuration("PURGE_MIN_AGE", DefaultPurgeMinAge), "minimum age of foreign challenge records matching the purge patterns before they are reported or purged")
+	fs.BoolVar(&s.purgeForeignRecords, "purge-foreign-records", envBool("PURGE_FOREIGN_RECORDS", false), "delete the foreign challenge records matching the purge patterns rather than only reporting them")
+	fs.IntVar(&s.maxZoneRecords, "zone-record-threshold", envInt("ZONE_RECORD_THRESHOLD", 0), "warn when a zone holds more than this many records after a challenge is presented (0 to disable)")
+	fs.IntVar(&s.maxChallengeRecords, "zone-challenge-threshold", envInt("ZONE_CHALLENGE_THRESHOLD", 0), "warn when a zone holds more than this many _acme-challenge TXT records after a challenge is presented (0 to disable)")
+	fs.DurationVar(&s.credentialCheckEvery, "credential-check-interval", envDuration("CREDENTIAL_CHECK_INTERVAL", 0), "how often the linode API tokens used by the webhook are validated and their expiry checked (0 to disable)")
+	fs.DurationVar(&s.credentialWarning, "credential-expiry-warning", envDuration("CREDENTIAL_EXPIRY_WARNING", DefaultCredentialExpiryWarning), "emit warning events for linode API tokens that expire within this period")
+	fs.DurationVar(&s.prewarmWindow, "prewarm-window", envDuration("PREWARM_WINDOW", 0), "pre-warm the zone lookups of certificates that will be renewed within this window so that their challenges are presented faster (requires RBAC permissions to watch certificates and read issuers; 0 disables)")
+	fs.BoolVar(&s.annotate, "annotate-challenges", envBool("ANNOTATE_CHALLENGES", false), "annotate challenge resources with the linode domain and record IDs and emit an event targeting them (requires RBAC permissions on challenges and events)")
+	fs.StringVar(&s.policyFile, "policy-file", envString("POLICY_FILE", ""), "path to a YAML or JSON file of CEL rules that must allow every DNS mutation (disabled if empty)")
+	fs.StringVar(&s.routesFile, "routes-file", envString("ROUTES_FILE", ""), "path to a YAML or JSON file that maps zone suffixes to the linode API token secrets used for challenges in those zones")
+	fs.StringVar(&s.fallbackProvider, "fallback-provider", envString("FALLBACK_PROVIDER", ""), "secondary dns provider used for zones not hosted in linode or when the linode API is unavailable (supported: rfc2136)")
+	fs.StringVar(&s.rfc2136.Nameserver, "rfc2136-nameserver", envString("RFC2136_NAMESERVER", ""), "host:port of the nameserver that accepts rfc2136 updates for the rfc2136 fallback provider")
+	fs.StringVar(&s.rfc2136.TSIGKeyName, "rfc2136-tsig-key-name", envString("RFC2136_TSIG_KEY_NAME", ""), "name of the TSIG key used to sign rfc2136 updates")
+	fs.StringVar(&s.rfc2136.TSIGAlgorithm, "rfc2136-tsig-algorithm", envString("RFC2136_TSIG_ALGORITHM", "hmac-sha256"), "algorithm of the TSIG key used to sign rfc2136 updates")
+	fs.StringVar(&s.rfc2136.TSIGSecretFile, "rfc2136-tsig-secret-file", envString("RFC2136_TSIG_SECRET_FILE", ""), "path to a file containing the base64 encoded TSIG secret used to sign rfc2136 updates")
+	fs.IntVar(&s.failureLimit, "failure-threshold", envInt("FAILURE_THRESHOLD", 0), "number of consecutive linode API failures after which the webhook is marked unready (0 to disable)")
+	fs.DurationVar(&s.failureCooldown, "failure-cooldown", envDuration("FAILURE_COOLDOWN", DefaultFailureCooldown), "how long the webhook stays unready after the failure threshold is reached before it probes linode again")
+	fs.StringVar(&s.namespace, "namespace", "", "namespace the webhook is running in (defaults to $POD_NAMESPACE, the --namespace-file, or the service account namespace)")
+	fs.StringVar(&s.namespaceFile, "namespace-file", envString("NAMESPACE_FILE", ""), "path to a file containing the webhook namespace, e.g. mounted with the downward API")
+	fs.BoolVar(&s.namespaceCredentials, "namespace-credentials", envBool("NAMESPACE_CREDENTIALS", false), "look for a linode API token secret with the default name or the "+AnnotationCredentials+" annotation in the challenge namespace before falling back to the webhook's namespace")
+	fs.BoolVar(&s.delegationCheck, "check-delegation", envBool("CHECK_DELEGATION", false), "verify that the published NS records of the zone include the linode nameservers before presenting a challenge")
+	fs.BoolVar(&s.singleNamespace, "single-namespace", envBool("SINGLE_NAMESPACE", false), "only read the linode API token secret from the webhook's namespace and reject per-issuer secret references")
+}
+
+//===========================================================================
+// Environment Helpers
+//===========================================================================
+
+func envString(key, defaultValue string) string {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		return val
+	}
+	return defaultValue
+}
+
+func envStrings(key string, defaultValue []string) []string {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		var vals []string
+		for _, s := range strings.Split(val, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				vals = append(vals, s)
+			}
+		}
+		return vals
+	}
+	return defaultValue
+}
+
+func envInt(key string, defaultValue int) int {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			klog.Warningf("could not parse %s=%q as an integer, using default %d", key, val, defaultValue)
+			return defaultValue
+		}
+		return i
+	}
+	return defaultValue
+}
+
+func envBool(key string, defaultValue bool) bool {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			klog.Warningf("could not parse %s=%q as a boolean, using default %t", key, val, defaultValue)
+			return defaultValue
+		}
+		return b
+	}
+	return defaultValue
+}
+
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			klog.Warningf("could not parse %s=%q as a duration, using default %s", key, val, defaultValue)
+			return defaultValue
+		}
+		return d
+	}
+	return defaultValue
+}
+
+func envFloat32(key string, defaultValue float32) float32 {
+	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
+		f, err := strconv.ParseFloat(val, 32)
+		if err != nil {
+			klog.Warningf("could not parse %s=%q as a number, using default %v", key, val, defaultValue)
+			return defaultValue
+		}
+		return float32(f)
+	}
+	return defaultValue
+}
@@ -0,0 +1,238 @@
+package acme
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Policy is a set of CEL rules evaluated before every DNS mutation the webhook makes,
+// so that platform teams can centrally restrict which names each namespace or issuer
+// may validate. Policies are loaded from a YAML or JSON file of the form:
+//
+//	rules:
+//	  - name: team-x
+//	    expression: request.namespace != "x" || request.fqdn.endsWith(".x.example.com")
+//	    message: namespace x may only validate *.x.example.com
+//
+// Every rule must evaluate to true for the mutation to be allowed. The expressions
+// can refer to the following fields of the request:
+//
+//	request.action     "present" or "cleanup"
+//	request.namespace  the namespace of the challenge
+//	request.dnsName    the name being validated, e.g. *.example.com
+//	request.fqdn       the challenge record, e.g. _acme-challenge.example.com
+//	request.zone       the zone the record is in, e.g. example.com
+//	request.entry      the name of the record in the zone, e.g. _acme-challenge
+//	request.domainID   the ID of the Linode domain (0 for the fallback provider)
+//	request.issuer     the kind and name of the issuer, e.g. {"kind": "ClusterIssuer", "name": "letsencrypt"}
+//
+// Names are lower case without a trailing dot. The issuer is not part of a challenge
+// request, so it is read from the Challenge resource only if a rule refers to it.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+
+	programs    []cel.Program
+	needsIssuer bool
+}
+
+// PolicyRule is a CEL expression that must evaluate to true to allow a mutation.
+type PolicyRule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Message    string `json:"message,omitempty"`
+}
+
+// PolicyRequest describes the mutation that the policy is evaluated for.
+type PolicyRequest struct {
+	Action    string
+	Namespace string
+	DNSName   string
+	FQDN      string
+	Zone      string
+	Entry     string
+	DomainID  int
+	Issuer    PolicyIssuer
+}
+
+// PolicyIssuer identifies the issuer of the challenge; it is empty if the Challenge
+// resource could not be found.
+type PolicyIssuer struct {
+	Kind string
+	Name string
+}
+
+// LoadPolicy reads the policy from the specified file and compiles its rules.
+func LoadPolicy(path string) (_ *Policy, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("could not read policy file: %w", err)
+	}
+
+	policy := &Policy{}
+	if err = yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("could not parse policy file %q: %w", path, err)
+	}
+
+	if err = policy.Compile(); err != nil {
+		return nil, fmt.Errorf("policy file %q: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Compile compiles the rules of the policy, rejecting rules that are not valid CEL
+// expressions or do not evaluate to a bool.
+func (p *Policy) Compile() (err error) {
+	var env *cel.Env
+	if env, err = cel.NewEnv(cel.Variable("request", cel.MapType(cel.StringType, cel.DynType))); err != nil {
+		return err
+	}
+
+	p.programs = make([]cel.Program, 0, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("%w: rule %d must have a name", ErrInvalidPolicy, i)
+		}
+
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return fmt.Errorf("%w: rule %q: %v", ErrInvalidPolicy, rule.Name, issues.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType {
+			return fmt.Errorf("%w: rule %q must evaluate to a bool, not %s", ErrInvalidPolicy, rule.Name, ast.OutputType())
+		}
+
+		var program cel.Program
+		if program, err = env.Program(ast); err != nil {
+			return fmt.Errorf("%w: rule %q: %v", ErrInvalidPolicy, rule.Name, err)
+		}
+
+		p.programs = append(p.programs, program)
+		p.needsIssuer = p.needsIssuer || strings.Contains(rule.Expression, "issuer")
+	}
+	return nil
+}
+
+// Evaluate returns an error wrapping ErrPolicyDenied if any rule does not allow the
+// request; rules that fail to evaluate deny the request. A nil policy allows every
+// request.
+func (p *Policy) Evaluate(req PolicyRequest) error {
+	if p == nil {
+		return nil
+	}
+
+	input := map[string]any{
+		"request": map[string]any{
+			"action":    req.Action,
+			"namespace": req.Namespace,
+			"dnsName":   normalizeZone(req.DNSName),
+			"fqdn":      normalizeZone(req.FQDN),
+			"zone":      normalizeZone(req.Zone),
+			"entry":     strings.ToLower(req.Entry),
+			"domainID":  req.DomainID,
+			"issuer":    map[string]any{"kind": req.Issuer.Kind, "name": req.Issuer.Name},
+		},
+	}
+
+	for i, program := range p.programs {
+		rule := p.Rules[i]
+		out, _, err := program.Eval(input)
+		if err != nil {
+			return fmt.Errorf("%w by rule %q: %v", ErrPolicyDenied, rule.Name, err)
+		}
+
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			message := rule.Message
+			if message == "" {
+				message = rule.Expression
+			}
+			return fmt.Errorf("%w by rule %q: %s", ErrPolicyDenied, rule.Name, message)
+		}
+	}
+	return nil
+}
+
+// Returns true if a rule of the policy refers to the issuer; a nil policy has no rules.
+func (p *Policy) needsIssuers() bool {
+	return p != nil && p.needsIssuer
+}
+
+// Loads the policy file if configured, logging the number of rules loaded.
+func (s *LinodeDNSProviderSolver) loadPolicy() (err error) {
+	if s.policyFile == "" {
+		return nil
+	}
+
+	var policy *Policy
+	if policy, err = LoadPolicy(s.policyFile); err != nil {
+		return err
+	}
+
+	s.policy.Store(policy)
+	klog.Infof("loaded %d authorization policy rules from %s", len(policy.Rules), s.policyFile)
+	return nil
+}
+
+// Evaluates the authorization policy for a mutation of the challenge record in the
+// zone, reading the issuer from the Challenge resource if a rule needs it.
+func (s *LinodeDNSProviderSolver) authorize(ch *v1alpha1.ChallengeRequest, action, zone, entry string, domainID int) (err error) {
+	policy := s.policy.Load()
+	if policy == nil {
+		return nil
+	}
+
+	req := PolicyRequest{
+		Action:    action,
+		Namespace: ch.ResourceNamespace,
+		DNSName:   ch.DNSName,
+		FQDN:      ch.ResolvedFQDN,
+		Zone:      zone,
+		Entry:     entry,
+		DomainID:  domainID,
+	}
+
+	if policy.needsIssuer && s.challenges != nil {
+		if req.Issuer, err = s.challengeIssuer(ch); err != nil {
+			klog.Warningf("could not find the issuer of the challenge for %s: %v", ch.ResolvedFQDN, err)
+		}
+	}
+
+	if err = policy.Evaluate(req); err != nil {
+		klog.Warningf("%s of %s in namespace %s: %v", action, ch.ResolvedFQDN, ch.ResourceNamespace, err)
+		return err
+	}
+	return nil
+}
+
+// Evaluates the authorization policy for a mutation made by the fallback provider in
+// the zone resolved by cert-manager.
+func (s *LinodeDNSProviderSolver) authorizeFallback(ch *v1alpha1.ChallengeRequest, action string) error {
+	entry, zone := DomainEntry(normalizeZone(ch.ResolvedFQDN), normalizeZone(ch.ResolvedZone))
+	return s.authorize(ch, action, zone, entry, 0)
+}
+
+// Returns the issuer of the Challenge resource with the request's key.
+func (s *LinodeDNSProviderSolver) challengeIssuer(ch *v1alpha1.ChallengeRequest) (issuer PolicyIssuer, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), annotateTimeout)
+	defer cancel()
+
+	var challenge *unstructured.Unstructured
+	if challenge, err = s.challenges.findChallenge(ctx, ch); err != nil || challenge == nil {
+		return issuer, err
+	}
+
+	issuer.Kind, _, _ = unstructured.NestedString(challenge.Object, "spec", "issuerRef", "kind")
+	issuer.Name, _, _ = unstructured.NestedString(challenge.Object, "spec", "issuerRef", "name")
+	if issuer.Kind == "" {
+		issuer.Kind = "Issuer"
+	}
+	return issuer, nil
+}
@@ -0,0 +1,144 @@
+package acme
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const testPolicy = `
+rules:
+  - name: team-x
+    expression: request.namespace != "x" || request.fqdn.endsWith(".x.example.com")
+    message: namespace x may only validate *.x.example.com
+  - name: production
+    expression: request.zone != "example.org" || request.issuer.name == "production"
+`
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(testPolicy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("could not load policy: %v", err)
+	}
+
+	if !policy.needsIssuer {
+		t.Error("expected the policy to need the issuer")
+	}
+
+	testCases := []struct {
+		req     PolicyRequest
+		allowed bool
+	}{
+		{PolicyRequest{Action: "present", Namespace: "x", FQDN: "_acme-challenge.www.x.example.com.", Zone: "example.com"}, true},
+		{PolicyRequest{Action: "present", Namespace: "x", FQDN: "_acme-challenge.www.example.com.", Zone: "example.com"}, false},
+		{PolicyRequest{Action: "present", Namespace: "y", FQDN: "_acme-challenge.www.example.com.", Zone: "example.com"}, true},
+		{PolicyRequest{Action: "present", Namespace: "y", FQDN: "_acme-challenge.example.org.", Zone: "example.org"}, false},
+		{PolicyRequest{Action: "present", Namespace: "y", FQDN: "_acme-challenge.example.org.", Zone: "example.org", Issuer: PolicyIssuer{Kind: "ClusterIssuer", Name: "production"}}, true},
+	}
+
+	for i, tc := range testCases {
+		err := policy.Evaluate(tc.req)
+		if tc.allowed && err != nil {
+			t.Errorf("test case %d: expected request to be allowed, got %v", i, err)
+		}
+
+		if !tc.allowed && !errors.Is(err, ErrPolicyDenied) {
+			t.Errorf("test case %d: expected request to be denied, got %v", i, err)
+		}
+	}
+
+	// A nil policy allows every request.
+	var disabled *Policy
+	if err := disabled.Evaluate(PolicyRequest{}); err != nil {
+		t.Errorf("expected a nil policy to allow requests, got %v", err)
+	}
+}
+
+func TestPolicyCompile(t *testing.T) {
+	testCases := []PolicyRule{
+		{Expression: "true"},
+		{Name: "syntax", Expression: "request.namespace =="},
+		{Name: "type", Expression: "request.namespace"},
+	}
+
+	for _, rule := range testCases {
+		policy := &Policy{Rules: []PolicyRule{rule}}
+		if err := policy.Compile(); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("expected rule %q to be invalid, got %v", rule.Name, err)
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	policy := &Policy{Rules: []PolicyRule{
+		{Name: "issuer", Expression: `request.issuer.kind == "ClusterIssuer" && request.issuer.name == "letsencrypt"`},
+		{Name: "cleanup", Expression: `request.action == "cleanup" || request.entry == "_acme-challenge.www"`},
+	}}
+	if err := policy.Compile(); err != nil {
+		t.Fatalf("could not compile policy: %v", err)
+	}
+	solver.policy.Store(policy)
+
+	challenge := func(name, key string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "acme.cert-manager.io/v1",
+			"kind":       "Challenge",
+			"metadata":   map[string]any{"name": name, "namespace": "default"},
+			"spec": map[string]any{
+				"key":       key,
+				"issuerRef": map[string]any{"kind": "ClusterIssuer", "name": "letsencrypt"},
+			},
+		}}
+	}
+
+	scheme := runtime.NewScheme()
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		challengeResource: "ChallengeList",
+	}, challenge("www-1234", "key1"), challenge("api-1234", "key2"))
+	solver.challenges = &challengeAnnotator{dynamic: dyn}
+
+	zone := fake.AddDomain("example.com")
+
+	// Challenges allowed by the policy are presented.
+	www := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.www.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(www); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	// Challenges denied by the policy do not mutate the zone.
+	api := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.api.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key2"}
+	if err := solver.Present(api); !errors.Is(err, ErrPolicyDenied) {
+		t.Fatalf("expected the challenge to be denied, got %v", err)
+	}
+
+	// Challenges without a challenge resource have no issuer.
+	unknown := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.www.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key3"}
+	if err := solver.Present(unknown); !errors.Is(err, ErrPolicyDenied) {
+		t.Fatalf("expected the challenge to be denied, got %v", err)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 1 || records[0].Target != "key1" {
+		t.Errorf("expected only the allowed record to be created, got %+v", records)
+	}
+
+	if err := solver.CleanUp(www); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 0 {
+		t.Errorf("expected the record to be cleaned up, got %+v", records)
+	}
+}
@@ -0,0 +1,93 @@
+package acme
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+const DefaultPresentCacheTTL = 30 * time.Second
+
+// Caches the successful outcome of a Present for a short window so that the rapid
+// re-invocations of Present for the same challenge by cert-manager's check loop
+// return immediately without touching the Linode API again. A record that is deleted
+// by another tool during the window is not recreated until the window expires, so
+// the window should stay short; CleanUp forgets the challenge immediately. Only the
+// last key presented for a name is remembered since in upsert mode presenting another
+// key replaces the record.
+type presentCache struct {
+	ttl       time.Duration
+	presented *lru[string, presented]
+	hits      atomic.Uint64
+}
+
+type presented struct {
+	key string
+	at  time.Time
+}
+
+// PresentCacheStats reports how many Presents were answered from the cache.
+type PresentCacheStats struct {
+	TTL  string `json:"ttl"`
+	Hits uint64 `json:"hits"`
+}
+
+// Returns nil if the ttl is not positive, disabling the cache.
+func newPresentCache(solver string, ttl time.Duration) *presentCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &presentCache{
+		ttl:       ttl,
+		presented: newLRU[string, presented](solver, "presentCache", DefaultCacheSize, nil),
+	}
+}
+
+// Reports whether the challenge was presented successfully within the window; a nil
+// cache never hits.
+func (c *presentCache) hit(ch *v1alpha1.ChallengeRequest) bool {
+	if c == nil {
+		return false
+	}
+
+	last, ok := c.presented.Get(presentKey(ch))
+	if !ok || last.key != ch.Key || time.Since(last.at) >= c.ttl {
+		return false
+	}
+
+	c.hits.Add(1)
+	return true
+}
+
+func (c *presentCache) store(ch *v1alpha1.ChallengeRequest) {
+	if c == nil {
+		return
+	}
+	c.presented.Add(presentKey(ch), presented{key: ch.Key, at: time.Now()})
+}
+
+func (c *presentCache) forget(ch *v1alpha1.ChallengeRequest) {
+	if c == nil {
+		return
+	}
+
+	if last, ok := c.presented.Get(presentKey(ch)); ok && last.key == ch.Key {
+		c.presented.Remove(presentKey(ch))
+	}
+}
+
+// Stats returns the present cache stats; a nil cache returns nil.
+func (c *presentCache) Stats() *PresentCacheStats {
+	if c == nil {
+		return nil
+	}
+	return &PresentCacheStats{TTL: c.ttl.String(), Hits: c.hits.Load()}
+}
+
+// Challenges in different namespaces may be configured with different credentials or
+// issuers, so the namespace is part of the cache key as well as the name.
+func presentKey(ch *v1alpha1.ChallengeRequest) string {
+	return ch.ResourceNamespace + " " + ch.ResolvedFQDN
+}
@@ -0,0 +1,75 @@
+package acme
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+)
+
+func TestPresentCache(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.presents = newPresentCache("test", time.Minute)
+	defer caches.Delete(cacheKey{solver: "test", name: "presentCache"})
+
+	zone := fake.AddDomain("example.com")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	// Repeated presents of the same challenge only touch the Linode API once.
+	for range 3 {
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected one linode lookup, got %d", calls)
+	}
+
+	if stats := solver.presents.Stats(); stats.Hits != 2 {
+		t.Errorf("expected 2 cache hits, got %+v", stats)
+	}
+
+	// Other keys for the same name are not answered from the cache, and since the
+	// record was updated with the other key the first key is presented again.
+	other := ch.DeepCopy()
+	other.Key = "key2"
+	if err := solver.Present(other); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	// Once cleaned up, the challenge is presented again.
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	if stats := solver.presents.Stats(); stats.Hits != 2 {
+		t.Errorf("expected no further cache hits, got %+v", stats)
+	}
+
+	// Entries expire after the window.
+	if newPresentCache("test", 0) != nil {
+		t.Error("expected the cache to be disabled without a ttl")
+	}
+
+	solver.presents.ttl = time.Nanosecond
+	if solver.presents.hit(ch) {
+		t.Error("expected expired challenges to miss the cache")
+	}
+}
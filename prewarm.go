@@ -0,0 +1,291 @@
+package acme
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Warmed domain lists are kept for this long after the renewal time of the
+	// certificate that warmed them, so that they outlast the order and its challenges.
+	DefaultPrewarmTTL = 10 * time.Minute
+
+	// Certificates are re-examined this often so that they are warmed when they enter
+	// the renewal window even if they have not changed.
+	prewarmResync = time.Minute
+)
+
+// The cert-manager resources that are watched to pre-warm the zone lookups of renewals.
+var (
+	certificateResource   = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+	issuerResource        = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "issuers"}
+	clusterIssuerResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "clusterissuers"}
+)
+
+// Warmed lists of the domains in Linode accounts, keyed by the account of the client
+// that listed them. A client with the cache finds zones in a warmed list rather than
+// listing the domains of the account, so that a Present for a renewal that was
+// anticipated skips the slowest lookup it makes.
+type domainCache struct {
+	accounts *lru[string, warmDomains]
+}
+
+type warmDomains struct {
+	domains []linodego.Domain
+	expires time.Time
+}
+
+func newDomainCache(solver string) *domainCache {
+	return &domainCache{accounts: newLRU[string, warmDomains](solver, "domainCache", DefaultCacheSize, nil)}
+}
+
+// Returns the warmed domains of the account unless they have expired; a nil cache
+// has no domains.
+func (c *domainCache) get(account string) ([]linodego.Domain, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	warm, ok := c.accounts.Get(account)
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(warm.expires) {
+		c.accounts.Remove(account)
+		return nil, false
+	}
+	return warm.domains, true
+}
+
+// Stores the domains of the account until the expiry, keeping a later expiry if the
+// account was already warmed by another certificate.
+func (c *domainCache) add(account string, domains []linodego.Domain, expires time.Time) {
+	if warm, ok := c.accounts.Get(account); ok && warm.expires.After(expires) {
+		expires = warm.expires
+	}
+	c.accounts.Add(account, warmDomains{domains: domains, expires: expires})
+}
+
+// Discards the warmed domains of the account; a nil cache is a no-op.
+func (c *domainCache) remove(account string) {
+	if c == nil {
+		return
+	}
+	c.accounts.Remove(account)
+}
+
+// SetDomainCache makes the client find zones in the domain lists warmed in the cache.
+func (l *Linode) SetDomainCache(cache *domainCache) *Linode {
+	l.domains = cache
+	return l
+}
+
+// Lists the domains in the account and stores them in the domain cache until the
+// expiry so that later clients for the same account do not list them again.
+func (l *Linode) warmDomains(expires time.Time) (err error) {
+	ctx, cancel := l.context()
+	defer cancel()
+
+	var domains []linodego.Domain
+	if domains, err = l.client.ListDomains(ctx, linodego.NewListOptions(0, "")); err != nil {
+		return apiError(err)
+	}
+
+	l.domains.add(l.account, domains, expires)
+	return nil
+}
+
+// The prewarmer watches Certificate resources and, shortly before cert-manager renews
+// one, resolves the Linode zones of its DNS names with the credentials its issuer
+// would use, so that the Present calls of the renewal find their zones without
+// listing the domains of the account. Pre-warming is best effort: certificates whose
+// issuer cannot be read are warmed with the zone routes or default credentials, and
+// missing RBAC permissions are reported once.
+type certificatePrewarmer struct {
+	solver    *LinodeDNSProviderSolver
+	dynamic   dynamic.Interface
+	window    time.Duration
+	warmed    *lru[string, string]
+	forbidden sync.Once
+}
+
+func newCertificatePrewarmer(solver *LinodeDNSProviderSolver, dyn dynamic.Interface, window time.Duration) *certificatePrewarmer {
+	return &certificatePrewarmer{
+		solver:  solver,
+		dynamic: dyn,
+		window:  window,
+		warmed:  newLRU[string, string](solver.Name(), "prewarmedCertificates", DefaultCacheSize, nil),
+	}
+}
+
+// Watches the certificates in every namespace until the stop channel is closed.
+func (p *certificatePrewarmer) run(stopCh <-chan struct{}) {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(p.dynamic, prewarmResync)
+	informer := factory.ForResource(certificateResource).Informer()
+
+	informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+		if k8serrors.IsForbidden(err) {
+			p.forbidden.Do(func() {
+				klog.Warningf("not permitted to watch certificate resources, grant list and watch on certificates.cert-manager.io to pre-warm renewals: %v", err)
+			})
+			return
+		}
+		cache.DefaultWatchErrorHandler(context.Background(), r, err)
+	})
+
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    p.observe,
+		UpdateFunc: func(_, obj any) { p.observe(obj) },
+	}); err != nil {
+		klog.Warningf("could not watch certificate resources: %v", err)
+		return
+	}
+	factory.Start(stopCh)
+}
+
+// Warms the zone lookups of the certificate if its renewal time is within the window
+// and it has not already been warmed for that renewal.
+func (p *certificatePrewarmer) observe(obj any) {
+	cert, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	value, _, _ := unstructured.NestedString(cert.Object, "status", "renewalTime")
+	if value == "" {
+		return
+	}
+
+	renewal, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		klog.V(2).Infof("could not parse renewal time of certificate %s/%s: %v", cert.GetNamespace(), cert.GetName(), err)
+		return
+	}
+
+	if time.Until(renewal) > p.window {
+		return
+	}
+
+	// Renewals are warmed once; a failed renewal is warmed again when it is rescheduled.
+	if warmed, ok := p.warmed.Get(string(cert.GetUID())); ok && warmed == value {
+		return
+	}
+	p.warmed.Add(string(cert.GetUID()), value)
+
+	p.warm(cert, renewal.Add(DefaultPrewarmTTL))
+}
+
+// Finds the zone of the challenge record of each DNS name of the certificate, warming
+// the domain list of each account the challenges will be solved with.
+func (p *certificatePrewarmer) warm(cert *unstructured.Unstructured, expires time.Time) {
+	names, _, _ := unstructured.NestedStringSlice(cert.Object, "spec", "dnsNames")
+	if common, _, _ := unstructured.NestedString(cert.Object, "spec", "commonName"); common != "" {
+		names = append(names, common)
+	}
+
+	config := p.issuerConfig(cert)
+	warmed := make(map[string]bool)
+	for _, name := range names {
+		ch := &v1alpha1.ChallengeRequest{
+			ResolvedFQDN:      AutomationLabel + "." + strings.TrimPrefix(name, "*.") + ".",
+			ResourceNamespace: cert.GetNamespace(),
+			Config:            config,
+		}
+
+		linode, cfg, err := p.solver.linodeClient(ch)
+		if err != nil {
+			klog.V(2).Infof("could not pre-warm %s for certificate %s/%s: %v", name, cert.GetNamespace(), cert.GetName(), err)
+			continue
+		}
+
+		// Challenges with a domainID do not look up their zone.
+		if cfg.DomainID != 0 {
+			continue
+		}
+
+		if !warmed[linode.account] {
+			if err = linode.warmDomains(expires); err != nil {
+				klog.Warningf("could not pre-warm the linode domains for certificate %s/%s: %v", cert.GetNamespace(), cert.GetName(), err)
+				return
+			}
+			warmed[linode.account] = true
+		}
+
+		var zone *linodego.Domain
+		if zone, _, err = linode.FindCandidateZone(ch.ResolvedFQDN, ""); err != nil {
+			klog.V(2).Infof("could not pre-warm %s for certificate %s/%s: %v", name, cert.GetNamespace(), cert.GetName(), err)
+			continue
+		}
+		klog.V(2).Infof("pre-warmed zone %s for %s before the renewal of certificate %s/%s", zone.Domain, name, cert.GetNamespace(), cert.GetName())
+	}
+}
+
+// Returns the config of the first solver of the certificate's issuer that uses this
+// webhook, or nil if the issuer cannot be read or does not use the webhook, in which
+// case the zone routes and default credentials are used.
+func (p *certificatePrewarmer) issuerConfig(cert *unstructured.Unstructured) *extapi.JSON {
+	kind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind")
+	name, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "name")
+	if name == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), annotateTimeout)
+	defer cancel()
+
+	var (
+		issuer *unstructured.Unstructured
+		err    error
+	)
+
+	if kind == "ClusterIssuer" {
+		issuer, err = p.dynamic.Resource(clusterIssuerResource).Get(ctx, name, k8smetav1.GetOptions{})
+	} else {
+		issuer, err = p.dynamic.Resource(issuerResource).Namespace(cert.GetNamespace()).Get(ctx, name, k8smetav1.GetOptions{})
+	}
+
+	if err != nil {
+		klog.V(2).Infof("could not read issuer %s of certificate %s/%s: %v", name, cert.GetNamespace(), cert.GetName(), err)
+		return nil
+	}
+
+	solvers, _, _ := unstructured.NestedSlice(issuer.Object, "spec", "acme", "solvers")
+	for _, solver := range solvers {
+		spec, ok := solver.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if solverName, _, _ := unstructured.NestedString(spec, "dns01", "webhook", "solverName"); solverName != p.solver.Name() {
+			continue
+		}
+
+		config, ok, _ := unstructured.NestedFieldNoCopy(spec, "dns01", "webhook", "config")
+		if !ok {
+			return nil
+		}
+
+		raw, err := json.Marshal(config)
+		if err != nil {
+			return nil
+		}
+		return &extapi.JSON{Raw: raw}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package acme
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestCertificatePrewarmer(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.domains = newDomainCache("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "domainCache"})
+	defer caches.Delete(cacheKey{solver: "linode", name: "prewarmedCertificates"})
+
+	fake.AddDomain("example.com")
+
+	issuer := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "Issuer",
+		"metadata":   map[string]any{"name": "letsencrypt", "namespace": "default"},
+		"spec": map[string]any{"acme": map[string]any{"solvers": []any{
+			map[string]any{"http01": map[string]any{}},
+			map[string]any{"dns01": map[string]any{"webhook": map[string]any{
+				"groupName":  "acme.example.com",
+				"solverName": "linode",
+				"config":     map[string]any{"apiKeySecretRef": map[string]any{"name": "linode-credentials", "key": "token"}},
+			}}},
+		}}},
+	}}
+
+	scheme := runtime.NewScheme()
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, map[schema.GroupVersionResource]string{
+		certificateResource:   "CertificateList",
+		issuerResource:        "IssuerList",
+		clusterIssuerResource: "ClusterIssuerList",
+	}, issuer)
+
+	prewarmer := newCertificatePrewarmer(solver, dyn, time.Hour)
+	certificate := func(uid string, renewal time.Time) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "cert-manager.io/v1",
+			"kind":       "Certificate",
+			"metadata":   map[string]any{"name": "www", "namespace": "default", "uid": uid},
+			"spec": map[string]any{
+				"dnsNames":  []any{"www.example.com", "*.example.com"},
+				"issuerRef": map[string]any{"name": "letsencrypt"},
+			},
+			"status": map[string]any{"renewalTime": renewal.UTC().Format(time.RFC3339)},
+		}}
+	}
+
+	if config := prewarmer.issuerConfig(certificate("1", time.Now())); config == nil || string(config.Raw) != `{"apiKeySecretRef":{"key":"token","name":"linode-credentials"}}` {
+		t.Fatalf("unexpected issuer config %v", config)
+	}
+
+	// Certificates that are not due for renewal are not warmed.
+	prewarmer.observe(certificate("1", time.Now().Add(48*time.Hour)))
+	if calls := fake.Calls("GET /v4/domains"); calls != 0 {
+		t.Fatalf("expected no domain lookups, got %d", calls)
+	}
+
+	// Certificates within the renewal window are warmed once per renewal.
+	cert := certificate("2", time.Now().Add(time.Minute))
+	prewarmer.observe(cert)
+	prewarmer.observe(cert)
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Fatalf("expected one domain lookup for the renewal, got %d", calls)
+	}
+
+	// The renewal finds its zone without listing the domains again.
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.www.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected the pre-warmed domains to be used, got %d domain lookups", calls)
+	}
+
+	// Expired domain lists are not used.
+	solver.domains.accounts.Add(accountKey("linodetest"), warmDomains{expires: time.Now().Add(-time.Second)})
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 2 {
+		t.Errorf("expected expired domains to be listed again, got %d domain lookups", calls)
+	}
+}
@@ -0,0 +1,59 @@
+package acme
+
+import (
+	"context"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// The authoritative nameservers of the Linode DNS Manager that are queried by the
+// authoritative propagation check.
+var LinodeNameservers = []string{
+	"ns1.linode.com:53",
+	"ns2.linode.com:53",
+	"ns3.linode.com:53",
+	"ns4.linode.com:53",
+	"ns5.linode.com:53",
+}
+
+// How often the nameservers are queried while waiting for propagation.
+var PropagationInterval = 2 * time.Second
+
+// WaitForPropagation polls the Linode nameservers until all of them serve the TXT
+// record with the value, returning ErrNotPropagated if the timeout elapses first.
+func WaitForPropagation(fqdn, value string, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return waitForPropagation(ctx, fqdn, value)
+}
+
+// Polls the Linode nameservers until the record has propagated or the context is done.
+func waitForPropagation(ctx context.Context, fqdn, value string) error {
+	return linodeSelfCheck().Wait(ctx, fqdn, value)
+}
+
+// CheckPropagation queries each of the Linode nameservers for the TXT record and
+// returns ErrNotPropagated if any of them do not serve the value.
+func CheckPropagation(ctx context.Context, fqdn, value string) error {
+	return linodeSelfCheck().Check(ctx, fqdn, value)
+}
+
+// Returns a self check that queries the Linode nameservers directly since they are
+// authoritative for every zone in the Linode DNS Manager.
+func linodeSelfCheck() *SelfCheck {
+	return &SelfCheck{Nameservers: LinodeNameservers, Interval: PropagationInterval, Retries: 1}
+}
+
+func hasTXT(reply *dns.Msg, value string) bool {
+	for _, rr := range reply.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			for _, s := range txt.Txt {
+				if s == value {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package acme
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestPropagationCheck(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	fake.AddDomain("example.com")
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	nameservers, interval := LinodeNameservers, PropagationInterval
+	LinodeNameservers, PropagationInterval = []string{dns.Addr()}, 10*time.Millisecond
+	defer func() { LinodeNameservers, PropagationInterval = nameservers, interval }()
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+		Config:            &extapi.JSON{Raw: []byte(`{"propagationCheck": "authoritative", "timeoutSeconds": 1}`)},
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("expected record to propagate: %v", err)
+	}
+
+	if err := WaitForPropagation("_acme-challenge.example.com.", "key2", 50*time.Millisecond); !errors.Is(err, ErrNotPropagated) {
+		t.Errorf("expected ErrNotPropagated got %v", err)
+	}
+
+	// The nameservers are queried for the punycode form of internationalized names.
+	fake.AddDomain("xn--bcher-kva.example")
+	ch.ResolvedFQDN, ch.ResolvedZone = "_acme-challenge.bücher.example.", "bücher.example."
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("expected the record of the IDN zone to propagate: %v", err)
+	}
+}
+
+func TestPropagationWait(t *testing.T) {
+	expected := []string{"ns1.linode.com:53", "ns2.linode.com:53", "ns3.linode.com:53", "ns4.linode.com:53", "ns5.linode.com:53"}
+	if !slices.Equal(LinodeNameservers, expected) {
+		t.Fatalf("expected the linode nameservers %v, got %v", expected, LinodeNameservers)
+	}
+
+	solver, fake := newTestSolver(t)
+	fake.AddDomain("example.com")
+
+	// The second nameserver has not published the record yet: it serves a copy of the
+	// zone that the record is added to later.
+	lagging := linodetest.New()
+	defer lagging.Close()
+	zone := lagging.AddDomain("example.com")
+
+	var addrs []string
+	for _, backend := range []*linodetest.Server{fake, lagging} {
+		dns, err := linodetest.NewDNSServer(backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dns.Close()
+		addrs = append(addrs, dns.Addr())
+	}
+
+	nameservers, interval := LinodeNameservers, PropagationInterval
+	LinodeNameservers, PropagationInterval = addrs, 10*time.Millisecond
+	defer func() { LinodeNameservers, PropagationInterval = nameservers, interval }()
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+		Config:            &extapi.JSON{Raw: []byte(`{"propagationCheck": "authoritative", "timeoutSeconds": 5}`)},
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- solver.Present(ch) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected Present to wait for every nameserver, returned %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	lagging.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected the record to propagate: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Present did not return once the record propagated")
+	}
+}
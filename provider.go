@@ -0,0 +1,151 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// DNSProvider presents and cleans up DNS01 challenge records with a DNS service other
+// than Linode. A DNSProvider can be configured as a fallback so that estates that are
+// migrating between providers can solve challenges for zones that are not (or no
+// longer) hosted in Linode, or while the Linode API is unavailable.
+type DNSProvider interface {
+	Name() string
+	Present(ch *v1alpha1.ChallengeRequest) error
+	CleanUp(ch *v1alpha1.ChallengeRequest) error
+}
+
+// Returns true if the error from Linode means that the challenge should be delegated
+// to the fallback provider: either the zone is not hosted in the Linode account (or
+// only as a secondary zone) or the Linode API is unavailable. Errors such as invalid
+// credentials are not delegated since they must be fixed by the user.
+func shouldFallback(err error) bool {
+	if errors.Is(err, ErrNoZone) || errors.Is(err, ErrZoneDelegated) || errors.Is(err, ErrSecondaryZone) {
+		return true
+	}
+
+	return errors.Is(err, ErrLinodeUnavailable)
+}
+
+// Creates the configured fallback provider or returns nil if none is configured.
+func (s *LinodeDNSProviderSolver) newFallbackProvider() (DNSProvider, error) {
+	switch strings.ToLower(s.fallbackProvider) {
+	case "":
+		return nil, nil
+	case "rfc2136":
+		provider := &RFC2136Provider{
+			Nameserver:    s.rfc2136.Nameserver,
+			TSIGKeyName:   s.rfc2136.TSIGKeyName,
+			TSIGAlgorithm: s.rfc2136.TSIGAlgorithm,
+		}
+
+		if provider.Nameserver == "" {
+			return nil, errors.New("the rfc2136 fallback provider requires --rfc2136-nameserver")
+		}
+
+		if s.rfc2136.TSIGSecretFile != "" {
+			data, err := os.ReadFile(s.rfc2136.TSIGSecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not read rfc2136 tsig secret file: %w", err)
+			}
+			provider.TSIGSecret = strings.TrimSpace(string(data))
+		}
+
+		if (provider.TSIGKeyName == "") != (provider.TSIGSecret == "") {
+			return nil, errors.New("both --rfc2136-tsig-key-name and --rfc2136-tsig-secret-file are required to sign rfc2136 updates")
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unknown fallback provider %q", s.fallbackProvider)
+	}
+}
+
+//===========================================================================
+// RFC2136 Provider
+//===========================================================================
+
+const DefaultRFC2136Timeout = 10 * time.Second
+
+// RFC2136Provider presents challenge records using RFC2136 dynamic DNS updates to an
+// authoritative nameserver (e.g. BIND or PowerDNS), optionally signed with TSIG.
+type RFC2136Provider struct {
+	Nameserver    string
+	TSIGKeyName   string
+	TSIGSecret    string
+	TSIGAlgorithm string
+	Timeout       time.Duration
+}
+
+// RFC2136 options are configured by command line flags.
+type rfc2136Options struct {
+	Nameserver     string
+	TSIGKeyName    string
+	TSIGAlgorithm  string
+	TSIGSecretFile string
+}
+
+var _ DNSProvider = (*RFC2136Provider)(nil)
+
+func (p *RFC2136Provider) Name() string {
+	return "rfc2136"
+}
+
+// Present adds the TXT record for the challenge; adding a record that already exists
+// is a no-op for the nameserver so Present can be called multiple times.
+func (p *RFC2136Provider) Present(ch *v1alpha1.ChallengeRequest) error {
+	return p.update(ch, false)
+}
+
+// CleanUp removes only the TXT record with the challenge key.
+func (p *RFC2136Provider) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	return p.update(ch, true)
+}
+
+func (p *RFC2136Provider) update(ch *v1alpha1.ChallengeRequest, remove bool) (err error) {
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(ch.ResolvedFQDN), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: DefaultTTL},
+		Txt: []string{ch.Key},
+	}
+
+	msg := new(dns.Msg)
+	msg.SetUpdate(dns.Fqdn(ch.ResolvedZone))
+	if remove {
+		msg.Remove([]dns.RR{rr})
+	} else {
+		msg.Insert([]dns.RR{rr})
+	}
+
+	client := &dns.Client{Net: "tcp", Timeout: p.Timeout}
+	if client.Timeout == 0 {
+		client.Timeout = DefaultRFC2136Timeout
+	}
+
+	if p.TSIGKeyName != "" {
+		algorithm := dns.HmacSHA256
+		if p.TSIGAlgorithm != "" {
+			algorithm = dns.Fqdn(strings.ToLower(p.TSIGAlgorithm))
+		}
+
+		keyName := dns.Fqdn(p.TSIGKeyName)
+		client.TsigSecret = map[string]string{keyName: p.TSIGSecret}
+		msg.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+	}
+
+	var reply *dns.Msg
+	if reply, _, err = client.Exchange(msg, p.Nameserver); err != nil {
+		klog.Errorf("rfc2136 update of TXT record %s on %s failed: %v", ch.ResolvedFQDN, p.Nameserver, err)
+		return err
+	}
+
+	if reply.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("rfc2136 update of TXT record %s was rejected by %s: %s", ch.ResolvedFQDN, p.Nameserver, dns.RcodeToString[reply.Rcode])
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package acme
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/miekg/dns"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestFallbackProvider(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	fallback := &recordingProvider{}
+	solver.fallback = fallback
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.org.",
+		ResolvedZone:      "example.org.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	// Zones that are not hosted in Linode are delegated to the fallback.
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	// Challenges are delegated when the Linode API is unavailable.
+	zone := fake.AddDomain("example.com")
+	fake.Fail("GET /v4/domains", linodetest.Fault{Status: http.StatusInternalServerError})
+	ch.ResolvedFQDN, ch.ResolvedZone = "_acme-challenge.example.com.", "example.com."
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if fallback.presented != 2 || fallback.cleaned != 1 {
+		t.Errorf("expected 2 presents and 1 cleanup with the fallback, got %d and %d", fallback.presented, fallback.cleaned)
+	}
+
+	// Zones hosted in Linode are not delegated.
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	// Errors that the user must fix are not delegated (unauthorized requests are retried once).
+	fake.Fail("GET /v4/domains", linodetest.Fault{Status: http.StatusUnauthorized}, linodetest.Fault{Status: http.StatusUnauthorized})
+	if err := solver.Present(ch); err == nil {
+		t.Error("expected unauthorized error to be returned")
+	}
+
+	if fallback.presented != 2 {
+		t.Errorf("expected the fallback not to be used, got %d presents", fallback.presented)
+	}
+}
+
+func TestRFC2136Provider(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		updates []*dns.Msg
+	)
+
+	sock, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates are rejected as not implemented by the server unless explicitly accepted.
+	accept := func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept }
+	srv := &dns.Server{Listener: sock, MsgAcceptFunc: accept, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		updates = append(updates, r)
+		mu.Unlock()
+
+		reply := new(dns.Msg)
+		reply.SetReply(r)
+		if r.Question[0].Name != "example.com." {
+			reply.Rcode = dns.RcodeNotAuth
+		}
+		w.WriteMsg(reply)
+	})}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	provider := &RFC2136Provider{Nameserver: sock.Addr().String()}
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", Key: "key1"}
+
+	if err := provider.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if err := provider.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	mu.Lock()
+	if len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got %d", len(updates))
+	}
+
+	if rr := updates[0].Ns[0]; rr.Header().Class != dns.ClassINET || rr.(*dns.TXT).Txt[0] != "key1" {
+		t.Errorf("expected insert of key1, got %s", rr)
+	}
+
+	// Only the record with the key is removed, not the entire RRset.
+	if rr := updates[1].Ns[0]; rr.Header().Class != dns.ClassNONE || rr.(*dns.TXT).Txt[0] != "key1" {
+		t.Errorf("expected removal of key1, got %s", rr)
+	}
+	mu.Unlock()
+
+	ch.ResolvedZone = "example.org."
+	if err := provider.Present(ch); err == nil {
+		t.Error("expected rejected update to return an error")
+	}
+}
+
+type recordingProvider struct {
+	presented int
+	cleaned   int
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func (p *recordingProvider) Present(*v1alpha1.ChallengeRequest) error {
+	p.presented++
+	return nil
+}
+
+func (p *recordingProvider) CleanUp(*v1alpha1.ChallengeRequest) error {
+	p.cleaned++
+	return nil
+}
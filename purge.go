@@ -0,0 +1,146 @@
+package acme
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+const DefaultPurgeMinAge = 24 * time.Hour
+
+// The name prefix of the challenge records deleted by DeleteStaleChallengeRecords.
+const challengePrefix = "_acme-challenge"
+
+// The purge policy detects stale challenge records left in the zones of tracked
+// records by other ACME tooling that was previously used on the same zones (e.g.
+// certbot or another webhook), easing migrations to this webhook. Records are foreign
+// if they are TXT records whose name within the zone matches one of the patterns and
+// that are not tracked by this webhook. Only foreign records that are older than the
+// minimum age are reported, and they are only deleted if deletion is enabled, so that
+// records of tooling that is still in use are not removed while it is solving them.
+type purgePolicy struct {
+	patterns []string
+	minAge   time.Duration
+	delete   bool
+}
+
+// Returns nil if there are no patterns, disabling the purge, or an error if any of the
+// patterns is malformed.
+func newPurgePolicy(patterns []string, minAge time.Duration, delete bool) (*purgePolicy, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: invalid purge pattern %q: %w", ErrInvalidConfig, pattern, err)
+		}
+	}
+	return &purgePolicy{patterns: patterns, minAge: minAge, delete: delete}, nil
+}
+
+// Reports whether the record name within the zone matches one of the patterns.
+func (p *purgePolicy) matches(name string) bool {
+	for _, pattern := range p.patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Finds the foreign challenge records in each zone with a tracked record, deleting
+// them if the policy allows. Records that are tracked or hold the key of a tracked
+// challenge are never foreign, nor are records whose age is unknown.
+func (s *LinodeDNSProviderSolver) purgeForeign(tracked []*trackedRecord) (foreign, purged, failures uint64) {
+	failures = s.eachTrackedZone(tracked, "purge foreign records", func(linode *Linode, zoneID int, recs []*trackedRecord) (err error) {
+		ids := make(map[int]bool, len(recs))
+		keys := make(map[string]bool, len(recs))
+		for _, rec := range recs {
+			ids[rec.recordID] = true
+			keys[rec.challenge.Key] = true
+		}
+
+		unlock := lockZone(zoneID)
+		defer unlock()
+
+		var records []linodego.DomainRecord
+		if records, err = linode.listZoneRecords(zoneID); err != nil {
+			return err
+		}
+
+		for i := range records {
+			record := &records[i]
+			if record.Type != linodego.RecordTypeTXT || ids[record.ID] || keys[record.Target] || !s.purge.matches(record.Name) {
+				continue
+			}
+
+			age, ok := RecordAge(record)
+			if !ok || age < s.purge.minAge {
+				continue
+			}
+
+			foreign++
+			if !s.purge.delete {
+				klog.Warningf("reconcile: foreign challenge record %s (ID %d) in zone ID %d is %s old; enable --purge-foreign-records to delete it", record.Name, record.ID, zoneID, age.Round(time.Second))
+				continue
+			}
+
+			klog.Infof("reconcile: purging foreign challenge record %s (ID %d) in zone ID %d that is %s old", record.Name, record.ID, zoneID, age.Round(time.Second))
+			if err = linode.DeleteRecord(zoneID, record.ID); err != nil {
+				if linodego.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	return foreign, purged, failures
+}
+
+// DeleteStaleChallengeRecords deletes the TXT records in the Linode Zone whose name
+// starts with _acme-challenge and that were last created or updated more than
+// olderThan ago, e.g. records orphaned by a webhook crash or a failed CleanUp. Records
+// whose age is unknown are kept. The threshold must be positive so that the records
+// of in-flight challenges are not deleted; it should be longer than any challenge can
+// take to be solved. The deleted records are returned, along with the records deleted
+// before an error if one occurs.
+func (l *Linode) DeleteStaleChallengeRecords(zoneID int, olderThan time.Duration) (deleted []linodego.DomainRecord, err error) {
+	if olderThan <= 0 {
+		return nil, fmt.Errorf("%w: the age of stale challenge records must be positive", ErrInvalidConfig)
+	}
+
+	unlock := lockZone(zoneID)
+	defer unlock()
+
+	var records []linodego.DomainRecord
+	if records, err = l.listZoneRecords(zoneID); err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Type != linodego.RecordTypeTXT || !strings.HasPrefix(strings.ToLower(record.Name), challengePrefix) {
+			continue
+		}
+
+		if age, ok := RecordAge(&record); !ok || age < olderThan {
+			continue
+		}
+
+		klog.Infof("deleting stale challenge record %s (ID %d) in zone ID %d", record.Name, record.ID, zoneID)
+		if err = l.DeleteRecord(zoneID, record.ID); err != nil {
+			if linodego.IsNotFound(err) {
+				continue
+			}
+			return deleted, err
+		}
+		deleted = append(deleted, record)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,131 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+)
+
+func TestPurgeForeign(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.tracker = newRecordTracker("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "trackedRecords"})
+
+	zone := fake.AddDomain("example.com")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	// Leftovers of other tooling, e.g. certbot, alongside records that do not match.
+	for _, name := range []string{"_acme-challenge.www", "_acme-challenge.api", "_dmarc"} {
+		fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: name, Target: "leftover"})
+	}
+
+	// Records that are too young are neither reported nor deleted.
+	var err error
+	if solver.purge, err = newPurgePolicy([]string{"_acme-challenge*"}, time.Hour, true); err != nil {
+		t.Fatal(err)
+	}
+
+	solver.reconcile()
+	if stats := solver.tracker.Stats(); stats.Foreign != 0 || stats.Purged != 0 {
+		t.Errorf("unexpected reconcile stats %+v", stats)
+	}
+
+	// Old records are only reported unless deletion is enabled.
+	solver.purge.minAge = 0
+	solver.purge.delete = false
+	solver.reconcile()
+	if stats := solver.tracker.Stats(); stats.Foreign != 2 || stats.Purged != 0 {
+		t.Errorf("unexpected reconcile stats %+v", stats)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www", "leftover")
+
+	// With deletion enabled, foreign records are purged but the tracked record is kept.
+	solver.purge.delete = true
+	solver.reconcile()
+	if stats := solver.tracker.Stats(); stats.Foreign != 4 || stats.Purged != 2 || stats.Errors != 0 {
+		t.Errorf("unexpected reconcile stats %+v", stats)
+	}
+
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www")
+	assertTargets(t, fake, zone.ID, "_acme-challenge.api")
+	assertTargets(t, fake, zone.ID, "_dmarc", "leftover")
+}
+
+func TestPurgePolicy(t *testing.T) {
+	if policy, err := newPurgePolicy(nil, time.Hour, true); policy != nil || err != nil {
+		t.Errorf("expected the purge to be disabled without patterns, got %+v %v", policy, err)
+	}
+
+	if _, err := newPurgePolicy([]string{"_acme-challenge["}, time.Hour, true); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected malformed patterns to be rejected, got %v", err)
+	}
+
+	policy, _ := newPurgePolicy([]string{"_acme-challenge", "_acme-challenge.*"}, time.Hour, false)
+	for name, expected := range map[string]bool{
+		"_acme-challenge":        true,
+		"_acme-challenge.www":    true,
+		"_acme-challenge.a.b":    true,
+		"_acme-challengex":       false,
+		"www._acme-challenge":    false,
+		"_letsencrypt-challenge": false,
+	} {
+		if policy.matches(name) != expected {
+			t.Errorf("expected match of %q to be %t", name, expected)
+		}
+	}
+}
+
+func TestDeleteStaleChallengeRecords(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	old := time.Now().Add(-48 * time.Hour)
+	stale := []linodego.DomainRecord{
+		{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "orphan1", Created: &old},
+		{Type: linodego.RecordTypeTXT, Name: "_acme-challenge.www", Target: "orphan2", Created: &old},
+	}
+
+	// Records of in-flight challenges and other old records are kept.
+	kept := []linodego.DomainRecord{
+		{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "inflight"},
+		{Type: linodego.RecordTypeTXT, Name: "_dmarc", Target: "v=DMARC1", Created: &old},
+		{Type: linodego.RecordTypeCNAME, Name: "_acme-challenge.api", Target: "api.example.net", Created: &old},
+	}
+
+	for _, record := range append(stale, kept...) {
+		fake.AddRecord(zone.ID, record)
+	}
+
+	deleted, err := linode.DeleteStaleChallengeRecords(zone.ID, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("could not delete stale challenge records: %v", err)
+	}
+
+	if len(deleted) != 2 || deleted[0].Target != "orphan1" || deleted[1].Target != "orphan2" {
+		t.Errorf("expected the orphaned records to be deleted, got %+v", deleted)
+	}
+
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "inflight")
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www")
+	assertTargets(t, fake, zone.ID, "_dmarc", "v=DMARC1")
+	if n := len(fake.Records(zone.ID)); n != 3 {
+		t.Errorf("expected 3 records to be kept, found %d:\n%s", n, fake)
+	}
+
+	if _, err := linode.DeleteStaleChallengeRecords(zone.ID, 0); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected a threshold of 0 to be rejected, got %v", err)
+	}
+}
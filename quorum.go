@@ -0,0 +1,135 @@
+package acme
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+// The public recursive resolvers queried by the quorum propagation check if none are
+// configured. They answer from many regions, approximating the view of the record
+// that the ACME server's validation will have.
+var PublicResolvers = []string{
+	"8.8.8.8:53",
+	"1.1.1.1:53",
+	"9.9.9.9:53",
+	"208.67.222.222:53",
+}
+
+// ResolverQuorum checks that a quorum of recursive resolvers serve a TXT record. Unlike
+// the SelfCheck, which queries the authoritative nameservers, it verifies what clients
+// of the resolvers will see, including any negative caching of the record.
+type ResolverQuorum struct {
+	// Recursive resolvers (host:port) to query; defaults to PublicResolvers.
+	Resolvers []string
+
+	// Number of resolvers that must serve the record; if not positive then a majority
+	// of the resolvers is required.
+	Quorum int
+
+	// Number of times a failed query is retried; see SelfCheck.
+	Retries int
+
+	// How often the resolvers are queried by Wait; if not positive then
+	// PropagationInterval is used.
+	Interval time.Duration
+
+	// Timeout of each individual query; if not positive then DefaultQueryTimeout is used.
+	Timeout time.Duration
+}
+
+// Wait runs the check until a quorum of the resolvers serve the TXT record with the
+// value, returning the last error if the context is done first.
+func (q *ResolverQuorum) Wait(ctx context.Context, fqdn, value string) (err error) {
+	check := &SelfCheck{Interval: q.Interval}
+	ticker := time.NewTicker(check.interval())
+	defer ticker.Stop()
+
+	for {
+		if err = q.Check(ctx, fqdn, value); err == nil {
+			klog.V(2).Infof("TXT record %s is served by a quorum of resolvers", fqdn)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			klog.Warningf("TXT record %s is not served by a quorum of resolvers: %v", fqdn, err)
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check queries each of the resolvers for the TXT record concurrently and returns
+// ErrNotPropagated, listing the resolvers that did not serve the value, if fewer than
+// the quorum serve it.
+func (q *ResolverQuorum) Check(ctx context.Context, fqdn, value string) error {
+	resolvers := q.resolvers()
+	check := &SelfCheck{Retries: q.Retries, Timeout: q.Timeout}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(asciiName(fqdn)), dns.TypeTXT)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		served  int
+		missing []string
+	)
+
+	for _, resolver := range resolvers {
+		wg.Add(1)
+		go func(resolver string) {
+			defer wg.Done()
+			reply, err := check.exchange(ctx, msg.Copy(), resolver)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil && hasTXT(reply, value) {
+				served++
+				return
+			}
+			missing = append(missing, resolver)
+		}(resolver)
+	}
+	wg.Wait()
+
+	if quorum := q.quorum(len(resolvers)); served < quorum {
+		return fmt.Errorf("%w: %d of %d resolvers serve the record, %d required (not served by %s)", ErrNotPropagated, served, len(resolvers), quorum, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (q *ResolverQuorum) resolvers() []string {
+	if len(q.Resolvers) > 0 {
+		return q.Resolvers
+	}
+	return PublicResolvers
+}
+
+func (q *ResolverQuorum) quorum(resolvers int) int {
+	if q.Quorum > 0 {
+		return min(q.Quorum, resolvers)
+	}
+	return resolvers/2 + 1
+}
+
+// Waits until the record has propagated to the Linode nameservers and then until it is
+// served by a quorum of the resolvers. Resolvers are not queried before the record is
+// served by Linode so that they do not cache a negative answer for it.
+func (s *LinodeDNSProviderSolver) waitForQuorum(fqdn, value string, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err = waitForPropagation(ctx, fqdn, value); err != nil {
+		return err
+	}
+
+	quorum := &ResolverQuorum{Resolvers: s.quorumResolvers, Quorum: s.quorum, Interval: PropagationInterval}
+	return quorum.Wait(ctx, fqdn, value)
+}
@@ -0,0 +1,71 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestResolverQuorum(t *testing.T) {
+	// Two resolvers serve the record and one has not seen it yet.
+	fake, stale := linodetest.New(), linodetest.New()
+	defer fake.Close()
+	defer stale.Close()
+
+	zone := fake.AddDomain("example.com")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+	stale.AddDomain("example.com")
+
+	var resolvers []string
+	for _, api := range []*linodetest.Server{fake, fake, stale} {
+		dns, err := linodetest.NewDNSServer(api)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer dns.Close()
+		resolvers = append(resolvers, dns.Addr())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	quorum := &ResolverQuorum{Resolvers: resolvers, Retries: 1, Interval: 10 * time.Millisecond}
+	if err := quorum.Check(ctx, "_acme-challenge.example.com.", "key1"); err != nil {
+		t.Errorf("expected a majority of resolvers to serve the record: %v", err)
+	}
+
+	quorum.Quorum = 3
+	err := quorum.Check(ctx, "_acme-challenge.example.com.", "key1")
+	if !errors.Is(err, ErrNotPropagated) {
+		t.Fatalf("expected ErrNotPropagated got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "2 of 3 resolvers") || !strings.Contains(err.Error(), resolvers[2]) {
+		t.Errorf("expected the missing resolver to be reported: %v", err)
+	}
+}
+
+func TestQuorumFeatureGate(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	zone := fake.AddDomain("example.com")
+
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+		Config:            &extapi.JSON{Raw: []byte(`{"propagationCheck": "quorum"}`)},
+	}
+
+	if err := solver.Present(ch); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig without the feature gate, got %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+}
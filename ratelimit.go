@@ -0,0 +1,55 @@
+package acme
+
+import (
+	"math"
+
+	"github.com/linode/linodego"
+	"golang.org/x/time/rate"
+	"k8s.io/klog/v2"
+)
+
+// The client-side rate limiters of the Linode API tokens used by the webhook, keyed by
+// the account key of the token. A new client is created for every challenge, so the
+// limiters are shared by all of the concurrent Present and CleanUp calls with a token
+// to keep mass renewals within the per-token rate limit of the Linode API.
+var rateLimiters = newLRU[string, *rate.Limiter]("", "rateLimiters", DefaultCacheSize, nil)
+
+// SetRateLimit limits the Linode API requests made with the token of the client, by
+// this and every other client with the same token, to qps requests per second with
+// bursts of up to burst requests (the qps rounded up if not positive). Requests,
+// including retries, wait for the limiter until their context is done. The limiter of
+// the token is updated if the limit changes; a qps that is not positive does not limit
+// the requests of this client.
+func (l *Linode) SetRateLimit(qps float32, burst int) *Linode {
+	if qps <= 0 {
+		return l
+	}
+
+	if burst <= 0 {
+		burst = int(math.Ceil(float64(qps)))
+	}
+
+	limiter := accountLimiter(l.account, rate.Limit(qps), burst)
+	if l.limiter == nil {
+		l.client.OnBeforeRequest(func(req *linodego.Request) error {
+			return l.limiter.Wait(req.Context())
+		})
+	}
+
+	l.limiter = limiter
+	return l
+}
+
+// Returns the shared limiter of the account, creating it or updating its limits.
+func accountLimiter(account string, limit rate.Limit, burst int) *rate.Limiter {
+	limiter := rateLimiters.GetOrAdd(account, func() *rate.Limiter {
+		return rate.NewLimiter(limit, burst)
+	})
+
+	if limiter.Limit() != limit || limiter.Burst() != burst {
+		klog.V(2).Infof("changing the linode API rate limit of account %s to %v requests per second with bursts of %d", account, limit, burst)
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
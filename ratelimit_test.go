@@ -0,0 +1,40 @@
+package acme
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetRateLimit(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	linode.account = "ratelimit"
+	fake.AddDomain("example.com")
+	defer rateLimiters.Remove(linode.account)
+
+	// The first two requests use the burst and the third waits for the bucket.
+	linode.SetRateLimit(10, 2)
+	start := time.Now()
+	for range 3 {
+		if _, err := linode.FindZone("example.com"); err != nil {
+			t.Fatalf("could not find zone: %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected the third request to be rate limited, took %s", elapsed)
+	}
+
+	// Clients with the same token share the limiter, which is updated with the limits.
+	other, _ := newTestLinode(t)
+	other.account = linode.account
+	other.SetRateLimit(20, 4)
+
+	if linode.limiter != other.limiter || other.limiter.Burst() != 4 || other.limiter.Limit() != 20 {
+		t.Errorf("expected clients with the same token to share the updated limiter")
+	}
+
+	// A limit that is not positive does not limit the requests.
+	if unlimited, _ := newTestLinode(t); unlimited.SetRateLimit(0, 10).limiter != nil {
+		t.Error("expected no limiter without a qps")
+	}
+}
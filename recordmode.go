@@ -0,0 +1,145 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// RecordMode determines how Present and CleanUp manage challenge TXT records.
+type RecordMode string
+
+const (
+	// Present creates a record for the name or updates the existing record with the
+	// key, and CleanUp deletes the record for the name (the default).
+	RecordModeUpsert RecordMode = "upsert"
+
+	// Present never updates existing records: it creates a new record for its key and
+	// CleanUp deletes exactly that record. This is simpler to reason about in high
+	// concurrency environments at the cost of more records in the zone.
+	RecordModeCreate RecordMode = "create"
+
+	// Present creates a record per distinct key and reuses an existing record with the
+	// key, but never updates records with other keys, so that the challenges for both
+	// example.com and *.example.com can be served from the same name at once. CleanUp
+	// deletes the records with the key. Unlike create mode, no journal is required.
+	RecordModeAppend RecordMode = "append"
+)
+
+func (m RecordMode) validate() error {
+	switch m {
+	case RecordModeUpsert, RecordModeCreate, RecordModeAppend:
+		return nil
+	default:
+		return fmt.Errorf("unknown record mode %q (supported: %s, %s, %s)", m, RecordModeUpsert, RecordModeCreate, RecordModeAppend)
+	}
+}
+
+// Creates a new TXT record for the challenge in create mode. The record ID is
+// tracked so that repeated calls to Present for the same challenge return the record
+// that was already created rather than creating another.
+func (s *LinodeDNSProviderSolver) createTXT(linode LinodeAPI, zoneID int, entry string, ch *v1alpha1.ChallengeRequest) (record *linodego.DomainRecord, err error) {
+	unlock := lockZone(zoneID)
+	defer unlock()
+
+	if tracked, ok := s.tracker.lookup(ch); ok && tracked.zoneID == zoneID {
+		if record, err = linode.GetRecord(zoneID, tracked.recordID); err == nil && record.Target == ch.Key {
+			klog.V(2).Infof("TXT record %s (ID %d) already exists for challenge", entry, record.ID)
+			return record, nil
+		}
+
+		if err != nil && !errors.Is(err, ErrNoRecord) {
+			return nil, err
+		}
+	}
+
+	return linode.CreateRecord(zoneID, entry, ch.Key)
+}
+
+// Deletes exactly the TXT record created for the challenge in create mode. If the
+// record was not tracked (e.g. the webhook restarted since it was presented) then
+// only records with the challenge key are deleted. The challenge is untracked before
+// the zone lock is released so that the reconcile loop cannot recreate the record.
+func (s *LinodeDNSProviderSolver) deleteCreated(linode LinodeAPI, zoneID int, entry string, ch *v1alpha1.ChallengeRequest, force bool) (err error) {
+	unlock := lockZone(zoneID)
+	defer unlock()
+
+	defer func() {
+		if err == nil {
+			s.tracker.untrack(ch)
+		}
+	}()
+
+	if tracked, ok := s.tracker.lookup(ch); ok && tracked.zoneID == zoneID {
+		if err = s.checkAge(time.Since(tracked.presented), force); err != nil {
+			return err
+		}
+
+		if err = linode.DeleteRecord(zoneID, tracked.recordID); err != nil {
+			if linodego.IsNotFound(err) {
+				s.cleanups.observe(ch, false)
+				return nil
+			}
+			return err
+		}
+
+		s.cleanups.observe(ch, true)
+		return nil
+	}
+	return s.deleteTXT(linode, zoneID, entry, ch, force)
+}
+
+// Deletes the TXT records for the entry whose value is the challenge key, leaving the
+// records of other in-flight challenges for the same name (e.g. for a certificate for
+// both example.com and *.example.com) untouched. The caller must hold the zone lock.
+func (s *LinodeDNSProviderSolver) deleteTXT(linode LinodeAPI, zoneID int, entry string, ch *v1alpha1.ChallengeRequest, force bool) (err error) {
+	var records []linodego.DomainRecord
+	if records, err = linode.FindRecords(zoneID, entry); err != nil {
+		return err
+	}
+
+	for i := range records {
+		if records[i].Target == ch.Key {
+			if err = s.checkRecordAge(&records[i], force); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = linode.DeleteTXT(zoneID, entry, ch.Key); err != nil {
+		if errors.Is(err, ErrNoRecord) {
+			s.cleanups.observe(ch, false)
+			return nil
+		}
+		return err
+	}
+
+	s.cleanups.observe(ch, true)
+	return nil
+}
+
+// Returns ErrRecordTooYoung if the record was created or updated more recently than
+// the minimum record age, protecting against races where the CleanUp of a retried
+// challenge lands just after a fresh Present for the same name. The check is skipped
+// if the issuer forces the clean up or the timestamps of the record are unknown.
+func (s *LinodeDNSProviderSolver) checkRecordAge(record *linodego.DomainRecord, force bool) error {
+	age, ok := RecordAge(record)
+	if !ok {
+		return nil
+	}
+	return s.checkAge(age, force)
+}
+
+func (s *LinodeDNSProviderSolver) checkAge(age time.Duration, force bool) error {
+	minAge := s.currentMinRecordAge()
+	if force || minAge <= 0 || age >= minAge {
+		return nil
+	}
+
+	klog.Warningf("refusing to delete challenge record that is %s old (minimum age %s)", age.Round(time.Second), minAge)
+	return fmt.Errorf("%w: record is %s old, minimum age is %s", ErrRecordTooYoung, age.Round(time.Second), minAge)
+}
@@ -0,0 +1,195 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestCreateRecordMode(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.recordMode = RecordModeCreate
+	solver.tracker = newRecordTracker("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "trackedRecords"})
+
+	zone := fake.AddDomain("example.com")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "existing"})
+
+	challenge := func(key string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResolvedFQDN:      "_acme-challenge.example.com.",
+			ResolvedZone:      "example.com.",
+			ResourceNamespace: "default",
+			Key:               key,
+		}
+	}
+
+	// Existing records are never updated and repeated presents do not duplicate records.
+	for _, key := range []string{"key1", "key2", "key1"} {
+		if err := solver.Present(challenge(key)); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "existing", "key1", "key2")
+
+	if calls := fake.Calls("PUT /v4/domains/{domainID}/records/{recordID}"); calls != 0 {
+		t.Errorf("expected no updates in create mode, got %d", calls)
+	}
+
+	// CleanUp deletes exactly the record created for the challenge.
+	if err := solver.CleanUp(challenge("key1")); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "existing", "key2")
+
+	// Untracked challenges (e.g. after a restart) only delete records with their key.
+	solver.tracker.untrack(challenge("key2"))
+	if err := solver.CleanUp(challenge("key2")); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "existing")
+
+	// Cleaning up a challenge without a record is not an error.
+	if err := solver.CleanUp(challenge("key3")); err != nil {
+		t.Errorf("expected cleanup without a record to succeed: %v", err)
+	}
+
+	if stats := solver.cleanups.Stats(); stats.Deleted != 2 || stats.NoOp != 1 {
+		t.Errorf("expected 2 deleted and 1 no-op cleanups, got %+v", stats)
+	}
+
+	// Records evicted from a full journal are counted and cleaned up by their key.
+	solver.tracker.records.Resize(1)
+	for _, key := range []string{"key4", "key5"} {
+		if err := solver.Present(challenge(key)); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	if stats := solver.tracker.Stats(); stats.Tracked != 1 || stats.Evicted != 1 {
+		t.Errorf("expected 1 tracked and 1 evicted record, got %+v", stats)
+	}
+
+	if err := solver.CleanUp(challenge("key4")); err != nil {
+		t.Fatalf("could not clean up evicted challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "existing", "key5")
+}
+
+func TestUpsertCleanUpMatchesKey(t *testing.T) {
+	solver, fake := newTestSolver(t)
+
+	zone := fake.AddDomain("example.com")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key2"})
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+
+	challenge := func(key string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResolvedFQDN:      "_acme-challenge.example.com.",
+			ResolvedZone:      "example.com.",
+			ResourceNamespace: "default",
+			Key:               key,
+		}
+	}
+
+	// The record of another in-flight challenge for the same name is left untouched,
+	// even if it is the first record for the name.
+	if err := solver.CleanUp(challenge("key1")); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+
+	// Cleaning up a challenge whose record is gone does not delete other records.
+	if err := solver.CleanUp(challenge("key1")); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+
+	if stats := solver.cleanups.Stats(); stats.Deleted != 1 || stats.NoOp != 1 {
+		t.Errorf("expected 1 deleted and 1 no-op cleanup, got %+v", stats)
+	}
+}
+
+func TestMinRecordAge(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.minRecordAge = time.Hour
+
+	zone := fake.AddDomain("example.com")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	// A freshly presented record is not deleted.
+	if err := solver.CleanUp(ch); !errors.Is(err, ErrRecordTooYoung) {
+		t.Fatalf("expected ErrRecordTooYoung, got %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	// Records older than the minimum age are deleted.
+	solver.minRecordAge = time.Nanosecond
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+
+	// Forced clean ups skip the check.
+	solver.minRecordAge = time.Hour
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	ch.Config = &extapi.JSON{Raw: []byte(`{"forceCleanup": true}`)}
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not force clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+}
+
+func TestAppendRecordMode(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.recordMode = RecordModeAppend
+
+	zone := fake.AddDomain("example.com")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+
+	challenge := func(key string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResolvedFQDN:      "_acme-challenge.example.com.",
+			ResolvedZone:      "example.com.",
+			ResourceNamespace: "default",
+			Key:               key,
+		}
+	}
+
+	// The existing record is reused and a new record is created for the other key.
+	for _, key := range []string{"key1", "key2", "key2"} {
+		if err := solver.Present(challenge(key)); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1", "key2")
+
+	if calls := fake.Calls("PUT /v4/domains/{domainID}/records/{recordID}"); calls != 0 {
+		t.Errorf("expected no updates in append mode, got %d", calls)
+	}
+
+	if calls := fake.Calls("POST /v4/domains/{domainID}/records"); calls != 1 {
+		t.Errorf("expected 1 record to be created, got %d", calls)
+	}
+
+	if err := solver.CleanUp(challenge("key1")); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+}
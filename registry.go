@@ -0,0 +1,149 @@
+package acme
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// SolverRegistry declares additional solver instances that are registered by the
+// same webhook process alongside the default linode solver, so that an organization
+// can model many environments (e.g. separate Linode accounts or API endpoints for
+// staging and production) without maintaining separate deployments. The registry is
+// loaded from a YAML or JSON file of the form:
+//
+//	solvers:
+//	  - name: linode-staging
+//	    apiURL: https://api.staging.example.net
+//	    zones: [staging.example.com]
+//	    namespace: dns
+//	    apiKeySecretRef:
+//	      name: linode-staging
+//	      key: token
+//
+// Issuers select an instance with its name as the solverName of the webhook solver.
+// Each instance only solves challenges in its zones, with the credentials and tuning
+// of the instance unless the issuer overrides them; all other settings are shared
+// with the default solver.
+type SolverRegistry struct {
+	Solvers []SolverInstance `json:"solvers"`
+}
+
+// SolverInstance declares a named solver that solves challenges in the zones with the
+// credentials of the instance and, optionally, a different Linode API endpoint.
+type SolverInstance struct {
+	Name      string   `json:"name"`
+	Zones     []string `json:"zones"`
+	Namespace string   `json:"namespace,omitempty"`
+	LinodeDNSProviderConfig
+}
+
+// LoadSolverRegistry reads the solver instances from the specified file, validating
+// that every instance has a unique name, at least one zone, and credentials.
+func LoadSolverRegistry(path string) (_ *SolverRegistry, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("could not read solvers file: %w", err)
+	}
+
+	registry := &SolverRegistry{}
+	if err = yaml.UnmarshalStrict(data, registry); err != nil {
+		return nil, fmt.Errorf("could not parse solvers file %q: %w", path, err)
+	}
+
+	names := map[string]bool{(&LinodeDNSProviderSolver{}).Name(): true}
+	for i := range registry.Solvers {
+		instance := &registry.Solvers[i]
+		if errs := validation.IsDNS1123Label(instance.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("solver %d: %w: name %q: %s", i, ErrInvalidSolver, instance.Name, errs[0])
+		}
+
+		if names[instance.Name] {
+			return nil, fmt.Errorf("solver %q: %w: the name is already registered", instance.Name, ErrInvalidSolver)
+		}
+		names[instance.Name] = true
+
+		if len(instance.Zones) == 0 {
+			return nil, fmt.Errorf("solver %q: %w: at least one zone is required", instance.Name, ErrInvalidSolver)
+		}
+
+		for j, zone := range instance.Zones {
+			if instance.Zones[j] = normalizeZone(zone); instance.Zones[j] == "" {
+				return nil, fmt.Errorf("solver %q: %w", instance.Name, ErrInvalidRoute)
+			}
+		}
+
+		if instance.OAuth == nil && (instance.APIKeySecretRef.LocalObjectReference.Name == "" || instance.APIKeySecretRef.Key == "") {
+			return nil, fmt.Errorf("solver %q: %w", instance.Name, ErrInvalidSecretReference)
+		}
+
+		if err = instance.Validate(); err != nil {
+			return nil, fmt.Errorf("solver %q: %w", instance.Name, err)
+		}
+	}
+	return registry, nil
+}
+
+// NewSolver returns the solver for the instance. Its flags must be applied from the
+// flags of the default solver with ApplyFlags before it is initialized.
+func (i SolverInstance) NewSolver() *LinodeDNSProviderSolver {
+	routes := &Routes{Routes: make([]Route, 0, len(i.Zones))}
+	for _, zone := range i.Zones {
+		routes.Routes = append(routes.Routes, Route{Zone: zone, Namespace: i.Namespace, LinodeDNSProviderConfig: i.LinodeDNSProviderConfig})
+	}
+
+	instance := i
+	solver := &LinodeDNSProviderSolver{name: i.Name, apiURL: i.APIURL, instance: &instance}
+	solver.routes.Store(routes)
+	return solver
+}
+
+// ApplyFlags sets the flags of the solver to the values of the parsed flag set of the
+// default solver, so that solver instances share the settings of the webhook.
+func (s *LinodeDNSProviderSolver) ApplyFlags(parsed *pflag.FlagSet) (err error) {
+	fs := pflag.NewFlagSet(s.Name(), pflag.ContinueOnError)
+	s.AddFlags(fs)
+
+	parsed.Visit(func(flag *pflag.Flag) {
+		if err != nil || fs.Lookup(flag.Name) == nil {
+			return
+		}
+
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = fs.Lookup(flag.Name).Value.(pflag.SliceValue).Replace(slice.GetSlice())
+			return
+		}
+		err = fs.Set(flag.Name, flag.Value.String())
+	})
+	return err
+}
+
+// Returns true if the solver is not restricted to zones or the fqdn is in one of its
+// zones.
+func (s *LinodeDNSProviderSolver) allowsZone(fqdn string) bool {
+	if s.instance == nil {
+		return true
+	}
+	return s.routes.Load().Match(fqdn) != nil
+}
+
+// Logs the zones and endpoint of a solver instance once it is initialized.
+func (s *LinodeDNSProviderSolver) logInstance() {
+	if s.instance == nil {
+		return
+	}
+
+	if s.routesFile != "" {
+		klog.Infof("solver %s ignores --routes-file: its routes are its zones", s.Name())
+	}
+
+	apiURL := s.apiURL
+	if apiURL == "" {
+		apiURL = linodeURL()
+	}
+	klog.Infof("solver %s solves challenges in %v with the linode API at %s", s.Name(), s.instance.Zones, apiURL)
+}
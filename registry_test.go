@@ -0,0 +1,99 @@
+package acme
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/spf13/pflag"
+)
+
+func TestSolverRegistry(t *testing.T) {
+	base, fake := newTestSolver(t)
+	zone := fake.AddDomain("staging.example.com")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "solvers.yaml")
+	data := []byte(`
+solvers:
+  - name: linode-staging
+    apiURL: ` + fake.URL() + `
+    zones: [Staging.Example.com.]
+    apiKeySecretRef:
+      name: linode-credentials
+      key: token
+    ttlSeconds: 120
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := LoadSolverRegistry(path)
+	if err != nil {
+		t.Fatalf("could not load solver registry: %v", err)
+	}
+
+	if len(registry.Solvers) != 1 || !slices.Equal(registry.Solvers[0].Zones, []string{"staging.example.com"}) {
+		t.Fatalf("unexpected solver registry %+v", registry)
+	}
+
+	// Instances share the flags of the default solver.
+	flags := pflag.NewFlagSet("acme-linode", pflag.ContinueOnError)
+	base.AddFlags(flags)
+	if err := flags.Parse([]string{"--min-record-age=5m", "--purge-patterns=_acme-challenge*,_dnsauth*"}); err != nil {
+		t.Fatal(err)
+	}
+
+	solver := registry.Solvers[0].NewSolver()
+	if err := solver.ApplyFlags(flags); err != nil {
+		t.Fatalf("could not apply flags: %v", err)
+	}
+
+	if solver.Name() != "linode-staging" || solver.minRecordAge != 5*time.Minute || !slices.Equal(solver.purgePatterns, []string{"_acme-challenge*", "_dnsauth*"}) {
+		t.Errorf("unexpected solver %s with min record age %s and purge patterns %v", solver.Name(), solver.minRecordAge, solver.purgePatterns)
+	}
+
+	// The instance uses its own API endpoint, credentials, and tuning.
+	t.Setenv("LINODE_URL", "http://127.0.0.1:1")
+	solver.k8s, solver.ctx, solver.namespace = base.k8s, base.ctx, base.namespace
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.www.staging.example.com.", ResolvedZone: "staging.example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 1 || records[0].TTLSec != 120 {
+		t.Errorf("unexpected records %+v", records)
+	}
+
+	// Challenges outside of the zones of the instance are rejected.
+	ch = &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key2"}
+	if err := solver.Present(ch); !errors.Is(err, ErrZoneNotAllowed) {
+		t.Errorf("expected zone not allowed error, got %v", err)
+	}
+}
+
+func TestSolverRegistryInvalid(t *testing.T) {
+	testCases := map[string]string{
+		"name":      "solvers: [{name: Not_A_Label, zones: [example.com], apiKeySecretRef: {name: a, key: b}}]",
+		"duplicate": "solvers: [{name: a, zones: [example.com], apiKeySecretRef: {name: a, key: b}}, {name: a, zones: [example.org], apiKeySecretRef: {name: a, key: b}}]",
+		"default":   "solvers: [{name: linode, zones: [example.com], apiKeySecretRef: {name: a, key: b}}]",
+		"zones":     "solvers: [{name: a, apiKeySecretRef: {name: a, key: b}}]",
+		"secret":    "solvers: [{name: a, zones: [example.com]}]",
+	}
+
+	dir := t.TempDir()
+	for name, data := range testCases {
+		path := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadSolverRegistry(path); !errors.Is(err, ErrInvalidSolver) && !errors.Is(err, ErrInvalidSecretReference) {
+			t.Errorf("%s: expected an invalid solver error, got %v", name, err)
+		}
+	}
+}
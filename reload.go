@@ -0,0 +1,46 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// Reload re-reads the configuration files of the solver (the runtime settings, the
+// zone routes, and the authorization policy), e.g. when a standalone webhook receives
+// SIGHUP. Each file is reloaded independently: a file that cannot be loaded is
+// reported in the returned error and the previous configuration from it remains in
+// effect. Command line flags and environment variables are not reloaded.
+func (s *LinodeDNSProviderSolver) Reload() error {
+	if !s.initialized.Load() {
+		return ErrNotInitialized
+	}
+
+	var errs []error
+	if s.settingsFile != "" && s.features.Enabled(FeatureRuntimeSettings) {
+		if _, err := s.loadSettings(nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := s.loadRoutes(); err != nil {
+		errs = append(errs, fmt.Errorf("keeping previous zone routes: %w", err))
+	}
+
+	if err := s.loadPolicy(); err != nil {
+		errs = append(errs, fmt.Errorf("keeping previous authorization policy: %w", err))
+	}
+
+	// The Challenge resources can only be read if a policy needed them at startup.
+	if s.policy.Load().needsIssuers() && s.challenges == nil {
+		klog.Warning("the reloaded authorization policy refers to issuers, which will be empty until the webhook is restarted")
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	klog.Infof("reloaded the configuration files of solver %s", s.Name())
+	return nil
+}
@@ -0,0 +1,63 @@
+package acme
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	solver, _ := newTestSolver(t)
+	if err := solver.Reload(); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("expected not initialized error, got %v", err)
+	}
+
+	dir := t.TempDir()
+	solver.routesFile = filepath.Join(dir, "routes.yaml")
+	solver.policyFile = filepath.Join(dir, "policy.yaml")
+
+	write := func(path, data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(solver.routesFile, "routes:\n  - zone: example.com\n    apiKeySecretRef: {name: a, key: token}\n")
+	write(solver.policyFile, "rules:\n  - name: allow\n    expression: \"true\"\n")
+	if err := solver.loadRoutes(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := solver.loadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	solver.initialized.Store(true)
+
+	// Changed files are applied.
+	write(solver.routesFile, "routes:\n  - zone: example.org\n    apiKeySecretRef: {name: b, key: token}\n")
+	write(solver.policyFile, "rules:\n  - name: deny\n    expression: \"false\"\n")
+	if err := solver.Reload(); err != nil {
+		t.Fatalf("could not reload: %v", err)
+	}
+
+	if route := solver.routes.Load().Match("_acme-challenge.example.org."); route == nil || route.APIKeySecretRef.Name != "b" {
+		t.Errorf("expected the reloaded routes, got %+v", solver.routes.Load())
+	}
+
+	if policy := solver.policy.Load(); len(policy.Rules) != 1 || policy.Rules[0].Name != "deny" {
+		t.Errorf("expected the reloaded policy, got %+v", policy.Rules)
+	}
+
+	// Invalid files are reported and the previous configuration stays in effect.
+	write(solver.routesFile, "routes:\n  - zone: example.net\n")
+	write(solver.policyFile, "rules:\n  - name: invalid\n    expression: \"request.\"\n")
+	if err := solver.Reload(); !errors.Is(err, ErrInvalidSecretReference) || !errors.Is(err, ErrInvalidPolicy) {
+		t.Errorf("expected the invalid files to be reported, got %v", err)
+	}
+
+	if solver.routes.Load().Match("_acme-challenge.example.org.") == nil || solver.policy.Load().Rules[0].Name != "deny" {
+		t.Error("expected the previous configuration to remain in effect")
+	}
+}
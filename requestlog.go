@@ -0,0 +1,52 @@
+package acme
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+// Logs an access-log style record of a webhook request once it has been handled and
+// records it in the audit log if enabled. Every request is logged at the same level
+// regardless of its outcome so that the records form a complete log of the webhook
+// traffic; it should be deferred as:
+//
+//	defer s.logRequest("Present", ch, time.Now(), &err)
+func (s *LinodeDNSProviderSolver) logRequest(method string, ch *v1alpha1.ChallengeRequest, start time.Time, err *error) {
+	keysAndValues := []any{
+		"method", method,
+		"uid", ch.UID,
+		"namespace", ch.ResourceNamespace,
+		"fqdn", ch.ResolvedFQDN,
+		"duration", time.Since(start).Round(time.Millisecond),
+		"outcome", outcome(*err),
+	}
+
+	if *err != nil {
+		keysAndValues = append(keysAndValues, "error", (*err).Error())
+	}
+	klog.InfoS("webhook request", keysAndValues...)
+
+	s.audit.Record(AuditEvent{
+		Operation: strings.ToLower(method),
+		UID:       string(ch.UID),
+		Namespace: ch.ResourceNamespace,
+		FQDN:      ch.ResolvedFQDN,
+		Value:     ch.Key,
+	}, *err)
+}
+
+// Classifies the result of a request for the request log.
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrOverloaded):
+		return "shed"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,50 @@
+package acme
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"k8s.io/klog/v2"
+)
+
+func TestLogRequest(t *testing.T) {
+	var buf bytes.Buffer
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	fs.Set("logtostderr", "false")
+	klog.SetOutput(&buf)
+	defer func() {
+		fs.Set("logtostderr", "true")
+		klog.SetOutput(nil)
+	}()
+
+	ch := &v1alpha1.ChallengeRequest{UID: "1234", ResourceNamespace: "default", ResolvedFQDN: "_acme-challenge.example.com."}
+
+	solver := &LinodeDNSProviderSolver{}
+
+	var err error
+	solver.logRequest("Present", ch, time.Now(), &err)
+
+	err = errors.New("boom")
+	solver.logRequest("CleanUp", ch, time.Now(), &err)
+	klog.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 request log lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	for i, expected := range []string{
+		`"webhook request" method="Present" uid="1234" namespace="default" fqdn="_acme-challenge.example.com." duration="0s" outcome="success"`,
+		`"webhook request" method="CleanUp" uid="1234" namespace="default" fqdn="_acme-challenge.example.com." duration="0s" outcome="error" error="boom"`,
+	} {
+		if !strings.HasSuffix(lines[i], expected) {
+			t.Errorf("expected log line to end with %s, got %s", expected, lines[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package acme
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// Creates a linodego client that sends requests with the http client and retries
+// transient failures. In addition to the conditions retried by linodego (rate limits,
+// 503s, and request timeouts), bad gateways are retried, as are gateway timeouts and
+// connection resets for requests that can be safely repeated. The wait between
+// retries is a jittered exponential backoff unless the response has a Retry-After
+// header, which may be either a number of seconds or an HTTP date. Debug logs, if
+// enabled, are redacted, and every request is recorded in the Linode API metrics.
+func newLinodeClient(hc *http.Client) linodego.Client {
+	client := linodego.NewClient(instrumentClient(hc))
+	client.SetLogger(linodeLogger{})
+	client.SetUserAgent(UserAgent)
+	client.AddRetryCondition(transientRetryCondition)
+	client.SetRetryAfter(retryAfter)
+	return client
+}
+
+func transientRetryCondition(rep *resty.Response, err error) bool {
+	if rep == nil {
+		return false
+	}
+
+	if rep.StatusCode() == http.StatusBadGateway {
+		return true
+	}
+
+	// The request may have been applied before the gateway timed out or the connection
+	// was reset, so only requests that are idempotent are retried.
+	if rep.Request == nil || rep.Request.Method == http.MethodPost {
+		return false
+	}
+
+	if rep.StatusCode() == http.StatusGatewayTimeout {
+		return true
+	}
+	return err != nil && (errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+// Returns the wait requested by the Retry-After header of the response, or zero to use
+// the exponential backoff if there is none; resty limits the wait to the maximum.
+func retryAfter(_ *resty.Client, rep *resty.Response) (time.Duration, error) {
+	value := rep.Header().Get("Retry-After")
+	if value == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, nil
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), nil
+	}
+
+	klog.V(2).Infof("ignoring invalid Retry-After header %q from the linode API", value)
+	return 0, nil
+}
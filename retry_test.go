@@ -0,0 +1,94 @@
+package acme
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestTransientRetries(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	zone := fake.AddDomain("example.com")
+
+	// Bad gateways and gateway timeouts are retried for idempotent requests.
+	fake.Fail("GET /v4/domains",
+		linodetest.Fault{Status: http.StatusBadGateway},
+		linodetest.Fault{Status: http.StatusGatewayTimeout},
+	)
+
+	if _, err := linode.FindZone("example.com"); err != nil {
+		t.Fatalf("expected transient errors to be retried: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 3 {
+		t.Errorf("expected 3 requests, got %d", calls)
+	}
+
+	// The record may have been created before the gateway timed out.
+	fake.Fail("POST /v4/domains/{domainID}/records", linodetest.Fault{Status: http.StatusGatewayTimeout})
+	if _, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key1"); !errors.Is(err, ErrLinodeUnavailable) {
+		t.Errorf("expected the create not to be retried, got %v", err)
+	}
+
+	if calls := fake.Calls("POST /v4/domains/{domainID}/records"); calls != 1 {
+		t.Errorf("expected 1 create request, got %d", calls)
+	}
+}
+
+func TestConnectionResetRetries(t *testing.T) {
+	fake := linodetest.New()
+	t.Cleanup(fake.Close)
+	fake.AddDomain("example.com")
+
+	// The first request fails with a connection reset before reaching the server.
+	var resets atomic.Int32
+	hc := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if resets.Add(1) == 1 {
+			return nil, syscall.ECONNRESET
+		}
+		return fake.Client().Transport.RoundTrip(req)
+	})}
+
+	linode := NewLinodeWithHTTPClient("token", hc).SetRetryPolicy(&RetryPolicy{MinWait: time.Millisecond, MaxWait: 10 * time.Millisecond})
+	linode.SetBaseURL(fake.URL())
+
+	if _, err := linode.FindZone("example.com"); err != nil {
+		t.Fatalf("expected the connection reset to be retried: %v", err)
+	}
+
+	if n := resets.Load(); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	response := func(value string) *resty.Response {
+		rep := &resty.Response{RawResponse: &http.Response{Header: http.Header{}}}
+		if value != "" {
+			rep.RawResponse.Header.Set("Retry-After", value)
+		}
+		return rep
+	}
+
+	if wait, err := retryAfter(nil, response("7")); err != nil || wait != 7*time.Second {
+		t.Errorf("expected a 7s wait, got %s (%v)", wait, err)
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if wait, err := retryAfter(nil, response(date)); err != nil || wait <= 50*time.Second || wait > time.Minute {
+		t.Errorf("expected a wait of about a minute, got %s (%v)", wait, err)
+	}
+
+	// Missing and invalid headers use the exponential backoff.
+	for _, value := range []string{"", "soon"} {
+		if wait, err := retryAfter(nil, response(value)); err != nil || wait != 0 {
+			t.Errorf("expected no wait for %q, got %s (%v)", value, wait, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package acme
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Routes map zone suffixes to the Linode credentials used to solve challenges in
+// those zones, so that a single webhook can split challenges across several Linode
+// accounts by domain without duplicating the config on every issuer. Routes are
+// loaded from a YAML or JSON file of the form:
+//
+//	routes:
+//	  - zone: example.com
+//	    apiKeySecretRef:
+//	      name: linode-account-a
+//	      key: token
+//	  - zone: example.org
+//	    namespace: dns
+//	    apiKeySecretRef:
+//	      name: linode-account-b
+//	      key: token
+//
+// The route with the longest zone that the challenge FQDN is in is used unless the
+// issuer config specifies its own apiKeySecretRef. Route secrets are read from the
+// route namespace, which defaults to the namespace of the webhook.
+type Routes struct {
+	Routes []Route `json:"routes"`
+}
+
+// Route maps challenges in the zone and all of its subdomains to a solver config.
+type Route struct {
+	Zone      string `json:"zone"`
+	Namespace string `json:"namespace,omitempty"`
+	LinodeDNSProviderConfig
+}
+
+// LoadRoutes reads the routing table from the specified file, validating the routes
+// and sorting them so that the most specific zone is matched first.
+func LoadRoutes(path string) (_ *Routes, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("could not read routes file: %w", err)
+	}
+
+	routes := &Routes{}
+	if err = yaml.UnmarshalStrict(data, routes); err != nil {
+		return nil, fmt.Errorf("could not parse routes file %q: %w", path, err)
+	}
+
+	for i := range routes.Routes {
+		route := &routes.Routes[i]
+		if route.Zone = normalizeZone(route.Zone); route.Zone == "" {
+			return nil, fmt.Errorf("route %d: %w", i, ErrInvalidRoute)
+		}
+
+		if route.OAuth == nil && (route.APIKeySecretRef.LocalObjectReference.Name == "" || route.APIKeySecretRef.Key == "") {
+			return nil, fmt.Errorf("route for zone %q: %w", route.Zone, ErrInvalidSecretReference)
+		}
+
+		if err = route.Validate(); err != nil {
+			return nil, fmt.Errorf("route for zone %q: %w", route.Zone, err)
+		}
+	}
+
+	sort.SliceStable(routes.Routes, func(i, j int) bool {
+		return len(routes.Routes[i].Zone) > len(routes.Routes[j].Zone)
+	})
+	return routes, nil
+}
+
+// Match returns the most specific route for the FQDN or nil if no route matches; a
+// nil routing table never matches.
+func (r *Routes) Match(fqdn string) *Route {
+	if r == nil {
+		return nil
+	}
+
+	fqdn = normalizeZone(fqdn)
+	for i := range r.Routes {
+		if zone := r.Routes[i].Zone; fqdn == zone || strings.HasSuffix(fqdn, "."+zone) {
+			return &r.Routes[i]
+		}
+	}
+	return nil
+}
+
+// Returns the namespace the route's secret is read from.
+func (r *Route) SecretNamespace(defaultNamespace string) string {
+	if r.Namespace != "" {
+		return r.Namespace
+	}
+	return defaultNamespace
+}
+
+func normalizeZone(zone string) string {
+	return asciiName(strings.Trim(strings.TrimSpace(zone), "."))
+}
+
+// Loads the routes file if configured, logging the number of routes loaded.
+func (s *LinodeDNSProviderSolver) loadRoutes() (err error) {
+	if s.routesFile == "" || s.instance != nil {
+		return nil
+	}
+
+	var routes *Routes
+	if routes, err = LoadRoutes(s.routesFile); err != nil {
+		return err
+	}
+
+	for _, route := range routes.Routes {
+		if s.singleNamespace && route.Namespace != "" && route.Namespace != s.PodNamespace() {
+			return fmt.Errorf("route for zone %q: %w: namespace %q is not the webhook namespace", route.Zone, ErrSecretRefNotAllowed, route.Namespace)
+		}
+	}
+
+	s.routes.Store(routes)
+	klog.Infof("loaded %d zone routes from %s", len(routes.Routes), s.routesFile)
+	return nil
+}
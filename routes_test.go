@@ -0,0 +1,76 @@
+package acme_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	acme "go.rtnl.ai/acme-linode"
+)
+
+func TestLoadRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	data := []byte(`routes:
+  - zone: example.com
+    apiKeySecretRef: {name: account-a, key: token}
+  - zone: Dev.Example.com.
+    namespace: dns
+    apiKeySecretRef: {name: account-b, key: token}
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	routes, err := acme.LoadRoutes(path)
+	if err != nil {
+		t.Fatalf("could not load routes: %v", err)
+	}
+
+	tests := []struct {
+		fqdn   string
+		secret string
+	}{
+		{"_acme-challenge.example.com.", "account-a"},
+		{"_acme-challenge.www.example.com.", "account-a"},
+		{"_acme-challenge.dev.example.com.", "account-b"},
+		{"_acme-challenge.api.dev.example.com.", "account-b"},
+		{"_acme-challenge.notexample.com.", ""},
+		{"_acme-challenge.example.org.", ""},
+	}
+
+	for _, tc := range tests {
+		route := routes.Match(tc.fqdn)
+		switch {
+		case tc.secret == "" && route != nil:
+			t.Errorf("expected no route for %s, got %q", tc.fqdn, route.Zone)
+		case tc.secret != "" && route == nil:
+			t.Errorf("expected route for %s", tc.fqdn)
+		case route != nil && route.APIKeySecretRef.Name != tc.secret:
+			t.Errorf("expected %s to use secret %q, got %q", tc.fqdn, tc.secret, route.APIKeySecretRef.Name)
+		}
+	}
+
+	if ns := routes.Match("_acme-challenge.dev.example.com").SecretNamespace("cert-manager"); ns != "dns" {
+		t.Errorf("expected route namespace dns, got %q", ns)
+	}
+
+	if ns := routes.Match("_acme-challenge.example.com").SecretNamespace("cert-manager"); ns != "cert-manager" {
+		t.Errorf("expected default namespace cert-manager, got %q", ns)
+	}
+
+	// Routes without a zone or secret are rejected.
+	if err := os.WriteFile(path, []byte("routes:\n  - apiKeySecretRef: {name: a, key: b}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acme.LoadRoutes(path); !errors.Is(err, acme.ErrInvalidRoute) {
+		t.Errorf("expected ErrInvalidRoute, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("routes:\n  - zone: example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acme.LoadRoutes(path); !errors.Is(err, acme.ErrInvalidSecretReference) {
+		t.Errorf("expected ErrInvalidSecretReference, got %v", err)
+	}
+}
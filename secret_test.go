@@ -0,0 +1,100 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	k8sapiv1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSecretSelector(t *testing.T) {
+	secret := func(name, app string) *k8sapiv1.Secret {
+		return &k8sapiv1.Secret{
+			ObjectMeta: k8smetav1.ObjectMeta{Name: name, Namespace: "team", Labels: map[string]string{"app": app}},
+			Data:       map[string][]byte{"token": []byte(name)},
+		}
+	}
+
+	solver := &LinodeDNSProviderSolver{
+		k8s:       fake.NewClientset(secret("linode-abc123", "linode"), secret("other", "other"), secret("dup1", "dup"), secret("dup2", "dup")),
+		ctx:       context.Background(),
+		namespace: "default",
+	}
+
+	challenge := func(app string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResourceNamespace: "team",
+			Config:            &extapi.JSON{Raw: []byte(`{"apiKeySecretSelector": {"matchLabels": {"app": "` + app + `"}, "key": "token"}}`)},
+		}
+	}
+
+	linode, cfg, err := solver.linodeClient(challenge("linode"))
+	if err != nil || linode == nil {
+		t.Fatalf("could not select secret: %v", err)
+	}
+
+	if token, err := solver.getSecretBySelector(*cfg.APIKeySecretSelector, "team"); err != nil || token != "linode-abc123" {
+		t.Errorf("expected token linode-abc123 got %q: %v", token, err)
+	}
+
+	for _, app := range []string{"missing", "dup"} {
+		if _, _, err := solver.linodeClient(challenge(app)); !errors.Is(err, ErrSecretSelector) {
+			t.Errorf("expected ErrSecretSelector for %s got %v", app, err)
+		}
+	}
+
+	// The selector may not be combined with a secret reference or be empty.
+	for _, raw := range []string{
+		`{"apiKeySecretRef": {"name": "linode", "key": "token"}, "apiKeySecretSelector": {"matchLabels": {"app": "linode"}, "key": "token"}}`,
+		`{"apiKeySecretSelector": {"key": "token"}}`,
+		`{"apiKeySecretSelector": {"matchLabels": {"app": "linode"}}}`,
+	} {
+		if _, err := LoadConfig(&extapi.JSON{Raw: []byte(raw)}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %s got %v", raw, err)
+		}
+	}
+}
+
+func TestNamespaceCredentials(t *testing.T) {
+	secret := func(namespace, name string, annotations map[string]string) *k8sapiv1.Secret {
+		return &k8sapiv1.Secret{
+			ObjectMeta: k8smetav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+			Data:       map[string][]byte{"token": []byte(namespace + "/" + name), "custom": []byte("custom")},
+		}
+	}
+
+	solver := &LinodeDNSProviderSolver{
+		k8s: fake.NewClientset(
+			secret("default", "linode-credentials", nil),
+			secret("named", "linode-credentials", nil),
+			secret("annotated", "rotated-1", map[string]string{AnnotationCredentials: "custom"}),
+			secret("annotated", "unrelated", nil),
+		),
+		ctx:       context.Background(),
+		namespace: "default",
+	}
+
+	tests := []struct {
+		namespace string
+		enabled   bool
+		expected  string
+	}{
+		{"named", false, "default/linode-credentials"},
+		{"named", true, "named/linode-credentials"},
+		{"annotated", true, "custom"},
+		{"other", true, "default/linode-credentials"},
+	}
+
+	for _, tc := range tests {
+		solver.namespaceCredentials = tc.enabled
+		if token, err := solver.GetAPIKey(cmmeta.SecretKeySelector{}, tc.namespace); err != nil || token != tc.expected {
+			t.Errorf("expected token %q for namespace %q got %q: %v", tc.expected, tc.namespace, token, err)
+		}
+	}
+}
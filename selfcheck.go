@@ -0,0 +1,241 @@
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"k8s.io/klog/v2"
+)
+
+const (
+	DefaultSelfCheckRetries = 3
+	DefaultQueryTimeout     = 5 * time.Second
+)
+
+// The recursive resolvers used to discover the authoritative nameservers of a domain
+// if none are configured and /etc/resolv.conf cannot be read.
+var DefaultResolvers = []string{"8.8.8.8:53", "1.1.1.1:53"}
+
+// SelfCheck replicates the DNS01 pre-check performed by cert-manager before it asks the
+// ACME server to validate a challenge: the authoritative nameservers of the zone are
+// discovered with a recursive resolver, then each of them is queried directly (without
+// recursion) for the TXT record, retrying failed queries. Consumers that solve
+// challenges without cert-manager can use it to get the same correctness guarantees.
+type SelfCheck struct {
+	// Recursive resolvers (host:port) used to discover the zone and its nameservers;
+	// defaults to the nameservers in /etc/resolv.conf or DefaultResolvers.
+	Resolvers []string
+
+	// Authoritative nameservers (host:port) to query; if empty they are discovered
+	// from the NS records of the zone that contains the fqdn.
+	Nameservers []string
+
+	// Number of times a failed query is retried before the check fails; if not
+	// positive then DefaultSelfCheckRetries is used.
+	Retries int
+
+	// How often the nameservers are queried by Wait; if not positive then
+	// PropagationInterval is used.
+	Interval time.Duration
+
+	// Timeout of each individual query; if not positive then DefaultQueryTimeout is used.
+	Timeout time.Duration
+}
+
+// Wait runs the check until every authoritative nameserver serves the TXT record with
+// the value, returning the last error if the context is done first.
+func (c *SelfCheck) Wait(ctx context.Context, fqdn, value string) (err error) {
+	ticker := time.NewTicker(c.interval())
+	defer ticker.Stop()
+
+	for {
+		if err = c.Check(ctx, fqdn, value); err == nil {
+			klog.V(2).Infof("TXT record %s has propagated to the authoritative nameservers", fqdn)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			klog.Warningf("TXT record %s did not propagate: %v", fqdn, err)
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check queries each of the authoritative nameservers for the TXT record once and
+// returns ErrNotPropagated if any of them do not serve the value. Internationalized
+// names are queried in their ASCII form.
+func (c *SelfCheck) Check(ctx context.Context, fqdn, value string) (err error) {
+	fqdn = dns.Fqdn(asciiName(fqdn))
+
+	nameservers := c.Nameservers
+	if len(nameservers) == 0 {
+		if nameservers, err = c.AuthoritativeNameservers(ctx, fqdn); err != nil {
+			return err
+		}
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(fqdn, dns.TypeTXT)
+	msg.RecursionDesired = false
+
+	for _, ns := range nameservers {
+		reply, err := c.exchange(ctx, msg, ns)
+		if err != nil {
+			return fmt.Errorf("%w: could not query %s: %v", ErrNotPropagated, ns, err)
+		}
+
+		if !hasTXT(reply, value) {
+			return fmt.Errorf("%w: %s does not serve the record yet", ErrNotPropagated, ns)
+		}
+	}
+	return nil
+}
+
+// AuthoritativeNameservers returns the addresses (host:port) of the nameservers listed
+// in the NS records of the zone that contains the fqdn.
+func (c *SelfCheck) AuthoritativeNameservers(ctx context.Context, fqdn string) (nameservers []string, err error) {
+	var zone string
+	if zone, err = c.FindZone(ctx, fqdn); err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+	if hosts, err = c.ZoneNameservers(ctx, zone); err != nil {
+		return nil, err
+	}
+
+	for _, host := range hosts {
+		nameservers = append(nameservers, net.JoinHostPort(host, "53"))
+	}
+	return nameservers, nil
+}
+
+// ZoneNameservers returns the lower case host names, without a trailing dot, of the
+// nameservers published in the NS records of the zone by the recursive resolvers.
+func (c *SelfCheck) ZoneNameservers(ctx context.Context, zone string) (hosts []string, err error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(zone), dns.TypeNS)
+
+	var reply *dns.Msg
+	if reply, err = c.resolve(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	for _, rr := range reply.Answer {
+		if ns, ok := rr.(*dns.NS); ok {
+			hosts = append(hosts, strings.ToLower(strings.TrimSuffix(ns.Ns, ".")))
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%w for zone %q", ErrNoNameservers, zone)
+	}
+	return hosts, nil
+}
+
+// FindZone returns the zone (apex) that contains the fqdn by querying the recursive
+// resolvers for the SOA record of each parent domain in turn.
+func (c *SelfCheck) FindZone(ctx context.Context, fqdn string) (_ string, err error) {
+	fqdn = dns.Fqdn(fqdn)
+	for _, offset := range dns.Split(fqdn) {
+		domain := fqdn[offset:]
+
+		msg := new(dns.Msg)
+		msg.SetQuestion(domain, dns.TypeSOA)
+
+		var reply *dns.Msg
+		if reply, err = c.resolve(ctx, msg); err != nil {
+			return "", err
+		}
+
+		if reply.Rcode != dns.RcodeSuccess && reply.Rcode != dns.RcodeNameError {
+			continue
+		}
+
+		// The SOA is in the answer for the apex or in the authority section otherwise.
+		for _, rr := range append(reply.Answer, reply.Ns...) {
+			if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Hdr.Name, domain) {
+				return domain, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w for fqdn %q", ErrNoNameservers, fqdn)
+}
+
+// Sends the query to each of the recursive resolvers until one of them answers.
+func (c *SelfCheck) resolve(ctx context.Context, msg *dns.Msg) (reply *dns.Msg, err error) {
+	for _, resolver := range c.resolvers() {
+		if reply, err = c.exchange(ctx, msg, resolver); err == nil {
+			return reply, nil
+		}
+	}
+	return nil, err
+}
+
+// Sends the query to the server, retrying network errors and server failures, and
+// repeating the query over TCP if the UDP response was truncated.
+func (c *SelfCheck) exchange(ctx context.Context, msg *dns.Msg, server string) (reply *dns.Msg, err error) {
+	client := &dns.Client{Timeout: c.timeout()}
+	for attempt := 0; attempt <= c.retries(); attempt++ {
+		if reply, _, err = client.ExchangeContext(ctx, msg, server); err == nil && reply.Truncated {
+			tcp := &dns.Client{Net: "tcp", Timeout: c.timeout()}
+			reply, _, err = tcp.ExchangeContext(ctx, msg, server)
+		}
+
+		switch {
+		case err == nil && reply.Rcode == dns.RcodeServerFailure:
+			err = fmt.Errorf("%s returned %s", server, dns.RcodeToString[reply.Rcode])
+		case err == nil:
+			return reply, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (c *SelfCheck) resolvers() []string {
+	if len(c.Resolvers) > 0 {
+		return c.Resolvers
+	}
+
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(conf.Servers) == 0 {
+		return DefaultResolvers
+	}
+
+	resolvers := make([]string, 0, len(conf.Servers))
+	for _, server := range conf.Servers {
+		resolvers = append(resolvers, net.JoinHostPort(server, conf.Port))
+	}
+	return resolvers
+}
+
+func (c *SelfCheck) retries() int {
+	if c.Retries > 0 {
+		return c.Retries
+	}
+	return DefaultSelfCheckRetries
+}
+
+func (c *SelfCheck) interval() time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return PropagationInterval
+}
+
+func (c *SelfCheck) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultQueryTimeout
+}
@@ -0,0 +1,50 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestSelfCheck(t *testing.T) {
+	fake := linodetest.New()
+	defer fake.Close()
+
+	fake.AddDomain("example.com")
+	zone := fake.AddDomain("sub.example.com")
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	check := &SelfCheck{Resolvers: []string{dns.Addr()}, Nameservers: []string{dns.Addr()}, Interval: 10 * time.Millisecond}
+
+	// The zone is discovered from the closest SOA record.
+	if apex, err := check.FindZone(ctx, "_acme-challenge.sub.example.com"); err != nil || apex != "sub.example.com." {
+		t.Errorf("expected zone sub.example.com. got %q: %v", apex, err)
+	}
+
+	if _, err := check.FindZone(ctx, "_acme-challenge.example.org."); !errors.Is(err, ErrNoNameservers) {
+		t.Errorf("expected ErrNoNameservers got %v", err)
+	}
+
+	if err := check.Check(ctx, "_acme-challenge.sub.example.com.", "key1"); err != nil {
+		t.Errorf("expected record to be served: %v", err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer waitCancel()
+	if err := check.Wait(waitCtx, "_acme-challenge.sub.example.com.", "key2"); !errors.Is(err, ErrNotPropagated) {
+		t.Errorf("expected ErrNotPropagated got %v", err)
+	}
+}
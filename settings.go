@@ -0,0 +1,196 @@
+package acme
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// How often the settings file is read to check for changes.
+var SettingsPollInterval = 10 * time.Second
+
+// Settings are operational knobs that can be changed while the webhook is running by
+// editing a ConfigMap mounted at the --settings-file path, e.g. to raise the log level
+// or disable a check during an incident without a rollout. Unlike the solver config
+// they apply to every issuer; unset fields use the value of the corresponding flag.
+//
+//	logLevel: 4
+//	propagationCheck: authoritative
+//	reconcileInterval: 5m
+//	minRecordAge: 2m
+//	checkDelegation: false
+//	confirmEvents: true
+//
+// The propagation check is the default for issuers that do not specify their own.
+type Settings struct {
+	LogLevel          *int                `json:"logLevel,omitempty"`
+	PropagationCheck  PropagationCheck    `json:"propagationCheck,omitempty"`
+	ReconcileInterval *k8smetav1.Duration `json:"reconcileInterval,omitempty"`
+	MinRecordAge      *k8smetav1.Duration `json:"minRecordAge,omitempty"`
+	CheckDelegation   *bool               `json:"checkDelegation,omitempty"`
+	ConfirmEvents     *bool               `json:"confirmEvents,omitempty"`
+}
+
+// ParseSettings decodes and validates the YAML or JSON settings.
+func ParseSettings(data []byte) (settings *Settings, err error) {
+	settings = &Settings{}
+	if err = yaml.UnmarshalStrict(data, settings); err != nil {
+		return nil, fmt.Errorf("%w: could not parse settings: %w", ErrInvalidConfig, err)
+	}
+
+	if err = settings.PropagationCheck.validate(); err != nil {
+		return nil, err
+	}
+
+	if settings.LogLevel != nil && *settings.LogLevel < 0 {
+		return nil, fmt.Errorf("%w: logLevel must not be negative", ErrInvalidConfig)
+	}
+
+	for name, d := range map[string]*k8smetav1.Duration{"reconcileInterval": settings.ReconcileInterval, "minRecordAge": settings.MinRecordAge} {
+		if d != nil && d.Duration < 0 {
+			return nil, fmt.Errorf("%w: %s must not be negative", ErrInvalidConfig, name)
+		}
+	}
+	return settings, nil
+}
+
+// Returns the current runtime settings, which are empty if no settings file is loaded.
+func (s *LinodeDNSProviderSolver) settings() *Settings {
+	if settings := s.runtimeSettings.Load(); settings != nil {
+		return settings
+	}
+	return &Settings{}
+}
+
+func (s *LinodeDNSProviderSolver) currentMinRecordAge() time.Duration {
+	if age := s.settings().MinRecordAge; age != nil {
+		return age.Duration
+	}
+	return s.minRecordAge
+}
+
+func (s *LinodeDNSProviderSolver) currentReconcileInterval() time.Duration {
+	if interval := s.settings().ReconcileInterval; interval != nil {
+		return interval.Duration
+	}
+	return s.reconcileEvery
+}
+
+func (s *LinodeDNSProviderSolver) currentConfirmEvents() bool {
+	if confirm := s.settings().ConfirmEvents; confirm != nil {
+		return *confirm
+	}
+	return s.confirmEvents
+}
+
+// Returns the self check used to verify delegation or nil if the check is disabled.
+func (s *LinodeDNSProviderSolver) currentDelegationCheck() *SelfCheck {
+	if enabled := s.settings().CheckDelegation; enabled != nil {
+		if !*enabled {
+			return nil
+		}
+
+		if s.delegation == nil {
+			return &SelfCheck{}
+		}
+	}
+	return s.delegation
+}
+
+// Loads the settings file at startup, failing if it is invalid, then polls it for
+// changes until the stop channel is closed. Invalid changes are logged and ignored so
+// that the previous settings remain in effect.
+func (s *LinodeDNSProviderSolver) watchSettings(stopCh <-chan struct{}) (err error) {
+	if s.settingsFile == "" {
+		return nil
+	}
+
+	if !s.features.Enabled(FeatureRuntimeSettings) {
+		klog.Warningf("ignoring the %s settings file: the %s feature is disabled", s.settingsFile, FeatureRuntimeSettings)
+		return nil
+	}
+
+	// Record the log level from the flags so that it is restored if it is removed.
+	s.logLevel = verbosity()
+
+	var data []byte
+	if data, err = s.loadSettings(nil); err != nil {
+		return err
+	}
+
+	klog.Infof("watching %s for runtime settings", s.settingsFile)
+	go func() {
+		ticker := time.NewTicker(SettingsPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if updated, err := s.loadSettings(data); err != nil {
+					klog.Warningf("could not reload runtime settings, keeping previous settings: %v", err)
+				} else {
+					data = updated
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Reads the settings file and applies the settings if they differ from the previous
+// contents, returning the contents that are now in effect.
+func (s *LinodeDNSProviderSolver) loadSettings(previous []byte) (data []byte, err error) {
+	if data, err = os.ReadFile(s.settingsFile); err != nil {
+		return previous, fmt.Errorf("could not read settings file: %w", err)
+	}
+
+	if previous != nil && bytes.Equal(data, previous) {
+		return previous, nil
+	}
+
+	var settings *Settings
+	if settings, err = ParseSettings(data); err != nil {
+		return previous, fmt.Errorf("settings file %q: %w", s.settingsFile, err)
+	}
+
+	s.applySettings(settings)
+	return data, nil
+}
+
+func (s *LinodeDNSProviderSolver) applySettings(settings *Settings) {
+	level := s.logLevel
+	if settings.LogLevel != nil {
+		level = klog.Level(*settings.LogLevel)
+	}
+
+	if err := setVerbosity(level); err != nil {
+		klog.Warningf("could not set log level to %d: %v", level, err)
+	}
+
+	s.runtimeSettings.Store(settings)
+	klog.Infof("applied runtime settings from %s", s.settingsFile)
+}
+
+// Sets the klog verbosity if it differs from the current verbosity.
+func setVerbosity(level klog.Level) error {
+	if level == verbosity() {
+		return nil
+	}
+	return level.Set(strconv.Itoa(int(level)))
+}
+
+// Returns the current klog verbosity, which klog does not otherwise expose.
+func verbosity() (level klog.Level) {
+	for klog.V(level + 1).Enabled() {
+		level++
+	}
+	return level
+}
@@ -0,0 +1,81 @@
+package acme
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuntimeSettings(t *testing.T) {
+	solver, _ := newTestSolver(t)
+	solver.minRecordAge = time.Hour
+	solver.settingsFile = filepath.Join(t.TempDir(), "settings.yaml")
+
+	interval := SettingsPollInterval
+	SettingsPollInterval = 10 * time.Millisecond
+	defer func() { SettingsPollInterval = interval }()
+
+	level := verbosity()
+	defer solver.applySettings(&Settings{})
+
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(solver.settingsFile, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	// Invalid settings prevent the webhook from starting.
+	write("propagationCheck: recursive\n")
+	if err := solver.watchSettings(stopCh); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+
+	// Settings override the flags and are applied when the file changes.
+	write("minRecordAge: 1m\nlogLevel: 5\n")
+	if err := solver.watchSettings(stopCh); err != nil {
+		t.Fatalf("could not load settings: %v", err)
+	}
+
+	if age := solver.currentMinRecordAge(); age != time.Minute {
+		t.Errorf("expected min record age to be overridden, got %s", age)
+	}
+
+	if v := verbosity(); v != 5 {
+		t.Errorf("expected log level 5, got %d", v)
+	}
+
+	write("confirmEvents: true\n")
+	eventually(t, func() bool { return solver.currentConfirmEvents() })
+
+	if age := solver.currentMinRecordAge(); age != time.Hour {
+		t.Errorf("expected min record age flag when unset, got %s", age)
+	}
+
+	if v := verbosity(); v != level {
+		t.Errorf("expected log level to be restored to %d, got %d", level, v)
+	}
+
+	// Invalid changes are ignored.
+	write("confirmEvents: maybe\n")
+	time.Sleep(5 * SettingsPollInterval)
+	if !solver.currentConfirmEvents() {
+		t.Error("expected previous settings to remain in effect")
+	}
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
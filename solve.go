@@ -0,0 +1,225 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// PresentTXT solves a DNS-01 challenge outside of cert-manager by creating a TXT
+// record for the fully qualified domain name with the value in the Linode account of
+// the API token. The zone is resolved from the closest parent domain of the fqdn in
+// the account and the record is created or updated as by the webhook solver. PresentTXT
+// returns once every Linode nameserver serves the record, or ErrNotPropagated if the
+// context is done first; if the context has no deadline then DefaultTimeout is used.
+func PresentTXT(ctx context.Context, token, fqdn, value string) (err error) {
+	ctx, cancel := solveContext(ctx)
+	defer cancel()
+
+	linode := NewLinode(token).SetContext(ctx).Memoize()
+
+	var (
+		zone  *linodego.Domain
+		entry string
+	)
+
+	if zone, entry, err = linode.FindZoneForFQDN(fqdn); err != nil {
+		return err
+	}
+
+	var record *linodego.DomainRecord
+	if record, err = linode.EnsureTXT(zone.ID, entry, value); err != nil {
+		return err
+	}
+
+	klog.V(2).Infof("presented TXT record %s (ID %d) in zone %s", fqdn, record.ID, zone.Domain)
+	return waitForPropagation(ctx, fqdn, value)
+}
+
+// CleanupTXT deletes the TXT records for the fully qualified domain name with the value
+// that were created by PresentTXT, leaving any records with other values untouched. It
+// is not an error if there are no matching records.
+func CleanupTXT(ctx context.Context, token, fqdn, value string) (err error) {
+	ctx, cancel := solveContext(ctx)
+	defer cancel()
+
+	linode := NewLinode(token).SetContext(ctx).Memoize()
+
+	var (
+		zone  *linodego.Domain
+		entry string
+	)
+
+	if zone, entry, err = linode.FindZoneForFQDN(fqdn); err != nil {
+		return err
+	}
+
+	unlock := lockZone(zone.ID)
+	defer unlock()
+
+	if err = linode.DeleteTXT(zone.ID, entry, value); err != nil && !errors.Is(err, ErrNoRecord) {
+		return err
+	}
+	return nil
+}
+
+// The number of zones that PresentAll and CleanupAll process concurrently by default.
+const DefaultSolveParallelism = 4
+
+// TXTRecord is a challenge TXT record for PresentAll and CleanupAll.
+type TXTRecord struct {
+	FQDN  string `json:"fqdn"`
+	Value string `json:"value"`
+}
+
+// TXTStatus reports the outcome of presenting or cleaning up one TXT record.
+type TXTStatus struct {
+	TXTRecord
+	Zone     string        `json:"zone,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Err      error         `json:"-"`
+}
+
+// PresentAll presents the TXT records of a certificate whose names span several zones,
+// e.g. a large multi-domain certificate, in less time than presenting them one by one.
+// The token is resolved into a single client whose domain lookups are shared by every
+// record, and the zones are processed concurrently with at most parallelism zones at
+// once (DefaultSolveParallelism if not positive). The records of each zone are
+// presented in order, since mutations of a zone are serialized, and then awaited
+// until every Linode nameserver serves them. Records are appended as in append mode
+// rather than upserted, so records with the same fqdn and different values (e.g. for
+// example.com and *.example.com) are all served. The status of every record is
+// returned in the order of the records along with the errors of the records that
+// failed.
+func PresentAll(ctx context.Context, token string, records []TXTRecord, parallelism int) ([]TXTStatus, error) {
+	return solveAll(ctx, token, records, parallelism, func(linode *Linode, zone *linodego.Domain, entry string, record TXTRecord) error {
+		_, err := linode.AppendTXT(zone.ID, entry, record.Value)
+		return err
+	}, func(ctx context.Context, record TXTRecord) error {
+		return waitForPropagation(ctx, record.FQDN, record.Value)
+	})
+}
+
+// CleanupAll deletes the TXT records presented by PresentAll with the same bounded
+// parallelism across zones. As with CleanupTXT, it is not an error if a record has
+// already been deleted.
+func CleanupAll(ctx context.Context, token string, records []TXTRecord, parallelism int) ([]TXTStatus, error) {
+	return solveAll(ctx, token, records, parallelism, func(linode *Linode, zone *linodego.Domain, entry string, record TXTRecord) (err error) {
+		unlock := lockZone(zone.ID)
+		defer unlock()
+
+		if err = linode.DeleteTXT(zone.ID, entry, record.Value); err != nil && !errors.Is(err, ErrNoRecord) {
+			return err
+		}
+		return nil
+	}, nil)
+}
+
+type (
+	solveFunc func(linode *Linode, zone *linodego.Domain, entry string, record TXTRecord) error
+	awaitFunc func(ctx context.Context, record TXTRecord) error
+)
+
+// Resolves the zone of every record, then runs the operation on the records of each
+// zone with bounded parallelism across the zones, followed by the wait if any.
+func solveAll(ctx context.Context, token string, records []TXTRecord, parallelism int, solve solveFunc, await awaitFunc) ([]TXTStatus, error) {
+	ctx, cancel := solveContext(ctx)
+	defer cancel()
+
+	if parallelism <= 0 {
+		parallelism = DefaultSolveParallelism
+	}
+
+	linode := NewLinode(token).SetContext(ctx).Memoize()
+	statuses := make([]TXTStatus, len(records))
+
+	// The zones of a batch are resolved from a single listing of every domain rather
+	// than a filtered lookup per record; if the listing fails each lookup is retried.
+	if len(records) > 1 {
+		if _, err := linode.listDomains(); err != nil {
+			klog.V(2).Infof("could not list the linode domains of the batch: %v", err)
+		}
+	}
+
+	// Group the records by zone, preserving their order within each zone.
+	type zoneRecords struct {
+		zone    *linodego.Domain
+		entries map[int]string
+		indices []int
+	}
+
+	var zones []*zoneRecords
+	byZone := make(map[int]*zoneRecords)
+	for i, record := range records {
+		statuses[i].TXTRecord = record
+
+		zone, entry, err := linode.FindZoneForFQDN(record.FQDN)
+		if err != nil {
+			statuses[i].Err = err
+			continue
+		}
+
+		statuses[i].Zone = zone.Domain
+		group, ok := byZone[zone.ID]
+		if !ok {
+			group = &zoneRecords{zone: zone, entries: make(map[int]string)}
+			byZone[zone.ID] = group
+			zones = append(zones, group)
+		}
+
+		group.entries[i] = entry
+		group.indices = append(group.indices, i)
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+	for _, group := range zones {
+		wg.Go(func() {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			for _, i := range group.indices {
+				start := time.Now()
+				statuses[i].Err = solve(linode, group.zone, group.entries[i], records[i])
+				statuses[i].Duration = time.Since(start)
+			}
+
+			if await == nil {
+				return
+			}
+
+			for _, i := range group.indices {
+				if statuses[i].Err != nil {
+					continue
+				}
+
+				start := time.Now()
+				statuses[i].Err = await(ctx, records[i])
+				statuses[i].Duration += time.Since(start)
+			}
+		})
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, status := range statuses {
+		if status.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", status.FQDN, status.Err))
+		}
+	}
+
+	klog.V(2).Infof("solved %d TXT records in %d zones with %d errors", len(records), len(zones), len(errs))
+	return statuses, errors.Join(errs...)
+}
+
+func solveContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
@@ -0,0 +1,277 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestSolveTXT(t *testing.T) {
+	fake := linodetest.New()
+	defer fake.Close()
+	t.Setenv("LINODE_URL", fake.URL())
+
+	fake.AddDomain("example.com")
+	zone := fake.AddDomain("sub.example.com")
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	nameservers, interval := LinodeNameservers, PropagationInterval
+	LinodeNameservers, PropagationInterval = []string{dns.Addr()}, 10*time.Millisecond
+	defer func() { LinodeNameservers, PropagationInterval = nameservers, interval }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The record is created in the closest parent zone of the fqdn.
+	if err := PresentTXT(ctx, "linodetest", "_acme-challenge.sub.example.com.", "key1"); err != nil {
+		t.Fatalf("could not present TXT record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	if err := CleanupTXT(ctx, "linodetest", "_acme-challenge.sub.example.com.", "key1"); err != nil {
+		t.Fatalf("could not clean up TXT record: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+
+	// Cleaning up a record that does not exist is not an error.
+	if err := CleanupTXT(ctx, "linodetest", "_acme-challenge.sub.example.com.", "key1"); err != nil {
+		t.Errorf("expected clean up without a record to succeed: %v", err)
+	}
+
+	if err := PresentTXT(ctx, "linodetest", "_acme-challenge.example.org.", "key1"); !errors.Is(err, ErrNoZone) {
+		t.Errorf("expected ErrNoZone got %v", err)
+	}
+}
+
+func TestSolveAll(t *testing.T) {
+	fake := linodetest.New()
+	defer fake.Close()
+	t.Setenv("LINODE_URL", fake.URL())
+
+	zones := []linodego.Domain{fake.AddDomain("example.com"), fake.AddDomain("example.net"), fake.AddDomain("example.org")}
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dns.Close()
+
+	nameservers, interval := LinodeNameservers, PropagationInterval
+	LinodeNameservers, PropagationInterval = []string{dns.Addr()}, 10*time.Millisecond
+	defer func() { LinodeNameservers, PropagationInterval = nameservers, interval }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The records for example.com and *.example.com share the same fqdn.
+	records := []TXTRecord{
+		{FQDN: "_acme-challenge.example.com.", Value: "key1"},
+		{FQDN: "_acme-challenge.www.example.com.", Value: "key2"},
+		{FQDN: "_acme-challenge.example.net.", Value: "key3"},
+		{FQDN: "_acme-challenge.example.invalid.", Value: "key4"},
+		{FQDN: "_acme-challenge.example.org.", Value: "key5"},
+		{FQDN: "_acme-challenge.example.com.", Value: "key6"},
+	}
+
+	// Records in zones that are not hosted fail without affecting the other records.
+	statuses, err := PresentAll(ctx, "linodetest", records, 2)
+	if !errors.Is(err, ErrNoZone) {
+		t.Fatalf("expected the record in the missing zone to fail, got %v", err)
+	}
+
+	for i, status := range statuses {
+		if status.TXTRecord != records[i] || (i == 3) != (status.Err != nil) {
+			t.Errorf("unexpected status for %s: %+v", records[i].FQDN, status)
+		}
+	}
+
+	if statuses[1].Zone != "example.com" || statuses[2].Zone != "example.net" {
+		t.Errorf("unexpected zones %q and %q", statuses[1].Zone, statuses[2].Zone)
+	}
+
+	assertTargets(t, fake, zones[0].ID, "_acme-challenge", "key1", "key6")
+	assertTargets(t, fake, zones[0].ID, "_acme-challenge.www", "key2")
+	assertTargets(t, fake, zones[1].ID, "_acme-challenge", "key3")
+	assertTargets(t, fake, zones[2].ID, "_acme-challenge", "key5")
+
+	// The domains are only listed once for all of the records.
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected the domains to be listed once, got %d", calls)
+	}
+
+	if _, err = CleanupAll(ctx, "linodetest", append(records[:3:3], records[4:]...), 0); err != nil {
+		t.Fatalf("could not clean up records: %v", err)
+	}
+
+	for _, zone := range zones {
+		if n := len(fake.Records(zone.ID)); n != 0 {
+			t.Errorf("expected no records in zone %s, got %d", zone.Domain, n)
+		}
+	}
+}
+
+func TestStopContext(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	zone := fake.AddDomain("example.com")
+
+	stopCh := make(chan struct{})
+	solver.ctx = stopContext(stopCh)
+	if err := solver.ctx.Err(); err != nil {
+		t.Fatalf("expected the context to be active before the webhook stops, got %v", err)
+	}
+
+	close(stopCh)
+	select {
+	case <-solver.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the context to be canceled when the webhook stops")
+	}
+
+	// Linode API requests are not made once the webhook has stopped.
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a canceled error, got %v", err)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 0 {
+		t.Errorf("expected no records to be created, found %+v", records)
+	}
+
+	if stopContext(nil).Done() != nil {
+		t.Error("expected a nil stop channel to never cancel the context")
+	}
+}
+
+func TestRecordTTL(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.recordTTL = 3600
+	zone := fake.AddDomain("example.com")
+
+	present := func(key, config string) linodego.DomainRecord {
+		t.Helper()
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge." + key + ".example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: key}
+		if config != "" {
+			ch.Config = &extapi.JSON{Raw: []byte(config)}
+		}
+
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+
+		for _, record := range fake.Records(zone.ID) {
+			if record.Target == key {
+				return record
+			}
+		}
+		t.Fatalf("no record presented for %s", key)
+		return linodego.DomainRecord{}
+	}
+
+	// Issuers that do not set a TTL use the default of the webhook.
+	if record := present("key1", ""); record.TTLSec != 3600 {
+		t.Errorf("expected the default ttl of 3600, got %d", record.TTLSec)
+	}
+
+	// Issuers override the default, rounded up to a TTL supported by Linode.
+	if record := present("key2", `{"apiKeySecretRef": {"name": "linode-credentials", "key": "token"}, "ttlSeconds": 60}`); record.TTLSec != 120 {
+		t.Errorf("expected the issuer ttl rounded up to 120, got %d", record.TTLSec)
+	}
+}
+
+func TestIssuerAPIURL(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	zone := fake.AddDomain("example.com")
+
+	// The default endpoint is unreachable so that only the issuer's apiURL can succeed.
+	t.Setenv("LINODE_URL", "http://127.0.0.1:1")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+		Config:            &extapi.JSON{Raw: []byte(`{"apiKeySecretRef": {"name": "linode-credentials", "key": "token"}, "apiURL": "` + fake.URL() + `"}`)},
+	}
+
+	// Issuers may only send their token to the URLs allowed by the webhook.
+	if err := solver.Present(ch); !errors.Is(err, ErrAPIURLNotAllowed) {
+		t.Fatalf("expected the apiURL not to be allowed, got %v", err)
+	}
+
+	solver.allowedAPIURLs = []string{fake.URL()}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+
+	// The webhook endpoint is used by issuers that do not set one.
+	solver.apiURL = fake.URL()
+	ch.Config, ch.Key = nil, "key2"
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key2")
+}
+
+func TestConcurrentZoneMutations(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	zone := fake.AddDomain("example.com")
+
+	// Every challenge contends for the same name in the same zone.
+	challenge := func(i int) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: fmt.Sprintf("key%d", i)}
+	}
+
+	mutate := func(n int, op func(*v1alpha1.ChallengeRequest) error) {
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Go(func() {
+				if err := op(challenge(i % 8)); err != nil {
+					t.Errorf("could not mutate challenge %d: %v", i, err)
+				}
+			})
+		}
+		wg.Wait()
+	}
+
+	// Upserts with different keys are serialized, so each one finds and updates the
+	// record of the previous one rather than all of them creating a record.
+	mutate(8, solver.Present)
+	if records := fake.Records(zone.ID); len(records) != 1 {
+		t.Fatalf("expected the upserts to share 1 record, got %d:\n%s", len(records), fake)
+	}
+
+	mutate(8, solver.CleanUp)
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+
+	// Appends of each key twice create exactly one record per key, and the clean ups
+	// of every key remove all of them.
+	solver.recordMode = RecordModeAppend
+	mutate(16, solver.Present)
+
+	var targets []string
+	for _, record := range fake.Records(zone.ID) {
+		targets = append(targets, record.Target)
+	}
+
+	slices.Sort(targets)
+	if expected := []string{"key0", "key1", "key2", "key3", "key4", "key5", "key6", "key7"}; !slices.Equal(targets, expected) {
+		t.Fatalf("expected one record per key %v, got %v", expected, targets)
+	}
+
+	mutate(8, solver.CleanUp)
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+}
@@ -2,15 +2,20 @@ package acme
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
 	"github.com/linode/linodego"
 	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
@@ -23,10 +28,15 @@ import (
 )
 
 const (
-	DefaultTokenSecretName = "linode-credentials"
-	DefaultTokenSecretKey  = "token"
+	DefaultTokenSecretName      = "linode-credentials"
+	DefaultTokenSecretKey       = "token"
+	ServiceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 )
 
+// With --namespace-credentials, a secret in the challenge namespace with this annotation
+// provides the Linode API token; the value of the annotation is the key of the token.
+const AnnotationCredentials = "acme-linode.rtnl.ai/credentials"
+
 //===========================================================================
 // Solver Interface
 //===========================================================================
@@ -35,10 +45,99 @@ const (
 // 'present' an ACME challenge TXT record for your own DNS provider.
 // Implements `github.com/cert-manager/cert-manager/pkg/acme/webhook.Solver`
 type LinodeDNSProviderSolver struct {
-	k8s          *kubernetes.Clientset
-	ctx          context.Context
-	namespace    string
-	secretKeyRef *cmmeta.SecretKeySelector
+	name                 string
+	apiURL               string
+	linodeAPIURL         string
+	allowedAPIURLs       []string
+	transportOptions     transportOptions
+	linodeDebug          bool
+	userAgentSuffix      string
+	verifyTokenScope     bool
+	verifiedTokens       *lru[string, time.Time]
+	instance             *SolverInstance
+	k8s                  kubernetes.Interface
+	ctx                  context.Context
+	namespace            string
+	namespaceFile        string
+	namespaceOnce        sync.Once
+	secretKeyRef         *cmmeta.SecretKeySelector
+	kubeQPS              float32
+	kubeBurst            int
+	linodeQPS            float32
+	linodeBurst          int
+	recordTTL            int
+	linodeTimeout        time.Duration
+	linodeReadTimeout    time.Duration
+	linodeWriteTimeout   time.Duration
+	singleNamespace      bool
+	namespaceCredentials bool
+	delegationCheck      bool
+	confirmEvents        bool
+	auditFile            string
+	auditMaxSize         int
+	auditMaxBackups      int
+	auditIssuers         bool
+	audit                *AuditLog
+	cloudEventsSink      string
+	cloudEventsSource    string
+	cloudEvents          *cloudEventSink
+	delegation           *SelfCheck
+	cnames               *SelfCheck
+	maxInflight          int
+	maxQueue             int
+	maxQueueWait         time.Duration
+	cacheSize            int
+	trackRecords         bool
+	reconcileEvery       time.Duration
+	dedupeRecords        bool
+	purgePatterns        []string
+	purgeMinAge          time.Duration
+	purgeForeignRecords  bool
+	purge                *purgePolicy
+	maxZoneRecords       int
+	maxChallengeRecords  int
+	hygiene              *zoneHygiene
+	presentCacheTTL      time.Duration
+	presents             *presentCache
+	credentialCheckEvery time.Duration
+	credentialWarning    time.Duration
+	credentialHealth     *credentialHealth
+	clients              *clientPool
+	tracker              *recordTracker
+	annotate             bool
+	annotator            *challengeAnnotator
+	prewarmWindow        time.Duration
+	domains              *domainCache
+	zones                *zoneCache
+	zoneCacheTTL         time.Duration
+	recordMode           RecordMode
+	minRecordAge         time.Duration
+	settingsFile         string
+	runtimeSettings      atomic.Pointer[Settings]
+	logLevel             klog.Level
+	features             FeatureGates
+	quorumResolvers      []string
+	quorum               int
+	httpClient           *http.Client
+	retryPolicy          *RetryPolicy
+	linodeFactory        LinodeFactory
+	childTokens          *childTokenCache
+	oauthTokens          *oauthTokenCache
+	policyFile           string
+	policy               atomic.Pointer[Policy]
+	challenges           *challengeAnnotator
+	routesFile           string
+	routes               atomic.Pointer[Routes]
+	fallback             DNSProvider
+	fallbackProvider     string
+	rfc2136              rfc2136Options
+	history              errorHistory
+	cleanups             cleanupCounters
+	failureLimit         int
+	failureCooldown      time.Duration
+	watchdog             *watchdog
+	admission            *admission
+	initialized          atomic.Bool
 }
 
 // Ensure LinodeDNSProviderSolver meets the webhook.Solver interface
@@ -62,9 +161,90 @@ var _ webhook.Solver = (*LinodeDNSProviderSolver)(nil)
 // You should not include sensitive information here. If credentials need to
 // be used by your provider here, you should reference a Kubernetes Secret
 // resource and fetch these credentials using a Kubernetes clientset.
+//
+// The config may optionally specify an apiVersion and kind so that the layout can
+// evolve; see config.go for the supported versions and how they are migrated.
 type LinodeDNSProviderConfig struct {
+	// Optional version and kind of the config; unversioned configs are migrated.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
 	// Expect apiKeySecretRef with name: <secret name> and key: <token field in secret>
 	APIKeySecretRef cmmeta.SecretKeySelector `json:"apiKeySecretRef"`
+
+	// Alternatively select the secret by label in the challenge namespace, e.g. if the
+	// secret is renamed on every rotation; exactly one secret must match.
+	APIKeySecretSelector *SecretKeyLabelSelector `json:"apiKeySecretSelector,omitempty"`
+
+	// Alternatively obtain short-lived access tokens with OAuth client credentials.
+	OAuth *OAuthClientCredentials `json:"oauth,omitempty"`
+
+	// Optional base URL of the Linode API, e.g. a mock server, the beta endpoint, or an
+	// internal API proxy; issuers may only use the URLs allowed by the webhook.
+	APIURL string `json:"apiURL,omitempty"`
+
+	// Optional EUUID of an Akamai child account whose domains are managed with the
+	// token of its parent account, which is exchanged for a proxy token of the child.
+	ChildAccount string `json:"childAccount,omitempty"`
+
+	// Select the domain by ID or tag if the account has more than one domain with the
+	// name of the zone; only the selected domains are used to solve challenges.
+	DomainIDs []int  `json:"domainIDs,omitempty"`
+	DomainTag string `json:"domainTag,omitempty"`
+
+	// Alternatively skip zone discovery and use the domain with this ID as the zone
+	// resolved by cert-manager, e.g. for accounts with thousands of domains.
+	DomainID int `json:"domainID,omitempty"`
+
+	// Optional name of the zone that hosts the challenge records, used in place of the
+	// zone resolved by cert-manager, e.g. a dedicated zone for ACME challenges.
+	ZoneName string `json:"zoneName,omitempty"`
+
+	// Create the record at the target of the CNAME records of the challenge name, if
+	// any, e.g. if _acme-challenge.example.com is delegated to a validation zone.
+	FollowCNAME bool `json:"followCNAME,omitempty"`
+
+	// Optional tuning of the challenge records and Linode API requests; zero values
+	// use the webhook defaults. See Validate for the allowed ranges.
+	TTLSeconds       int              `json:"ttlSeconds,omitempty"`
+	TimeoutSeconds   int              `json:"timeoutSeconds,omitempty"`
+	MaxRetries       *int             `json:"maxRetries,omitempty"`
+	PropagationCheck PropagationCheck `json:"propagationCheck,omitempty"`
+
+	// Optional timeouts of the Linode API operations that list or read the domains and
+	// records (read) and that create, update, or delete records (write), overriding
+	// timeoutSeconds for the Linode API operations of their kind.
+	ReadTimeoutSeconds  int `json:"readTimeoutSeconds,omitempty"`
+	WriteTimeoutSeconds int `json:"writeTimeoutSeconds,omitempty"`
+
+	// Optional client-side rate limit of the Linode API requests made with the token,
+	// shared by every challenge solved with it; zero values use the webhook defaults.
+	APIQPS   float32 `json:"apiQPS,omitempty"`
+	APIBurst int     `json:"apiBurst,omitempty"`
+
+	// Delete challenge records even if they are younger than the minimum record age.
+	ForceCleanup bool `json:"forceCleanup,omitempty"`
+
+	// Create a master zone for the zone resolved by cert-manager if the Linode account
+	// has no zone for the challenge, with the SOA email (hostmaster@<zone> if unset).
+	CreateZoneIfMissing bool   `json:"createZoneIfMissing,omitempty"`
+	ZoneSOAEmail        string `json:"zoneSOAEmail,omitempty"`
+}
+
+// SetHTTPClient sets the http client used for all Linode API requests, e.g. so that
+// programs embedding the solver can use their own instrumented or retrying client. It
+// must be called before the solver is initialized; nil uses the default client.
+func (s *LinodeDNSProviderSolver) SetHTTPClient(hc *http.Client) *LinodeDNSProviderSolver {
+	s.httpClient = hc
+	return s
+}
+
+// SetRetryPolicy sets the retry policy of all Linode API requests; the maxRetries of
+// the issuer config takes precedence. It must be called before the solver is
+// initialized; nil uses the linodego defaults.
+func (s *LinodeDNSProviderSolver) SetRetryPolicy(policy *RetryPolicy) *LinodeDNSProviderSolver {
+	s.retryPolicy = policy
+	return s
 }
 
 // Name is used as the name for this DNS solver when referencing it on the ACME
@@ -76,6 +256,9 @@ type LinodeDNSProviderConfig struct {
 //
 // For example, `cloudflare` may be used as the name of a solver.
 func (s *LinodeDNSProviderSolver) Name() string {
+	if s.name != "" {
+		return s.name
+	}
 	return "linode"
 }
 
@@ -87,36 +270,128 @@ func (s *LinodeDNSProviderSolver) Name() string {
 func (s *LinodeDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) (err error) {
 	klog.Infof("presented with challenge for fqdn=%s zone=%s", ch.ResolvedFQDN, ch.ResolvedZone)
 
-	var linode *Linode
-	if linode, err = s.LinodeClient(ch); err != nil {
+	defer s.logRequest("Present", ch, time.Now(), &err)
+
+	if ch, err = adaptChallenge(ch, v1alpha1.ChallengeActionPresent); err != nil {
+		return err
+	}
+
+	if s.presents.hit(ch) {
+		klog.V(2).Infof("challenge for fqdn=%s was presented recently, skipping linode API requests", ch.ResolvedFQDN)
+		return nil
+	}
+
+	var release func()
+	if release, err = s.admission.acquire("present"); err != nil {
+		return err
+	}
+	defer release()
+
+	err = s.retryUnauthorized(ch, s.present)
+	s.watchdog.observe(err)
+
+	if err != nil && s.fallback != nil && shouldFallback(err) {
+		klog.Warningf("presenting challenge for fqdn=%s with the %s fallback provider: %v", ch.ResolvedFQDN, s.fallback.Name(), err)
+		if err = s.authorizeFallback(ch, AuditPresent); err == nil {
+			if err = s.fallback.Present(ch); err == nil {
+				s.cloudEvents.emit(CloudEventPresented, ch)
+			}
+		}
+	}
+
+	if err != nil {
+		s.history.record("present", ch.ResolvedZone, ch.ResolvedFQDN, err)
+		return err
+	}
+
+	s.presents.store(ch)
+	return nil
+}
+
+func (s *LinodeDNSProviderSolver) present(ch *v1alpha1.ChallengeRequest) (err error) {
+	var (
+		linode LinodeAPI
+		cfg    LinodeDNSProviderConfig
+	)
+
+	if linode, cfg, err = s.linodeAPI(ch); err != nil {
 		klog.Errorf("failed to create linode client: %v", err)
 		return err
 	}
 
-	// Compute the entry and the domain from the request
-	entry, domain := DomainEntry(ch.ResolvedFQDN, ch.ResolvedZone)
+	if cfg.PropagationCheck == PropagationCheckQuorum && !s.features.Enabled(FeatureResolverQuorum) {
+		return fmt.Errorf("%w: the quorum propagation check requires the %s feature gate", ErrInvalidConfig, FeatureResolverQuorum)
+	}
+
+	// Create the record at the target of the CNAME of the challenge name if requested.
+	if cfg.FollowCNAME {
+		if ch, err = s.followCNAME(ch); err != nil {
+			return err
+		}
+	}
+
+	// Fetch the zone that hosts the fqdn from the Linode account and compute the entry
+	var (
+		zone  *linodego.Domain
+		entry string
+	)
+
+	if zone, entry, err = s.findZone(linode, ch, cfg); err != nil {
+		if !cfg.CreateZoneIfMissing || !errors.Is(err, ErrNoZone) {
+			klog.Errorf("failed to find zone for %q in linode account: %v", ch.ResolvedFQDN, err)
+			return err
+		}
+
+		if zone, entry, err = s.createMissingZone(linode, ch, cfg); err != nil {
+			return err
+		}
+	}
+
+	// Fail fast if another provider is authoritative for the zone
+	if err = s.checkDelegation(zone.Domain); err != nil {
+		return err
+	}
 
-	// Fetch the zone from the Linode account
-	var zone *linodego.Domain
-	if zone, err = linode.FindZone(domain); err != nil {
-		klog.Errorf("failed to find zone %q in linode account: %v", domain, err)
+	if err = s.authorize(ch, AuditPresent, zone.Domain, entry, zone.ID); err != nil {
 		return err
 	}
 
-	// Fetch the txt record for the specified entry
+	// Create or update the txt record for the specified entry
 	var record *linodego.DomainRecord
-	if record, err = linode.FindRecord(zone.ID, entry); err != nil {
-		if errors.Is(err, ErrNoRecord) {
-			// Record does not exist, create it
-			return linode.CreateRecord(zone.ID, entry, ch.Key)
-		}
+	switch s.recordMode {
+	case RecordModeCreate:
+		record, err = s.createTXT(linode, zone.ID, entry, ch)
+	case RecordModeAppend:
+		record, err = linode.AppendTXT(zone.ID, entry, ch.Key)
+	default:
+		record, err = linode.EnsureTXT(zone.ID, entry, ch.Key)
+	}
 
-		klog.Errorf("failed to find record %q in linode zone %q: %v", entry, domain, err)
+	if err != nil {
 		return err
 	}
 
-	// If the record already exists, update it
-	return linode.UpdateRecord(zone.ID, record.ID, record.Name, ch.Key)
+	s.tracker.track(ch, zone.ID, entry, record)
+	s.annotator.annotate(ch, zone.ID, record.ID)
+	if client, ok := linode.(*Linode); ok {
+		s.checkZoneHygiene(client, zone)
+	}
+	s.cloudEvents.emit(CloudEventPresented, ch)
+
+	// Wait for the record to be served by Linode if requested by the issuer.
+	switch cfg.PropagationCheck {
+	case PropagationCheckAuthoritative:
+		err = WaitForPropagation(ch.ResolvedFQDN, ch.Key, cfg.Timeout())
+	case PropagationCheckQuorum:
+		err = s.waitForQuorum(ch.ResolvedFQDN, ch.Key, cfg.Timeout())
+	default:
+		return nil
+	}
+
+	if err == nil {
+		s.cloudEvents.emit(CloudEventVerified, ch)
+	}
+	return err
 }
 
 // CleanUp should delete the relevant TXT record from the DNS provider console.
@@ -128,36 +403,135 @@ func (s *LinodeDNSProviderSolver) Present(ch *v1alpha1.ChallengeRequest) (err er
 func (s *LinodeDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) (err error) {
 	klog.Infof("cleaning up challenge for fqdn=%s zone=%s", ch.ResolvedFQDN, ch.ResolvedZone)
 
-	var linode *Linode
-	if linode, err = s.LinodeClient(ch); err != nil {
-		klog.Errorf("failed to create linode client: %v", err)
+	defer s.logRequest("CleanUp", ch, time.Now(), &err)
+
+	if ch, err = adaptChallenge(ch, v1alpha1.ChallengeActionCleanUp); err != nil {
 		return err
 	}
 
-	// Compute the entry and the domain from the request
-	entry, domain := DomainEntry(ch.ResolvedFQDN, ch.ResolvedZone)
+	// Forget the present even if the cleanup fails so that it is not skipped if the
+	// challenge is presented again.
+	s.presents.forget(ch)
 
-	// Fetch the zone from the Linode account
-	var zone *linodego.Domain
-	if zone, err = linode.FindZone(domain); err != nil {
-		klog.Warningf("failed to find zone %q in linode account: %v", domain, err)
+	var release func()
+	if release, err = s.admission.acquire("cleanup"); err != nil {
 		return err
 	}
+	defer release()
 
-	// Fetch the txt record for the specified entry
-	var record *linodego.DomainRecord
-	if record, err = linode.FindRecord(zone.ID, entry); err != nil {
-		if errors.Is(err, ErrNoRecord) {
-			// Record does not exist, nothing to clean up and no error
-			return nil
+	err = s.retryUnauthorized(ch, s.cleanup)
+	s.watchdog.observe(err)
+
+	if err != nil && s.fallback != nil && shouldFallback(err) {
+		klog.Warningf("cleaning up challenge for fqdn=%s with the %s fallback provider: %v", ch.ResolvedFQDN, s.fallback.Name(), err)
+		if err = s.authorizeFallback(ch, AuditCleanUp); err == nil {
+			err = s.fallback.CleanUp(ch)
+		}
+	}
+
+	if err != nil {
+		s.history.record("cleanup", ch.ResolvedZone, ch.ResolvedFQDN, err)
+		return err
+	}
+
+	s.cloudEvents.emit(CloudEventCleanedUp, ch)
+	return nil
+}
+
+func (s *LinodeDNSProviderSolver) cleanup(ch *v1alpha1.ChallengeRequest) (err error) {
+	var (
+		linode LinodeAPI
+		cfg    LinodeDNSProviderConfig
+	)
+
+	if linode, cfg, err = s.linodeAPI(ch); err != nil {
+		klog.Errorf("failed to create linode client: %v", err)
+		return err
+	}
+
+	// The record was created at the target of the CNAME of the challenge name.
+	if cfg.FollowCNAME {
+		if ch, err = s.followCNAME(ch); err != nil {
+			return err
 		}
+	}
 
-		klog.Warningf("failed to find record %q in linode zone %q: %v", entry, domain, err)
+	// Fetch the zone that hosts the fqdn from the Linode account and compute the entry
+	var (
+		zone  *linodego.Domain
+		entry string
+	)
+
+	if zone, entry, err = s.findZone(linode, ch, cfg); err != nil {
+		klog.Warningf("failed to find zone for %q in linode account: %v", ch.ResolvedFQDN, err)
 		return err
 	}
 
-	// Delete the record for thee specified entry
-	return linode.DeleteRecord(zone.ID, record.ID)
+	if err = s.authorize(ch, AuditCleanUp, zone.Domain, entry, zone.ID); err != nil {
+		return err
+	}
+
+	// In create mode only the record created for this challenge is deleted.
+	if s.recordMode == RecordModeCreate {
+		return s.deleteCreated(linode, zone.ID, entry, ch, cfg.ForceCleanup)
+	}
+
+	// Delete only the txt records for the entry with the challenge key unless they are
+	// too young; if there are none then there is nothing to clean up and no error. The
+	// challenge is untracked with the zone lock held, like in create mode.
+	unlock := lockZone(zone.ID)
+	defer unlock()
+
+	if err = s.deleteTXT(linode, zone.ID, entry, ch, cfg.ForceCleanup); err != nil {
+		klog.Warningf("failed to clean up record %q in linode zone %q: %v", entry, zone.Domain, err)
+		return err
+	}
+
+	s.tracker.untrack(ch)
+	return nil
+}
+
+// Returns the Linode zone that hosts the fqdn of the challenge and the entry of the
+// fqdn in it. If the config sets a zoneName, the zone with that name is used instead
+// of the zone resolved by cert-manager and its parents. If the config sets a domainID,
+// the zone is not looked up: the domain is assumed to be the zone, so no domains are
+// listed and a secondary zone is not detected before its records are created.
+func (s *LinodeDNSProviderSolver) findZone(linode LinodeAPI, ch *v1alpha1.ChallengeRequest, cfg LinodeDNSProviderConfig) (*linodego.Domain, string, error) {
+	if cfg.DomainID == 0 && cfg.ZoneName == "" {
+		return linode.FindCandidateZone(ch.ResolvedFQDN, ch.ResolvedZone)
+	}
+
+	name, fqdn := normalizeZone(cfg.resolvedZone(ch)), normalizeZone(ch.ResolvedFQDN)
+	if name == "" || (fqdn != name && !strings.HasSuffix(fqdn, "."+name)) {
+		return nil, "", fmt.Errorf("%w: the challenge for %q is not in the zone %q", ErrInvalidConfig, ch.ResolvedFQDN, name)
+	}
+
+	entry, _ := DomainEntry(fqdn, name)
+	if cfg.DomainID == 0 {
+		zone, err := linode.FindZone(name)
+		return zone, entry, err
+	}
+
+	if client, ok := linode.(*Linode); ok {
+		client.auditZones.Store(cfg.DomainID, name)
+	}
+
+	return &linodego.Domain{ID: cfg.DomainID, Domain: name, Type: linodego.DomainTypeMaster}, entry, nil
+}
+
+// Returns a context that is canceled when the stop channel is closed; a nil channel is
+// never closed.
+func stopContext(stopCh <-chan struct{}) context.Context {
+	if stopCh == nil {
+		return context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	return ctx
 }
 
 // Initialize will be called when the webhook first starts.
@@ -174,39 +548,260 @@ func (s *LinodeDNSProviderSolver) CleanUp(ch *v1alpha1.ChallengeRequest) (err er
 // where a SIGTERM or similar signal is sent to the webhook process.
 func (s *LinodeDNSProviderSolver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) (err error) {
 	klog.Info("Initializing Linode DNS provider solver webhook")
+	klog.Infof("feature gates: %s", s.features.describe())
+
+	// Copy the config so that the client rate limits do not affect the webhook server.
+	kubeClientConfig = rest.CopyConfig(kubeClientConfig)
+	if s.kubeQPS != 0 {
+		kubeClientConfig.QPS = s.kubeQPS
+	}
+	if s.kubeBurst != 0 {
+		kubeClientConfig.Burst = s.kubeBurst
+	}
+
+	// The kubernetes client requires a burst when a positive QPS is specified.
+	if kubeClientConfig.QPS > 0 && kubeClientConfig.Burst <= 0 {
+		kubeClientConfig.Burst = rest.DefaultBurst
+	}
+
+	if kubeClientConfig.QPS < 0 {
+		klog.Info("kubernetes client-side rate limiting is disabled")
+	} else {
+		klog.V(2).Infof("kubernetes client configured with qps=%v burst=%d", kubeClientConfig.QPS, kubeClientConfig.Burst)
+	}
+
 	if s.k8s, err = kubernetes.NewForConfig(kubeClientConfig); err != nil {
 		return fmt.Errorf("failed to create kube client: %v", err)
 	}
 
-	s.ctx = context.Background()
-	return nil
-}
+	if s.admission = newAdmission(s.maxInflight, s.maxQueue, s.maxQueueWait); s.admission != nil {
+		klog.Infof("load shedding enabled with max %d in flight and %d queued operations", s.maxInflight, s.admission.maxQueue)
+	}
 
-// LoadConfig is a small helper function that decodes JSON configuration into
-// the typed config struct.
-func LoadConfig(data *extapi.JSON) (cfg LinodeDNSProviderConfig, err error) {
-	// handle the 'base case' where no configuration has been provided
-	if data == nil {
-		return cfg, nil
+	if err = s.loadRoutes(); err != nil {
+		return err
 	}
+	s.logInstance()
 
-	if err = json.Unmarshal(data.Raw, &cfg); err != nil {
-		return cfg, fmt.Errorf("error decoding solver config: %v", err)
+	if s.fallback, err = s.newFallbackProvider(); err != nil {
+		return err
 	}
 
-	return cfg, nil
+	if s.fallback != nil {
+		klog.Infof("challenges for zones not hosted in linode or when linode is unavailable will use the %s fallback provider", s.fallback.Name())
+	}
+
+	if err = s.loadPolicy(); err != nil {
+		return err
+	}
+
+	// The cert-manager resources are read with a dynamic client if they are needed.
+	var dyn dynamic.Interface
+	auditIssuers := s.auditFile != "" && s.auditIssuers
+	if s.annotate || s.prewarmWindow > 0 || s.policy.Load().needsIssuers() || auditIssuers {
+		if dyn, err = dynamic.NewForConfig(kubeClientConfig); err != nil {
+			return fmt.Errorf("failed to create dynamic kube client: %v", err)
+		}
+	}
+
+	if s.annotate {
+		s.annotator = &challengeAnnotator{k8s: s.k8s, dynamic: dyn, history: &s.history}
+		klog.Info("challenge resources will be annotated with the linode domain and record IDs")
+	}
+
+	if s.prewarmWindow > 0 {
+		s.domains = newDomainCache(s.Name())
+		go newCertificatePrewarmer(s, dyn, s.prewarmWindow).run(stopCh)
+		klog.Infof("pre-warming the zone lookups of certificates within %s of renewal", s.prewarmWindow)
+	}
+
+	if s.policy.Load().needsIssuers() {
+		s.challenges = &challengeAnnotator{dynamic: dyn}
+		klog.Info("the issuers of challenges will be read from challenge resources for the authorization policy")
+	}
+
+	if auditIssuers {
+		s.challenges = &challengeAnnotator{dynamic: dyn}
+		klog.Info("the issuers of challenges will be read from challenge resources for the audit log")
+	}
+
+	if s.recordMode == "" {
+		s.recordMode = RecordModeUpsert
+	}
+
+	if err = s.recordMode.validate(); err != nil {
+		return err
+	}
+
+	if s.recordTTL < 0 || s.recordTTL > MaxTTLSeconds {
+		return fmt.Errorf("%w: --record-ttl must be between 0 and %d", ErrInvalidConfig, MaxTTLSeconds)
+	}
+
+	if strings.ContainsFunc(s.userAgentSuffix, func(r rune) bool { return r < ' ' || r > '~' }) {
+		return fmt.Errorf("%w: --user-agent-suffix may only contain printable ASCII characters", ErrInvalidConfig)
+	}
+
+	// Solver instances use their own endpoint instead of the webhook's.
+	if s.apiURL == "" {
+		s.apiURL = s.linodeAPIURL
+	}
+
+	if err = (LinodeDNSProviderConfig{APIURL: s.apiURL}).Validate(); err != nil {
+		return fmt.Errorf("invalid --linode-api-url: %w", err)
+	}
+
+	for _, timeout := range []time.Duration{s.linodeTimeout, s.linodeReadTimeout, s.linodeWriteTimeout} {
+		if timeout < 0 || timeout > MaxTimeoutSeconds*time.Second {
+			return fmt.Errorf("%w: the linode API timeouts must be between 0 and %ds", ErrInvalidConfig, MaxTimeoutSeconds)
+		}
+	}
+
+	if s.recordTTL > 0 && linodeTTL(s.recordTTL) != s.recordTTL {
+		klog.Infof("linode does not support a record ttl of %ds, challenge records will have a ttl of %ds", s.recordTTL, linodeTTL(s.recordTTL))
+	}
+
+	// Create mode requires the journal of created records even if reconciliation of
+	// the tracked records is not enabled.
+	if s.recordMode == RecordModeCreate && !s.trackRecords {
+		s.tracker = newRecordTracker(s.Name())
+		klog.Info("create record mode enabled: present will never update existing records")
+	}
+
+	if err = s.watchSettings(stopCh); err != nil {
+		return err
+	}
+
+	if s.trackRecords {
+		s.tracker = newRecordTracker(s.Name())
+		if interval := s.currentReconcileInterval(); interval > 0 {
+			klog.Infof("record tracking enabled: reconciling tracked records every %s", interval)
+		} else {
+			klog.Info("record tracking enabled without periodic reconciliation")
+		}
+
+		// The loop also runs without an interval in case one is set at runtime.
+		if s.reconcileEvery > 0 || s.settingsFile != "" {
+			go s.reconcileLoop(stopCh)
+		}
+
+		if s.dedupeRecords {
+			klog.Info("duplicate TXT records in the zones of tracked records will be removed on reconcile")
+		}
+
+		if s.purge, err = newPurgePolicy(s.purgePatterns, s.purgeMinAge, s.purgeForeignRecords); err != nil {
+			return err
+		}
+
+		if s.purge != nil {
+			action := "reported"
+			if s.purgeForeignRecords {
+				action = "deleted"
+			}
+			klog.Infof("foreign challenge records matching %q older than %s will be %s on reconcile", s.purgePatterns, s.purgeMinAge, action)
+		}
+	} else if s.dedupeRecords || len(s.purgePatterns) > 0 {
+		klog.Warning("ignoring --dedupe-records and --purge-patterns: zones are only cleaned when --track-records is enabled")
+	}
+
+	if s.watchdog = newWatchdog(s.failureLimit, s.failureCooldown, s.emitUnhealthyEvent); s.watchdog != nil {
+		klog.Infof("webhook will be marked unready after %d consecutive linode API failures", s.failureLimit)
+	}
+
+	if s.hygiene = newZoneHygiene(s.Name(), s.maxZoneRecords, s.maxChallengeRecords); s.hygiene != nil {
+		klog.Infof("zone hygiene checks enabled: warning when zones exceed %d records or %d challenge records (0 is unlimited)", s.maxZoneRecords, s.maxChallengeRecords)
+	}
+
+	if s.delegationCheck {
+		s.delegation = &SelfCheck{}
+		klog.Info("delegation check enabled: challenges for zones that are not delegated to linode will fail")
+	}
+
+	if s.auditFile != "" {
+		s.audit = NewAuditLog(s.auditFile, s.auditMaxSize, s.auditMaxBackups)
+		klog.Infof("writing audit log of challenge operations and DNS mutations to %s", s.auditFile)
+	}
+
+	if s.cloudEvents = newCloudEventSink(s.cloudEventsSink, s.cloudEventsSource, nil, stopCh); s.cloudEvents != nil {
+		klog.Infof("posting challenge lifecycle cloudevents to %s", s.cloudEventsSink)
+	}
+
+	if s.presents = newPresentCache(s.Name(), s.presentCacheTTL); s.presents != nil {
+		klog.Infof("repeated presents of the same challenge within %s will skip the linode API", s.presentCacheTTL)
+	}
+
+	if s.httpClient == nil {
+		var transport *http.Transport
+		if transport, err = linodeTransport(s.transportOptions); err != nil {
+			return err
+		}
+		s.clients = newClientPool(s.Name(), transport)
+		s.childTokens = newChildTokenCache(s.Name())
+		s.oauthTokens = newOAuthTokenCache(s.Name())
+
+		if s.transportOptions.caFile != "" {
+			klog.Infof("the linode API client trusts the CA certificates in %s", s.transportOptions.caFile)
+		}
+	} else if s.transportOptions != (transportOptions{}) {
+		klog.Warningf("ignoring --linode-ca-file and the linode transport flags: the linode API requests are sent with the provided http client")
+	}
+
+	if s.zones = newZoneCache(s.Name(), s.zoneCacheTTL); s.zones != nil {
+		klog.Infof("the linode domains of zones will be cached for %s", s.zoneCacheTTL)
+	}
+
+	resizeCaches(s.cacheSize)
+
+	// Detect the namespace at startup so that it is logged before any challenges.
+	s.PodNamespace()
+
+	if s.singleNamespace {
+		klog.Infof("single namespace mode enabled: linode API token secrets will only be read from namespace %q", s.PodNamespace())
+	}
+
+	// Linode and Kubernetes requests in flight are canceled when the webhook stops.
+	s.ctx = stopContext(stopCh)
+
+	if s.verifyTokenScope {
+		s.verifiedTokens = newLRU[string, time.Time](s.Name(), "verifiedTokens", DefaultCacheSize, nil)
+		klog.Info("verifying the scopes of linode API tokens before they are used")
+	}
+
+	if s.credentialCheckEvery > 0 {
+		s.credentialHealth = newCredentialHealth(s.Name(), s.credentialWarning)
+		klog.Infof("checking the health of linode API tokens every %s", s.credentialCheckEvery)
+
+		// Register the default secret, if it exists, so that it is checked before it is
+		// used by a challenge; other secrets are registered when they are first used.
+		if _, err := s.getSecret(s.SecretKeyRef(), s.PodNamespace()); err != nil {
+			klog.V(2).Infof("default linode API token secret is not available for health checks: %v", err)
+		}
+		go s.credentialHealthLoop(s.credentialCheckEvery, stopCh)
+	}
+
+	s.initialized.Store(true)
+	return nil
+}
+
+// Ready returns an error if the solver is not yet able to handle challenge requests.
+func (s *LinodeDNSProviderSolver) Ready() error {
+	if !s.initialized.Load() {
+		return ErrNotInitialized
+	}
+	return s.watchdog.healthy()
 }
 
 // DomainEntry is a small helper function that decodes the entry and domain into a
-// string format that is recognized by the Linode DNS provider.
+// string format that is recognized by the Linode DNS provider. Internationalized names
+// are converted to their lowercased ASCII (punycode) form, which is how Linode stores
+// the names of zones and records.
 func DomainEntry(fqdn, zone string) (entry string, domain string) {
+	// The Linode API expects the domain to not have a trailing dot
+	domain = asciiName(zone)
+
 	// Strip the zone from the fqdn to get the record name (subdomain)
-	entry = strings.TrimSuffix(fqdn, zone)
+	entry = strings.TrimSuffix(asciiName(fqdn), domain)
 	entry = strings.TrimSuffix(entry, ".") // Trim trailing dot if present
 
-	// The Linode API expects the domain to not have a trailing dot
-	domain = strings.TrimSuffix(zone, ".")
-
 	return entry, domain
 }
 
@@ -214,32 +809,52 @@ func DomainEntry(fqdn, zone string) (entry string, domain string) {
 // Kubernetes and Linode Interactions
 //===========================================================================
 
+// PodNamespace returns the namespace the webhook is running in. The namespace is
+// detected once, in order of precedence, from:
+//
+//  1. the --namespace flag
+//  2. the POD_NAMESPACE environment variable
+//  3. the file specified by --namespace-file (e.g. mounted by the downward API)
+//  4. the namespace file of the pod's service account
+//
+// If the namespace cannot be detected then "default" is used.
 func (s *LinodeDNSProviderSolver) PodNamespace() string {
-	// The namespace the webhook is running in is available via the
-	// POD_NAMESPACE environment variable or read from the pod configuration.
-	if s.namespace == "" {
-		// First lookup namespace from the environment variable.
-		if s.namespace = os.Getenv("POD_NAMESPACE"); s.namespace == "" {
-			// Fallback to reading the namespace from the pod configuration.
-			data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
-			if err != nil {
-				klog.Errorf("failed to read pod namespace: %v", err)
-			} else {
-				s.namespace = string(data)
-			}
+	s.namespaceOnce.Do(func() {
+		var source string
+		if s.namespace, source = s.detectNamespace(); s.namespace == "" {
+			klog.Error("could not detect the webhook pod namespace, using the default namespace")
+			s.namespace, source = "default", "default"
 		}
+		klog.Infof("webhook pod namespace is %q (from %s)", s.namespace, source)
+	})
+	return s.namespace
+}
 
-		// Trim any whitespace from the namespace.
-		s.namespace = strings.TrimSpace(s.namespace)
+func (s *LinodeDNSProviderSolver) detectNamespace() (namespace, source string) {
+	if namespace = strings.TrimSpace(s.namespace); namespace != "" {
+		return namespace, "--namespace"
 	}
 
-	// Second check to make sure we have a valid namespace.
-	if s.namespace == "" {
-		klog.Error("invalid webhook pod namespace provided")
-		return "default"
+	if namespace = strings.TrimSpace(os.Getenv("POD_NAMESPACE")); namespace != "" {
+		return namespace, "$POD_NAMESPACE"
 	}
 
-	return s.namespace
+	for _, path := range []string{s.namespaceFile, ServiceAccountNamespaceFile} {
+		if path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			klog.Warningf("failed to read pod namespace: %v", err)
+			continue
+		}
+
+		if namespace = strings.TrimSpace(string(data)); namespace != "" {
+			return namespace, path
+		}
+	}
+	return "", ""
 }
 
 func (s *LinodeDNSProviderSolver) SecretKeyRef() cmmeta.SecretKeySelector {
@@ -265,29 +880,166 @@ func (s *LinodeDNSProviderSolver) SecretKeyRef() cmmeta.SecretKeySelector {
 }
 
 func (s *LinodeDNSProviderSolver) LinodeClient(ch *v1alpha1.ChallengeRequest) (_ *Linode, err error) {
-	// Load the solver configuration for this ChallengeRequest
-	var cfg LinodeDNSProviderConfig
-	if cfg, err = LoadConfig(ch.Config); err != nil {
+	var linode *Linode
+	if linode, _, err = s.linodeClient(ch); err != nil {
 		return nil, err
 	}
+	return linode, nil
+}
 
-	// Extract the Linode API key from the referenced Secret resource
+// Returns the Linode client for the ChallengeRequest along with the solver config
+// that it was configured from.
+func (s *LinodeDNSProviderSolver) linodeClient(ch *v1alpha1.ChallengeRequest) (_ *Linode, cfg LinodeDNSProviderConfig, err error) {
 	var apiKey string
-	if apiKey, err = s.GetAPIKey(cfg.APIKeySecretRef, ch.ResourceNamespace); err != nil {
-		return nil, err
+	if apiKey, cfg, err = s.linodeConfig(ch); err != nil {
+		return nil, cfg, err
+	}
+
+	// Create and return the client configured with the issuer's tuning; a new client
+	// is created for every request so its lookups are memoized for the request, but
+	// the connections of the token are pooled unless the http client was provided.
+	linode := NewLinodeWithHTTPClient(apiKey, s.linodeHTTPClient(apiKey)).
+		Memoize().
+		SetRetryPolicy(s.retryPolicy).
+		SetTTL(cfg.TTLSeconds).
+		SetTimeout(cfg.ReadTimeout()).
+		SetWriteTimeout(cfg.WriteTimeout()).
+		SetAdaptiveTimeouts(s.features.Enabled(FeatureAdaptiveTimeouts)).
+		SetConfirmEvents(s.currentConfirmEvents()).
+		SetAuditLog(s.audit).
+		SetDomainCache(s.domains).
+		SetZoneCache(s.zones).
+		SetBaseURL(cfg.APIURL).
+		SetDomainSelector(cfg.DomainIDs, cfg.DomainTag).
+		SetRateLimit(cfg.APIQPS, cfg.APIBurst).
+		SetContext(s.ctx).
+		SetDebug(s.linodeDebug).
+		SetUserAgentSuffix(s.userAgentSuffix)
+	if cfg.MaxRetries != nil {
+		linode.SetMaxRetries(*cfg.MaxRetries)
 	}
 
-	// Create and return the client
-	return NewLinode(apiKey), nil
+	if err = s.checkTokenScope(linode, apiKey); err != nil {
+		return nil, cfg, err
+	}
+	return linode, cfg, nil
+}
+
+// Loads the solver configuration of the challenge and the Linode API key it refers
+// to, applying the tuning of its zone route and the defaults of the solver.
+func (s *LinodeDNSProviderSolver) linodeConfig(ch *v1alpha1.ChallengeRequest) (apiKey string, cfg LinodeDNSProviderConfig, err error) {
+	// Load the solver configuration for this ChallengeRequest
+	if cfg, err = s.loadConfig(ch.Config); err != nil {
+		return "", cfg, err
+	}
+
+	if !s.allowsZone(ch.ResolvedFQDN) {
+		return "", cfg, fmt.Errorf("%w: solver %s does not solve challenges for %s", ErrZoneNotAllowed, s.Name(), ch.ResolvedFQDN)
+	}
+
+	// Extract the Linode API key from the referenced Secret resource; the issuer's
+	// secret takes precedence over the zone routes, which take precedence over the
+	// default secret in the webhook's namespace.
+	if cfg.APIKeySecretSelector != nil {
+		if apiKey, err = s.getSecretBySelector(*cfg.APIKeySecretSelector, ch.ResourceNamespace); err != nil {
+			return "", cfg, err
+		}
+	} else if cfg.OAuth != nil {
+		if apiKey, err = s.oauthToken(cfg.OAuth, ch.ResourceNamespace); err != nil {
+			return "", cfg, err
+		}
+	} else if route := s.routes.Load().Match(ch.ResolvedFQDN); route != nil && cfg.APIKeySecretRef.LocalObjectReference.Name == "" {
+		klog.V(2).Infof("using zone route %q for challenge %s", route.Zone, ch.ResolvedFQDN)
+		if route.OAuth != nil {
+			apiKey, err = s.oauthToken(route.OAuth, route.SecretNamespace(s.PodNamespace()))
+		} else {
+			apiKey, err = s.getSecret(route.APIKeySecretRef, route.SecretNamespace(s.PodNamespace()))
+		}
+
+		if err != nil {
+			return "", cfg, err
+		}
+
+		// The route's tuning applies unless it is overridden by the issuer.
+		cfg = cfg.WithDefaults(route.LinodeDNSProviderConfig)
+	} else if apiKey, err = s.GetAPIKey(cfg.APIKeySecretRef, ch.ResourceNamespace); err != nil {
+		return "", cfg, err
+	}
+
+	// The runtime settings provide the defaults for tuning the issuer has not set.
+	cfg = cfg.WithDefaults(LinodeDNSProviderConfig{
+		APIURL:              s.apiURL,
+		PropagationCheck:    s.settings().PropagationCheck,
+		TTLSeconds:          s.recordTTL,
+		TimeoutSeconds:      timeoutSeconds(s.linodeTimeout),
+		ReadTimeoutSeconds:  timeoutSeconds(s.linodeReadTimeout),
+		WriteTimeoutSeconds: timeoutSeconds(s.linodeWriteTimeout),
+		APIQPS:              s.linodeQPS,
+		APIBurst:            s.linodeBurst,
+	})
+
+	// The domainID or zoneName may be a default of a zone route, so the merged config
+	// is checked again before the CNAME of the challenge is resolved.
+	if err = cfg.validateFollowCNAME(); err != nil {
+		return "", cfg, err
+	}
+
+	// Issuers of a child account authenticate with its proxy token instead.
+	if cfg.ChildAccount != "" {
+		if apiKey, err = s.childAccountToken(apiKey, cfg); err != nil {
+			return "", cfg, err
+		}
+	}
+	return apiKey, cfg, nil
+}
+
+// loadConfig decodes the solver configuration and rejects any configuration that is
+// not permitted by the mode the webhook is running in.
+func (s *LinodeDNSProviderSolver) loadConfig(data *extapi.JSON) (cfg LinodeDNSProviderConfig, err error) {
+	if cfg, err = LoadConfig(data); err != nil {
+		return cfg, err
+	}
+
+	// The token would be sent to the apiURL, which may not be the credentials of the
+	// issuer if they fall back to the webhook's secret or a zone route.
+	if cfg.APIURL != "" && !slices.Contains(s.allowedAPIURLs, cfg.APIURL) {
+		return cfg, fmt.Errorf("%w: %s", ErrAPIURLNotAllowed, cfg.APIURL)
+	}
+
+	// Likewise the token of the webhook or a zone route may not be exchanged for the
+	// token of a child account that the issuer chooses.
+	if cfg.ChildAccount != "" && !cfg.hasCredentials() {
+		return cfg, ErrChildAccountNotAllowed
+	}
+
+	if s.singleNamespace && (cfg.APIKeySecretRef.LocalObjectReference.Name != "" || cfg.APIKeySecretRef.Key != "" || cfg.APIKeySecretSelector != nil || cfg.OAuth != nil) {
+		return cfg, ErrSecretRefNotAllowed
+	}
+
+	return cfg, nil
 }
 
 // GetAPIKey retrieves the Linode API key from the referenced Secret resource.
 func (s *LinodeDNSProviderSolver) GetAPIKey(secretRef cmmeta.SecretKeySelector, namespace string) (token string, err error) {
+	// In single namespace mode only the webhook's namespace is ever read from so that
+	// the RBAC permissions can be limited to a namespaced Role.
+	if s.singleNamespace {
+		return s.getSecret(s.SecretKeyRef(), s.PodNamespace())
+	}
+
 	// Get token from secret in the same namespace as the certificate if possible.
 	if token, err = s.getSecret(secretRef, namespace); err == nil {
 		return token, nil
 	}
 
+	// Look for the tenant's own credentials in the namespace by convention if enabled.
+	if s.namespaceCredentials {
+		klog.V(2).Infof("could not use issuer linode API token secret: %v", err)
+		if token, err = s.getNamespaceCredentials(namespace); err == nil {
+			return token, nil
+		}
+	}
+
 	// Fallback to getting the secret from the webhook's namespace.
 	klog.Warningf("failed to find certificate namespace linode API token secret: %v", err)
 	klog.Info("falling back to webhook namespace for linode API token secret")
@@ -306,12 +1058,80 @@ func (s *LinodeDNSProviderSolver) getSecret(secretRef cmmeta.SecretKeySelector,
 	// Get the secret
 	var secret *k8sapiv1.Secret
 	if secret, err = s.k8s.CoreV1().Secrets(namespace).Get(s.ctx, secretRef.LocalObjectReference.Name, k8smetav1.GetOptions{}); err != nil {
-		return "", fmt.Errorf("failed to get secret %q in namespace %q: %v", secretRef.LocalObjectReference.Name, namespace, err)
+		return "", fmt.Errorf("failed to get secret %q in namespace %q: %w", secretRef.LocalObjectReference.Name, namespace, err)
 	}
 
 	// Extract token from secret
 	if token, ok := secret.Data[secretRef.Key]; ok {
+		s.credentialHealth.observe(secretRef, namespace)
+		s.clients.observe(secretRef, namespace, string(token))
 		return string(token), nil
 	}
 	return "", fmt.Errorf("key %q not found in secret %s/%s", secretRef.Key, namespace, secretRef.LocalObjectReference.Name)
 }
+
+// Retrieves the Linode API key from the tenant's credentials in the namespace: first the
+// secret with the default token secret name, then the only secret annotated with the
+// AnnotationCredentials annotation, so that tenants can supply their own tokens without
+// editing shared Issuer resources.
+func (s *LinodeDNSProviderSolver) getNamespaceCredentials(namespace string) (token string, err error) {
+	if token, err = s.getSecret(s.SecretKeyRef(), namespace); err == nil {
+		return token, nil
+	}
+
+	var secrets *k8sapiv1.SecretList
+	if secrets, err = s.k8s.CoreV1().Secrets(namespace).List(s.ctx, k8smetav1.ListOptions{}); err != nil {
+		return "", fmt.Errorf("failed to list secrets in namespace %q: %w", namespace, err)
+	}
+
+	var annotated []k8sapiv1.Secret
+	for _, secret := range secrets.Items {
+		if _, ok := secret.Annotations[AnnotationCredentials]; ok {
+			annotated = append(annotated, secret)
+		}
+	}
+
+	if len(annotated) != 1 {
+		return "", fmt.Errorf("%d secrets in namespace %q have the %s annotation, expected exactly one", len(annotated), namespace, AnnotationCredentials)
+	}
+
+	ref := cmmeta.SecretKeySelector{
+		LocalObjectReference: cmmeta.LocalObjectReference{Name: annotated[0].Name},
+		Key:                  annotated[0].Annotations[AnnotationCredentials],
+	}
+
+	if ref.Key == "" {
+		ref.Key = s.SecretKeyRef().Key
+	}
+
+	klog.V(2).Infof("using linode API token secret %s/%s annotated with %s", namespace, ref.Name, AnnotationCredentials)
+	return s.getSecret(ref, namespace)
+}
+
+// Retrieves the Linode API key from the only Secret in the namespace that matches the
+// label selector, returning ErrSecretSelector if none or more than one secret matches.
+func (s *LinodeDNSProviderSolver) getSecretBySelector(selector SecretKeyLabelSelector, namespace string) (_ string, err error) {
+	var labels string
+	if labels, err = selector.String(); err != nil {
+		return "", err
+	}
+
+	var secrets *k8sapiv1.SecretList
+	if secrets, err = s.k8s.CoreV1().Secrets(namespace).List(s.ctx, k8smetav1.ListOptions{LabelSelector: labels}); err != nil {
+		return "", fmt.Errorf("failed to list secrets matching %q in namespace %q: %w", labels, namespace, err)
+	}
+
+	if n := len(secrets.Items); n != 1 {
+		return "", fmt.Errorf("%w: %d secrets match %q in namespace %q", ErrSecretSelector, n, labels, namespace)
+	}
+
+	secret := secrets.Items[0]
+	if token, ok := secret.Data[selector.Key]; ok {
+		klog.V(2).Infof("using linode API token secret %s/%s selected by %q", namespace, secret.Name, labels)
+		ref := cmmeta.SecretKeySelector{LocalObjectReference: cmmeta.LocalObjectReference{Name: secret.Name}, Key: selector.Key}
+		s.credentialHealth.observe(ref, namespace)
+		s.clients.observe(ref, namespace, string(token))
+		return string(token), nil
+	}
+	return "", fmt.Errorf("key %q not found in secret %s/%s", selector.Key, namespace, secret.Name)
+}
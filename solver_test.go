@@ -3,9 +3,12 @@ package acme_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	acmetest "github.com/cert-manager/cert-manager/test/acme"
+	"github.com/linode/linodego"
 	"go.rtnl.ai/acme-linode"
+	"go.rtnl.ai/acme-linode/linodetest"
 )
 
 var (
@@ -25,6 +28,14 @@ func TestRunsSuite(t *testing.T) {
 	// snippet of valid configuration that should be included on the
 	// ChallengeRequest passed as part of the test cases.
 
+	// Restore the records of the zone after the suite if a (plain text) token is
+	// available, so that the suite can be run against a zone shared with other records.
+	if token := os.Getenv("TEST_LINODE_TOKEN"); token != "" {
+		client := linodego.NewClient(nil)
+		client.SetToken(token)
+		linodetest.SnapshotZone(t, &client, zone)
+	}
+
 	solver := &acme.LinodeDNSProviderSolver{}
 	fixture := acmetest.NewFixture(solver,
 		acmetest.SetResolvedZone(zone),
@@ -39,7 +50,50 @@ func TestRunsSuite(t *testing.T) {
 	fixture.RunConformance(t)
 }
 
+// Runs the conformance suite hermetically against the in-memory fake of the Linode
+// API with a local authoritative DNS server that serves the fake's records.
+func TestRunsSuiteFake(t *testing.T) {
+	if !testAssetsExist() {
+		t.Skip("Skipping hermetic conformance tests as kubebuilder test assets were not found")
+	}
+
+	fake := linodetest.New()
+	defer fake.Close()
+	fake.AddDomain("example.com")
+
+	dns, err := linodetest.NewDNSServer(fake)
+	if err != nil {
+		t.Fatalf("could not start fake dns server: %v", err)
+	}
+	defer dns.Close()
+
+	// The linodego client will use the fake server as its base URL.
+	t.Setenv("LINODE_URL", fake.URL())
+
+	solver := &acme.LinodeDNSProviderSolver{}
+	fixture := acmetest.NewFixture(solver,
+		acmetest.SetResolvedZone("example.com."),
+		acmetest.SetAllowAmbientCredentials(false),
+		acmetest.SetManifestPath("testdata/fake"),
+		acmetest.SetDNSServer(dns.Addr()),
+		acmetest.SetUseAuthoritative(false),
+		acmetest.SetPollInterval(100*time.Millisecond),
+		acmetest.SetPropagationLimit(10*time.Second),
+	)
+
+	fixture.RunConformance(t)
+}
+
 func secretExists() bool {
 	_, err := os.Stat("testdata/linode/secret.yaml")
 	return err == nil
 }
+
+func testAssetsExist() bool {
+	for _, env := range []string{"TEST_ASSET_ETCD", "TEST_ASSET_KUBE_APISERVER", "TEST_ASSET_KUBECTL"} {
+		if _, err := os.Stat(os.Getenv(env)); err != nil {
+			return false
+		}
+	}
+	return true
+}
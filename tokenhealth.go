@@ -0,0 +1,241 @@
+package acme
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+const DefaultCredentialExpiryWarning = 14 * 24 * time.Hour
+
+// Expiry warning events are emitted at most this often for each credential.
+var CredentialWarningInterval = 24 * time.Hour
+
+// The scopes of a token are verified again after this long, e.g. in case the token
+// was replaced by one with the same prefix.
+var TokenScopeTTL = time.Hour
+
+// The scope that a Linode API token requires to solve challenges.
+const DomainsScope = "domains:read_write"
+
+// TokenInfo describes the personal access token that authenticates a Linode client.
+type TokenInfo struct {
+	Label  string     `json:"label,omitempty"`
+	Scopes string     `json:"scopes,omitempty"`
+	Expiry *time.Time `json:"expiry,omitempty"`
+}
+
+// CheckToken verifies that the Linode API accepts the token used by the client and
+// looks it up in the personal access tokens of the profile to report its expiry. The
+// token is matched by the prefix that the API lists; the returned info is nil if the
+// token is not listed (e.g. an OAuth token) or the token may not list the tokens of
+// the profile, since the expiry is then unavailable rather than an error.
+func (l *Linode) CheckToken(token string) (info *TokenInfo, err error) {
+	ctx, cancel := l.context()
+	defer cancel()
+
+	if _, err = l.client.GetProfile(ctx); err != nil {
+		return nil, apiError(err)
+	}
+
+	var tokens []linodego.Token
+	if tokens, err = l.client.ListTokens(ctx, nil); err != nil {
+		if linodego.ErrHasStatus(err, http.StatusUnauthorized, http.StatusForbidden) {
+			klog.V(2).Infof("token may not list the personal access tokens of the profile, expiry is unknown: %v", err)
+			return nil, nil
+		}
+		return nil, apiError(err)
+	}
+
+	for _, t := range tokens {
+		if t.Token != "" && strings.HasPrefix(token, t.Token) {
+			return &TokenInfo{Label: t.Label, Scopes: t.Scopes, Expiry: t.Expiry}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns true if the space or comma separated scopes of a token include the scope, or
+// all scopes (*).
+func hasScope(scopes, scope string) bool {
+	for _, s := range strings.FieldsFunc(scopes, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if s == "*" || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifies that the token of the client has the domains:read_write scope if token
+// scopes are verified, so that a token with the wrong scopes fails with a clear error
+// before Present rather than with a forbidden error part way through it. Verified
+// tokens are remembered by account key for TokenScopeTTL. Tokens that are not listed
+// in the profile (e.g. OAuth tokens or tokens that may not list the profile tokens)
+// cannot be verified and are allowed.
+func (s *LinodeDNSProviderSolver) checkTokenScope(linode *Linode, apiKey string) (err error) {
+	if s.verifiedTokens == nil {
+		return nil
+	}
+
+	account := accountKey(apiKey)
+	if verified, ok := s.verifiedTokens.Get(account); ok && time.Since(verified) < TokenScopeTTL {
+		return nil
+	}
+
+	var info *TokenInfo
+	if info, err = linode.CheckToken(apiKey); err != nil {
+		return err
+	}
+
+	if info == nil {
+		klog.V(2).Info("the linode API token is not listed in its profile, so its scopes cannot be verified")
+	} else if !hasScope(info.Scopes, DomainsScope) {
+		return fmt.Errorf("%w: token %q has the scopes %q", ErrTokenScope, info.Label, info.Scopes)
+	}
+
+	s.verifiedTokens.Add(account, time.Now())
+	return nil
+}
+
+// Credential health checks periodically validate every Linode API token the solver
+// has used (identified by the secret that holds it, e.g. the default secret, the zone
+// routes, or the secrets referenced by issuers) so that revoked or expiring tokens are
+// noticed and rotated before issuance breaks. The token itself is never retained;
+// the secret is read again for every check.
+type credentialHealth struct {
+	sync.Mutex
+	warnBefore  time.Duration
+	credentials *lru[string, *CredentialHealth]
+}
+
+// CredentialHealth reports the last health check of the token held by a secret.
+type CredentialHealth struct {
+	Secret        string     `json:"secret"`
+	Valid         bool       `json:"valid"`
+	Token         *TokenInfo `json:"token,omitempty"`
+	ExpiresInDays *int       `json:"expiresInDays,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	LastChecked   time.Time  `json:"lastChecked,omitzero"`
+
+	namespace  string
+	ref        cmmeta.SecretKeySelector
+	lastWarned time.Time
+}
+
+func newCredentialHealth(solver string, warnBefore time.Duration) *credentialHealth {
+	return &credentialHealth{
+		warnBefore:  warnBefore,
+		credentials: newLRU[string, *CredentialHealth](solver, "credentialHealth", DefaultCacheSize, nil),
+	}
+}
+
+// Registers the secret holding a token so that it is included in the health checks;
+// a nil checker is a no-op.
+func (c *credentialHealth) observe(ref cmmeta.SecretKeySelector, namespace string) {
+	if c == nil {
+		return
+	}
+
+	key := fmt.Sprintf("%s/%s[%s]", namespace, ref.LocalObjectReference.Name, ref.Key)
+	c.credentials.GetOrAdd(key, func() *CredentialHealth {
+		return &CredentialHealth{Secret: key, namespace: namespace, ref: ref}
+	})
+}
+
+// Stats returns the last health check of every known credential; a nil checker
+// returns nil.
+func (c *credentialHealth) Stats() []CredentialHealth {
+	if c == nil {
+		return nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	credentials := c.credentials.Values()
+	stats := make([]CredentialHealth, 0, len(credentials))
+	for _, credential := range credentials {
+		stats = append(stats, *credential)
+	}
+	return stats
+}
+
+// Runs the credential health checks every interval until the stop channel is closed.
+func (s *LinodeDNSProviderSolver) credentialHealthLoop(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.checkCredentials()
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Checks the token held by every known secret, logging and emitting warning events
+// for tokens that are rejected by the Linode API or expire within the warning period.
+func (s *LinodeDNSProviderSolver) checkCredentials() {
+	health := s.credentialHealth
+	if health == nil {
+		return
+	}
+
+	for _, credential := range health.credentials.Values() {
+		token, err := s.getSecret(credential.ref, credential.namespace)
+
+		var info *TokenInfo
+		if err == nil {
+			info, err = NewLinodeWithHTTPClient(token, s.linodeHTTPClient(token)).SetRetryPolicy(s.retryPolicy).SetContext(s.ctx).SetUserAgentSuffix(s.userAgentSuffix).CheckToken(token)
+		}
+
+		health.Lock()
+		credential.LastChecked = time.Now()
+		credential.Valid = err == nil
+		credential.Token = info
+		credential.ExpiresInDays = nil
+		credential.Error = ""
+
+		var warning string
+		switch {
+		case err != nil:
+			credential.Error = err.Error()
+			warning = fmt.Sprintf("Linode API token in secret %s failed its health check: %v", credential.Secret, err)
+		case info != nil && info.Expiry != nil:
+			remaining := time.Until(*info.Expiry)
+			days := int(remaining.Hours() / 24)
+			credential.ExpiresInDays = &days
+
+			if remaining < health.warnBefore {
+				warning = fmt.Sprintf("Linode API token %q in secret %s expires in %d days (%s); rotate it before issuance breaks", info.Label, credential.Secret, days, info.Expiry.Format(time.RFC3339))
+			}
+		}
+
+		warn := warning != "" && time.Since(credential.lastWarned) >= CredentialWarningInterval
+		if warn {
+			credential.lastWarned = time.Now()
+		} else if warning == "" {
+			credential.lastWarned = time.Time{}
+		}
+		health.Unlock()
+
+		if warning == "" {
+			klog.V(2).Infof("linode API token in secret %s is healthy", credential.Secret)
+			continue
+		}
+
+		klog.Warning(warning)
+		if warn {
+			s.emitPodEvent("LinodeCredentialUnhealthy", warning)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCredentialHealth(t *testing.T) {
+	t.Setenv("POD_NAME", "acme-linode-0")
+	solver, fake := newTestSolver(t)
+	solver.credentialHealth = newCredentialHealth("test", DefaultCredentialExpiryWarning)
+	defer caches.Delete(cacheKey{solver: "test", name: "credentialHealth"})
+
+	expiry := time.Now().Add(72 * time.Hour)
+	fake.AddToken(linodego.Token{Label: "cert-manager", Scopes: "domains:read_write", Token: "linodetest", Expiry: &expiry})
+	fake.AddDomain("example.com")
+
+	// Secrets are registered for health checks when they are used by a challenge.
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	check := func() CredentialHealth {
+		t.Helper()
+		solver.checkCredentials()
+
+		stats := solver.credentialHealth.Stats()
+		if len(stats) != 1 || stats[0].Secret != "default/linode-credentials[token]" {
+			t.Fatalf("unexpected credential health %+v", stats)
+		}
+		return stats[0]
+	}
+
+	// Tokens that expire within the warning period warn once per warning interval.
+	for range 2 {
+		if health := check(); !health.Valid || health.ExpiresInDays == nil || *health.ExpiresInDays != 2 || health.Token.Label != "cert-manager" {
+			t.Errorf("unexpected credential health %+v", health)
+		}
+	}
+
+	if n := podEvents(t, solver, "LinodeCredentialUnhealthy"); n != 1 {
+		t.Errorf("expected one warning event, got %d", n)
+	}
+
+	// Tokens that may not list the profile tokens are healthy with an unknown expiry.
+	fake.Fail("GET /v4/profile/tokens", linodetest.Fault{Status: http.StatusForbidden})
+	if health := check(); !health.Valid || health.Token != nil || health.ExpiresInDays != nil {
+		t.Errorf("unexpected credential health %+v", health)
+	}
+
+	// Tokens rejected by the Linode API are unhealthy and warn again since the token
+	// was healthy in between.
+	fake.Fail("GET /v4/profile", linodetest.Fault{Status: http.StatusUnauthorized})
+	if health := check(); health.Valid || health.Error == "" {
+		t.Errorf("unexpected credential health %+v", health)
+	}
+
+	if n := podEvents(t, solver, "LinodeCredentialUnhealthy"); n != 2 {
+		t.Errorf("expected two warning events, got %d", n)
+	}
+}
+
+// Counts the events with the reason that target the webhook pod.
+func podEvents(t *testing.T, solver *LinodeDNSProviderSolver, reason string) (count int) {
+	t.Helper()
+	events, err := solver.k8s.CoreV1().Events("default").List(context.Background(), k8smetav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("could not list events: %v", err)
+	}
+
+	for _, event := range events.Items {
+		if event.Reason == reason && event.InvolvedObject.Name == "acme-linode-0" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCheckTokenScope(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.verifiedTokens = newLRU[string, time.Time]("test", "verifiedTokens", DefaultCacheSize, nil)
+	defer caches.Delete(cacheKey{solver: "linode", name: "verifiedTokens"})
+
+	fake.AddDomain("example.com")
+	token := fake.AddToken(linodego.Token{Label: "cert-manager", Scopes: "account:read_only", Token: "linodetest"})
+
+	// Tokens without the domains scope fail before any record is created.
+	ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge.example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: "key1"}
+	if err := solver.Present(ch); !errors.Is(err, ErrTokenScope) {
+		t.Fatalf("expected ErrTokenScope, got %v", err)
+	}
+
+	if calls := fake.Calls("POST /v4/domains/{domainID}/records"); calls != 0 {
+		t.Errorf("expected no records to be created, got %d create requests", calls)
+	}
+
+	// Verified tokens are not verified again until the TTL has passed.
+	fake.RemoveToken(token.ID)
+	fake.AddToken(linodego.Token{Label: "cert-manager", Scopes: "account:read_only domains:read_write", Token: "linodetest"})
+	for range 2 {
+		if err := solver.Present(ch); err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("GET /v4/profile/tokens"); calls != 2 {
+		t.Errorf("expected the verified token to be remembered, got %d token requests", calls)
+	}
+
+	for scopes, expected := range map[string]bool{"*": true, "domains:read_write,linodes:read_only": true, "domains:read_only": false, "": false} {
+		if hasScope(scopes, DomainsScope) != expected {
+			t.Errorf("expected hasScope(%q) to be %t", scopes, expected)
+		}
+	}
+}
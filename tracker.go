@@ -0,0 +1,287 @@
+package acme
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+const DefaultReconcileInterval = 5 * time.Minute
+
+// The record tracker is an in-memory journal of the TXT records presented by the
+// solver that have not yet been cleaned up. When tracking is enabled, a periodic
+// reconcile loop compares the journal with the records in Linode, repairing records
+// that are missing for active challenges and reporting untracked leftovers. The
+// journal is bounded by the cache size so that challenges that are never cleaned up
+// cannot grow memory without bound; records that are evicted from a full journal are
+// logged and counted, and are cleaned up by the key of their challenge instead.
+type recordTracker struct {
+	sync.Mutex
+	records *lru[string, *trackedRecord]
+	stats   ReconcileStats
+}
+
+// A TXT record presented for a challenge that has not yet been cleaned up.
+type trackedRecord struct {
+	challenge *v1alpha1.ChallengeRequest
+	zoneID    int
+	recordID  int
+	entry     string
+	presented time.Time
+}
+
+// ReconcileStats reports the drift found by the reconcile loop.
+type ReconcileStats struct {
+	Tracked       int       `json:"tracked"`
+	Evicted       uint64    `json:"evicted"`
+	Reconciles    uint64    `json:"reconciles"`
+	Repaired      uint64    `json:"repaired"`
+	Untracked     uint64    `json:"untracked"`
+	Deduplicated  uint64    `json:"deduplicated"`
+	Foreign       uint64    `json:"foreign"`
+	Purged        uint64    `json:"purged"`
+	Errors        uint64    `json:"errors"`
+	LastReconcile time.Time `json:"lastReconcile"`
+}
+
+func newRecordTracker(solver string) *recordTracker {
+	records := newLRU[string, *trackedRecord](solver, "trackedRecords", DefaultCacheSize, nil)
+	records.evicted = func(_ string, record *trackedRecord) {
+		klog.Warningf("journal of tracked records is full: forgetting TXT record ID %d in zone ID %d for fqdn=%s presented at %s, increase --cache-size", record.recordID, record.zoneID, record.challenge.ResolvedFQDN, record.presented.Format(time.RFC3339))
+	}
+	return &recordTracker{records: records}
+}
+
+// Records the presented TXT record for the challenge; a nil tracker is a no-op.
+func (t *recordTracker) track(ch *v1alpha1.ChallengeRequest, zoneID int, entry string, record *linodego.DomainRecord) {
+	if t == nil {
+		return
+	}
+
+	t.records.Add(challengeKey(ch), &trackedRecord{
+		challenge: ch.DeepCopy(),
+		zoneID:    zoneID,
+		recordID:  record.ID,
+		entry:     entry,
+		presented: time.Now(),
+	})
+}
+
+// Returns the tracked record for the challenge; a nil tracker tracks nothing.
+func (t *recordTracker) lookup(ch *v1alpha1.ChallengeRequest) (*trackedRecord, bool) {
+	if t == nil {
+		return nil, false
+	}
+	return t.records.Get(challengeKey(ch))
+}
+
+// Removes the challenge from the journal once it has been cleaned up.
+func (t *recordTracker) untrack(ch *v1alpha1.ChallengeRequest) {
+	if t == nil {
+		return
+	}
+	t.records.Remove(challengeKey(ch))
+}
+
+// Returns the current reconcile stats; a nil tracker returns nil.
+func (t *recordTracker) Stats() *ReconcileStats {
+	if t == nil {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	stats := t.stats
+	stats.Tracked = t.records.Len()
+	stats.Evicted = t.records.Stats().Evictions
+	return &stats
+}
+
+// Challenges are uniquely identified by the record name and the key since multiple
+// challenges for the same name may be in flight concurrently.
+func challengeKey(ch *v1alpha1.ChallengeRequest) string {
+	return ch.ResolvedFQDN + " " + ch.Key
+}
+
+// Runs the reconcile loop until the stop channel is closed. The interval is read
+// before every run so that changes to the runtime settings take effect; while the
+// interval is zero the settings are checked again after the settings poll interval.
+func (s *LinodeDNSProviderSolver) reconcileLoop(stopCh <-chan struct{}) {
+	for {
+		interval := s.currentReconcileInterval()
+		wait := interval
+		if wait <= 0 {
+			wait = SettingsPollInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if interval > 0 {
+				s.reconcile()
+			}
+		}
+	}
+}
+
+// Compares every tracked record with the records in Linode, recreating any tracked
+// record that is missing and reporting challenge records that are not tracked.
+// Untracked records are only reported and never deleted since they may belong to
+// another webhook or issuer, however with --dedupe-records identical copies of any
+// TXT record in the zones of the tracked records are collapsed into one.
+func (s *LinodeDNSProviderSolver) reconcile() {
+	tracker := s.tracker
+	if tracker == nil {
+		return
+	}
+
+	var repaired, untracked, deduplicated, failures uint64
+	tracked := tracker.records.Values()
+
+	// Group the tracked values by record so that each record name is listed once.
+	type recordName struct {
+		zoneID int
+		entry  string
+	}
+
+	names := make(map[recordName][]*trackedRecord)
+	for _, rec := range tracked {
+		name := recordName{rec.zoneID, rec.entry}
+		names[name] = append(names[name], rec)
+	}
+
+	for name, recs := range names {
+		linode, err := s.LinodeClient(recs[0].challenge)
+		if err != nil {
+			klog.Warningf("reconcile: could not create linode client for %s: %v", recs[0].challenge.ResolvedFQDN, err)
+			s.history.record("reconcile", recs[0].challenge.ResolvedZone, recs[0].challenge.ResolvedFQDN, err)
+			failures++
+			continue
+		}
+
+		// The records are listed and repaired with the zone lock held, so that a record
+		// deleted by a concurrent CleanUp is not recreated from the stale snapshot.
+		unlock := lockZone(name.zoneID)
+		var records []linodego.DomainRecord
+		if records, err = linode.FindRecords(name.zoneID, name.entry); err != nil {
+			unlock()
+			klog.Warningf("reconcile: could not list records %q in zone ID %d: %v", name.entry, name.zoneID, err)
+			s.history.record("reconcile", recs[0].challenge.ResolvedZone, recs[0].challenge.ResolvedFQDN, err)
+			failures++
+			continue
+		}
+
+		targets := make(map[string]bool, len(records))
+		for _, record := range records {
+			targets[record.Target] = true
+		}
+
+		// Repair tracked records that are missing from Linode.
+		keys := make(map[string]bool, len(recs))
+		for _, rec := range recs {
+			keys[rec.challenge.Key] = true
+			if targets[rec.challenge.Key] {
+				continue
+			}
+
+			// The challenge was cleaned up since the snapshot was taken.
+			if current, ok := tracker.lookup(rec.challenge); !ok || current != rec {
+				continue
+			}
+
+			klog.Warningf("reconcile: TXT record %s (ID %d) for active challenge is missing from zone ID %d, recreating it", rec.entry, rec.recordID, rec.zoneID)
+			// The record is created rather than upserted so untracked records are not overwritten.
+			var record *linodego.DomainRecord
+			if record, err = linode.CreateRecord(rec.zoneID, rec.entry, rec.challenge.Key); err != nil {
+				klog.Errorf("reconcile: could not recreate TXT record %s in zone ID %d: %v", rec.entry, rec.zoneID, err)
+				s.history.record("reconcile", rec.challenge.ResolvedZone, rec.challenge.ResolvedFQDN, err)
+				failures++
+				continue
+			}
+
+			tracker.track(rec.challenge, rec.zoneID, rec.entry, record)
+			repaired++
+		}
+		unlock()
+
+		// Report records with the same name that are not tracked by this webhook. The
+		// other TXT records at the apex of a zone are not challenge records.
+		for _, record := range records {
+			if !keys[record.Target] && !isApex(name.entry) {
+				klog.Warningf("reconcile: untracked TXT record %s (ID %d) found in zone ID %d", record.Name, record.ID, name.zoneID)
+				untracked++
+			}
+		}
+	}
+
+	if s.dedupeRecords {
+		var errs uint64
+		deduplicated, errs = s.deduplicate(tracker.records.Values())
+		failures += errs
+	}
+
+	var foreign, purged uint64
+	if s.purge != nil {
+		var errs uint64
+		foreign, purged, errs = s.purgeForeign(tracker.records.Values())
+		failures += errs
+	}
+
+	tracker.Lock()
+	tracker.stats.Reconciles++
+	tracker.stats.Repaired += repaired
+	tracker.stats.Untracked += untracked
+	tracker.stats.Deduplicated += deduplicated
+	tracker.stats.Foreign += foreign
+	tracker.stats.Purged += purged
+	tracker.stats.Errors += failures
+	tracker.stats.LastReconcile = time.Now()
+	tracker.Unlock()
+
+	klog.V(2).Infof("reconciled %d tracked records: %d repaired, %d untracked, %d deduplicated, %d foreign, %d purged, %d errors", len(tracked), repaired, untracked, deduplicated, foreign, purged, failures)
+}
+
+// Deduplicates the TXT records in each zone with a tracked record, keeping the tracked
+// records so that they can still be cleaned up by ID.
+func (s *LinodeDNSProviderSolver) deduplicate(tracked []*trackedRecord) (deduplicated, failures uint64) {
+	failures = s.eachTrackedZone(tracked, "deduplicate TXT records", func(linode *Linode, zoneID int, recs []*trackedRecord) error {
+		keep := make([]int, 0, len(recs))
+		for _, rec := range recs {
+			keep = append(keep, rec.recordID)
+		}
+
+		deleted, err := linode.DeduplicateTXT(zoneID, keep...)
+		deduplicated += uint64(deleted)
+		return err
+	})
+	return deduplicated, failures
+}
+
+// Runs the operation on each zone with a tracked record using the credentials of one
+// of the zone's challenges, returning the number of zones for which it failed.
+func (s *LinodeDNSProviderSolver) eachTrackedZone(tracked []*trackedRecord, operation string, op func(linode *Linode, zoneID int, recs []*trackedRecord) error) (failures uint64) {
+	zones := make(map[int][]*trackedRecord)
+	for _, rec := range tracked {
+		zones[rec.zoneID] = append(zones[rec.zoneID], rec)
+	}
+
+	for zoneID, recs := range zones {
+		linode, err := s.LinodeClient(recs[0].challenge)
+		if err == nil {
+			err = op(linode, zoneID, recs)
+		}
+
+		if err != nil {
+			klog.Warningf("reconcile: could not %s in zone ID %d: %v", operation, zoneID, err)
+			s.history.record("reconcile", recs[0].challenge.ResolvedZone, recs[0].challenge.ResolvedFQDN, err)
+			failures++
+		}
+	}
+	return failures
+}
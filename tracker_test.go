@@ -0,0 +1,205 @@
+package acme
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+	k8sapiv1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReconcile(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.tracker = newRecordTracker("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "trackedRecords"})
+
+	zone := fake.AddDomain("example.com")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	// Tracked records that are missing from Linode are recreated.
+	record := fake.Records(zone.ID)[0]
+	linode, _ := solver.LinodeClient(ch)
+	if err := linode.DeleteRecord(zone.ID, record.ID); err != nil {
+		t.Fatalf("could not delete record: %v", err)
+	}
+
+	// Untracked leftovers are reported but not deleted.
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "leftover"})
+
+	solver.reconcile()
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "leftover", "key1")
+
+	stats := solver.tracker.Stats()
+	if stats.Tracked != 1 || stats.Repaired != 1 || stats.Untracked != 1 || stats.Errors != 0 {
+		t.Errorf("unexpected reconcile stats %+v", stats)
+	}
+
+	// Once cleaned up the record is no longer tracked.
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+
+	if stats = solver.tracker.Stats(); stats.Tracked != 0 {
+		t.Errorf("expected no tracked records after cleanup, got %d", stats.Tracked)
+	}
+}
+
+func TestReconcileCleanedUp(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.tracker = newRecordTracker("test")
+	defer caches.Delete(cacheKey{solver: "test", name: "trackedRecords"})
+
+	zone := fake.AddDomain("example.com")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	if err := solver.Present(ch); err != nil {
+		t.Fatalf("could not present challenge: %v", err)
+	}
+
+	// The reconcile loop takes its snapshot of the tracked records and then waits for
+	// the zone lock, which is held by a CleanUp of the challenge.
+	unlock := lockZone(zone.ID)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		solver.reconcile()
+	}()
+
+	for waiting := false; !waiting; {
+		select {
+		case <-done:
+			unlock()
+			t.Fatal("expected the reconcile loop to wait for the zone lock")
+		default:
+		}
+
+		zoneLocks.Lock()
+		zl, _ := zoneLocks.get(zone.ID)
+		waiting = zl.refs > 1
+		zoneLocks.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	linode, _ := solver.LinodeClient(ch)
+	if err := linode.DeleteTXT(zone.ID, "_acme-challenge", "key1"); err != nil {
+		t.Fatalf("could not delete record: %v", err)
+	}
+	solver.tracker.untrack(ch)
+	unlock()
+	<-done
+
+	// The deleted record is not recreated from the stale snapshot.
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+	if stats := solver.tracker.Stats(); stats.Tracked != 0 || stats.Repaired != 0 {
+		t.Errorf("unexpected reconcile stats %+v", stats)
+	}
+}
+
+// Creates a solver backed by a fake kubernetes clientset holding the default Linode
+// API token secret and a fake of the Linode API.
+func newTestSolver(t *testing.T) (*LinodeDNSProviderSolver, *linodetest.Server) {
+	t.Helper()
+	api := linodetest.New()
+	t.Cleanup(api.Close)
+	t.Setenv("LINODE_URL", api.URL())
+
+	k8s := fake.NewClientset(&k8sapiv1.Secret{
+		ObjectMeta: k8smetav1.ObjectMeta{Name: "linode-credentials", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("linodetest")},
+	})
+
+	// Generate names for created objects, e.g. events, as the API server does.
+	var generated atomic.Int64
+	k8s.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if obj, ok := action.(k8stesting.CreateAction).GetObject().(k8smetav1.Object); ok && obj.GetName() == "" && obj.GetGenerateName() != "" {
+			obj.SetName(obj.GetGenerateName() + strconv.FormatInt(generated.Add(1), 10))
+		}
+		return false, nil, nil
+	})
+
+	solver := &LinodeDNSProviderSolver{k8s: k8s, ctx: context.Background(), namespace: "default"}
+	return solver, api
+}
+
+func TestReconcileDeduplicate(t *testing.T) {
+	solver, fake := newTestSolver(t)
+	solver.tracker = newRecordTracker("test")
+	solver.dedupeRecords = true
+	defer caches.Delete(cacheKey{solver: "test", name: "trackedRecords"})
+
+	zone := fake.AddDomain("example.com")
+	ch := &v1alpha1.ChallengeRequest{
+		ResolvedFQDN:      "_acme-challenge.example.com.",
+		ResolvedZone:      "example.com.",
+		ResourceNamespace: "default",
+		Key:               "key1",
+	}
+
+	// Duplicates created before the tracked record are removed in favor of it.
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge", Target: "key1"})
+	linode, _ := solver.LinodeClient(ch)
+	record, err := linode.CreateRecord(zone.ID, "_acme-challenge", "key1")
+	if err != nil {
+		t.Fatalf("could not create record: %v", err)
+	}
+	solver.tracker.track(ch, zone.ID, "_acme-challenge", record)
+
+	// Identical leftovers for other names are collapsed to the oldest record, while
+	// records with different values and other record types are left untouched.
+	for _, target := range []string{"leftover", "leftover", "leftover", "other"} {
+		fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeTXT, Name: "_acme-challenge.www", Target: target})
+	}
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeA, Name: "www", Target: "192.0.2.1"})
+	fake.AddRecord(zone.ID, linodego.DomainRecord{Type: linodego.RecordTypeA, Name: "www", Target: "192.0.2.1"})
+
+	solver.reconcile()
+	assertTargets(t, fake, zone.ID, "_acme-challenge", "key1")
+	assertTargets(t, fake, zone.ID, "_acme-challenge.www", "leftover", "other")
+
+	var remaining []int
+	for _, r := range fake.Records(zone.ID) {
+		if r.Type == linodego.RecordTypeA {
+			remaining = append(remaining, r.ID)
+		}
+		if r.Name == "_acme-challenge" && r.ID != record.ID {
+			t.Errorf("expected the tracked record %d to be kept, found %d", record.ID, r.ID)
+		}
+	}
+
+	if len(remaining) != 2 {
+		t.Errorf("expected A records to be untouched, found %d", len(remaining))
+	}
+
+	if stats := solver.tracker.Stats(); stats.Deduplicated != 3 || stats.Errors != 0 {
+		t.Errorf("unexpected reconcile stats %+v", stats)
+	}
+
+	// The tracked record can still be cleaned up by ID.
+	if err := solver.CleanUp(ch); err != nil {
+		t.Fatalf("could not clean up challenge: %v", err)
+	}
+	assertTargets(t, fake, zone.ID, "_acme-challenge")
+}
@@ -0,0 +1,148 @@
+package acme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linode/linodego"
+	k8sapiv1 "k8s.io/api/core/v1"
+	k8smetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const DefaultFailureCooldown = 2 * time.Minute
+
+// The watchdog counts consecutive Linode API failures and, once the threshold is
+// reached, reports the webhook as unready so that a broken credential or network path
+// is surfaced by Kubernetes and alerting rather than by endless quiet retries. Since
+// an unready webhook may receive no further challenges, the webhook becomes ready
+// again after the cooldown so that the next challenge can probe Linode; another
+// failure trips the watchdog again immediately. A nil watchdog is always healthy.
+type watchdog struct {
+	sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	lastErr   error
+	trippedAt time.Time
+	onTrip    func(failures int, err error)
+}
+
+func newWatchdog(threshold int, cooldown time.Duration, onTrip func(int, error)) *watchdog {
+	if threshold <= 0 {
+		return nil
+	}
+
+	if cooldown <= 0 {
+		cooldown = DefaultFailureCooldown
+	}
+	return &watchdog{threshold: threshold, cooldown: cooldown, onTrip: onTrip}
+}
+
+// Records the outcome of a Linode operation. Only errors returned by the Linode API
+// or the HTTP client count as failures; other errors do not affect the count.
+func (w *watchdog) observe(err error) {
+	if w == nil {
+		return
+	}
+
+	var lerr *linodego.Error
+	if err != nil && !errors.As(err, &lerr) {
+		return
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if err == nil {
+		if !w.trippedAt.IsZero() {
+			klog.Infof("linode API recovered after %d consecutive failures", w.failures)
+		}
+		w.failures, w.lastErr, w.trippedAt = 0, nil, time.Time{}
+		return
+	}
+
+	w.failures++
+	w.lastErr = err
+
+	// Trip the watchdog when the threshold is reached or on a failed probe after the cooldown.
+	if w.failures >= w.threshold && (w.trippedAt.IsZero() || time.Since(w.trippedAt) >= w.cooldown) {
+		w.trippedAt = time.Now()
+		klog.Errorf("marking webhook unready after %d consecutive linode API failures: %v", w.failures, err)
+		if w.onTrip != nil {
+			go w.onTrip(w.failures, err)
+		}
+	}
+}
+
+// Returns an error if the watchdog has tripped and the cooldown has not elapsed.
+func (w *watchdog) healthy() error {
+	if w == nil {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if w.trippedAt.IsZero() || time.Since(w.trippedAt) >= w.cooldown {
+		return nil
+	}
+	return fmt.Errorf("%w: %d consecutive failures, last error: %v", ErrUnhealthy, w.failures, w.lastErr)
+}
+
+// Returns the number of consecutive failures observed.
+func (w *watchdog) consecutiveFailures() int {
+	if w == nil {
+		return 0
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	return w.failures
+}
+
+// Emits a warning Event targeting the webhook pod when the watchdog trips.
+func (s *LinodeDNSProviderSolver) emitUnhealthyEvent(failures int, err error) {
+	s.emitPodEvent("LinodeAPIUnhealthy", fmt.Sprintf("Marked unready after %d consecutive Linode API failures: %v", failures, err))
+}
+
+// Emits a warning Event targeting the webhook pod. The pod name must be provided by
+// the downward API in $POD_NAME, otherwise no event is sent.
+func (s *LinodeDNSProviderSolver) emitPodEvent(reason, message string) {
+	name := strings.TrimSpace(os.Getenv("POD_NAME"))
+	if name == "" || s.k8s == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), annotateTimeout)
+	defer cancel()
+
+	namespace := s.PodNamespace()
+	now := k8smetav1.Now()
+	if _, err := s.k8s.CoreV1().Events(namespace).Create(ctx, &k8sapiv1.Event{
+		ObjectMeta: k8smetav1.ObjectMeta{
+			GenerateName: name + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject: k8sapiv1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       name,
+			Namespace:  namespace,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           k8sapiv1.EventTypeWarning,
+		Source:         k8sapiv1.EventSource{Component: "acme-linode"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}, k8smetav1.CreateOptions{}); err != nil {
+		klog.Warningf("could not emit %s event for pod %s/%s: %v", reason, namespace, name, err)
+	}
+}
@@ -0,0 +1,75 @@
+package acme
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/linode/linodego"
+)
+
+func TestWatchdog(t *testing.T) {
+	trips := make(chan int, 10)
+	w := newWatchdog(3, 50*time.Millisecond, func(failures int, _ error) { trips <- failures })
+	apiErr := &linodego.Error{Code: http.StatusUnauthorized, Message: "Invalid Token"}
+
+	// Errors that are not from the Linode API do not count as failures.
+	for range 5 {
+		w.observe(ErrNoRecord)
+	}
+
+	if n := w.consecutiveFailures(); n != 0 {
+		t.Errorf("expected non-API errors to be ignored, got %d failures", n)
+	}
+
+	for range 2 {
+		w.observe(apiErr)
+	}
+
+	if err := w.healthy(); err != nil {
+		t.Errorf("expected watchdog to be healthy below the threshold: %v", err)
+	}
+
+	w.observe(apiErr)
+	if err := w.healthy(); !errors.Is(err, ErrUnhealthy) {
+		t.Errorf("expected ErrUnhealthy at the threshold, got %v", err)
+	}
+
+	if failures := <-trips; failures != 3 {
+		t.Errorf("expected trip after 3 failures, got %d", failures)
+	}
+
+	// Failures during the cooldown do not trip the watchdog again.
+	w.observe(apiErr)
+	select {
+	case <-trips:
+		t.Error("expected no trip during the cooldown")
+	default:
+	}
+
+	// After the cooldown the webhook is ready again to probe Linode.
+	time.Sleep(60 * time.Millisecond)
+	if err := w.healthy(); err != nil {
+		t.Errorf("expected watchdog to be healthy after the cooldown: %v", err)
+	}
+
+	w.observe(apiErr)
+	if err := w.healthy(); !errors.Is(err, ErrUnhealthy) {
+		t.Errorf("expected failed probe to trip the watchdog, got %v", err)
+	}
+	<-trips
+
+	// A success resets the watchdog.
+	w.observe(nil)
+	if err := w.healthy(); err != nil || w.consecutiveFailures() != 0 {
+		t.Errorf("expected success to reset the watchdog: %v", err)
+	}
+
+	// A nil watchdog is always healthy.
+	var disabled *watchdog
+	disabled.observe(apiErr)
+	if err := disabled.healthy(); err != nil {
+		t.Errorf("expected disabled watchdog to be healthy: %v", err)
+	}
+}
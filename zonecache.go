@@ -0,0 +1,113 @@
+package acme
+
+import (
+	"strings"
+	"time"
+
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+const DefaultZoneCacheTTL = 5 * time.Minute
+
+// Caches the Linode domains found for zone names, keyed by the account of the client
+// and the lower case name, so that the challenges of multi-SAN certificates and
+// renewals in the same zone do not look up the zone again for every challenge. The
+// domains of a zone are discarded once they expire or when a record operation in the
+// zone is not found, e.g. because the domain was deleted and recreated with a new ID.
+type zoneCache struct {
+	ttl   time.Duration
+	zones *lru[string, cachedZone]
+}
+
+type cachedZone struct {
+	domains []linodego.Domain
+	expires time.Time
+}
+
+// Returns nil if the ttl is not positive, disabling the cache.
+func newZoneCache(solver string, ttl time.Duration) *zoneCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &zoneCache{ttl: ttl, zones: newLRU[string, cachedZone](solver, "zoneCache", DefaultCacheSize, nil)}
+}
+
+// Returns the cached domains with the name unless they have expired; a nil cache has
+// no domains.
+func (c *zoneCache) get(account, name string) ([]linodego.Domain, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	key := account + "/" + name
+	zone, ok := c.zones.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(zone.expires) {
+		c.zones.Remove(key)
+		return nil, false
+	}
+	return zone.domains, true
+}
+
+// Caches the domains found in the account by their names.
+func (c *zoneCache) add(account string, domains []linodego.Domain) {
+	if c == nil {
+		return
+	}
+
+	byName := make(map[string][]linodego.Domain, len(domains))
+	for _, domain := range domains {
+		name := asciiName(domain.Domain)
+		byName[name] = append(byName[name], domain)
+	}
+
+	expires := time.Now().Add(c.ttl)
+	for name, domains := range byName {
+		c.zones.Add(account+"/"+name, cachedZone{domains: domains, expires: expires})
+	}
+}
+
+// Discards the cached zones of the account that include the domain.
+func (c *zoneCache) invalidate(account string, zoneID int) {
+	if c == nil {
+		return
+	}
+
+	prefix := account + "/"
+	if n := c.zones.RemoveFunc(func(key string, zone cachedZone) bool {
+		if !strings.HasPrefix(key, prefix) {
+			return false
+		}
+
+		for _, domain := range zone.domains {
+			if domain.ID == zoneID {
+				return true
+			}
+		}
+		return false
+	}); n > 0 {
+		klog.V(2).Infof("discarded the cached zone of linode domain ID %d", zoneID)
+	}
+}
+
+// SetZoneCache makes the client cache the zones it finds in the cache.
+func (l *Linode) SetZoneCache(cache *zoneCache) *Linode {
+	l.zones = cache
+	return l
+}
+
+// Returns the error of listing or creating the records of the zone, discarding the
+// cached zone if the API did not find it, e.g. because the domain was deleted. The
+// errors of operations on a record by its ID do not discard the zone, since a record
+// that is not found (e.g. while a created record is confirmed, or once it has already
+// been deleted) does not mean that the zone is gone.
+func (l *Linode) recordError(zoneID int, err error) error {
+	if linodego.IsNotFound(err) {
+		l.zones.invalidate(l.account, zoneID)
+	}
+	return apiError(err)
+}
@@ -0,0 +1,87 @@
+package acme
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/linode/linodego"
+	"go.rtnl.ai/acme-linode/linodetest"
+)
+
+func TestZoneCache(t *testing.T) {
+	linode, fake := newTestLinode(t)
+	linode.account = "zonecache"
+	linode.SetZoneCache(newZoneCache("test", time.Minute))
+	defer caches.Delete(cacheKey{solver: "test", name: "zoneCache"})
+	zone := fake.AddDomain("example.com")
+
+	// The challenges of every name in the zone are resolved with one lookup.
+	for _, fqdn := range []string{"_acme-challenge.example.com.", "_acme-challenge.www.example.com.", "_acme-challenge.api.example.com."} {
+		found, _, err := linode.FindCandidateZone(fqdn, "example.com.")
+		if err != nil || found.ID != zone.ID {
+			t.Fatalf("could not find zone for %s: %v", fqdn, err)
+		}
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected 1 list domains request, got %d", calls)
+	}
+
+	// A record that is not found, e.g. while a created record is confirmed, does not
+	// discard the cached zone.
+	if _, err := linode.GetRecord(zone.ID, 9999); !errors.Is(err, ErrNoRecord) {
+		t.Fatalf("expected ErrNoRecord, got %v", err)
+	}
+
+	if err := linode.DeleteRecord(zone.ID, 9999); !linodego.IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	if _, _, err := linode.FindCandidateZone("_acme-challenge.example.com.", "example.com."); err != nil {
+		t.Fatalf("could not find zone: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 1 {
+		t.Errorf("expected the zone to remain cached, got %d requests", calls)
+	}
+
+	// A zone whose records are not found is discarded, since the domain is gone.
+	fake.Fail("GET /v4/domains/{domainID}/records", linodetest.Fault{Status: http.StatusNotFound})
+	if _, err := linode.FindRecords(zone.ID, "_acme-challenge"); !linodego.IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	if _, _, err := linode.FindCandidateZone("_acme-challenge.example.com.", "example.com."); err != nil {
+		t.Fatalf("could not find zone: %v", err)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 2 {
+		t.Errorf("expected the zone to be looked up again, got %d requests", calls)
+	}
+}
+
+func TestZoneCacheExpiry(t *testing.T) {
+	cache := newZoneCache("test", time.Millisecond)
+	defer caches.Delete(cacheKey{solver: "test", name: "zoneCache"})
+
+	linode, fake := newTestLinode(t)
+	linode.SetZoneCache(cache)
+	fake.AddDomain("example.com")
+
+	for range 2 {
+		if _, err := linode.FindZone("example.com"); err != nil {
+			t.Fatalf("could not find zone: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if calls := fake.Calls("GET /v4/domains"); calls != 2 {
+		t.Errorf("expected expired zones to be looked up again, got %d requests", calls)
+	}
+
+	if newZoneCache("test", 0) != nil {
+		t.Error("expected the zone cache to be disabled without a ttl")
+	}
+}
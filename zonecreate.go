@@ -0,0 +1,124 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sync"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/linode/linodego"
+	"k8s.io/klog/v2"
+)
+
+// The mailbox of the SOA email of created zones if the issuer does not set one.
+const DefaultZoneSOAEmailUser = "hostmaster"
+
+// Serializes the creation of missing zones so that concurrent challenges for the same
+// zone create it only once; zones are rarely created, so a single lock suffices.
+var zoneCreation sync.Mutex
+
+// CreateZone creates a master zone for the domain in the Linode account with the SOA
+// email and the Linode defaults for its SOA timers, tagging it with the tag of the
+// domain selector so that the client selects it. The lookups of the client and the
+// pre-warmed domains of its account are discarded so the zone is found afterwards.
+func (l *Linode) CreateZone(domain, soaEmail string) (zone *linodego.Domain, err error) {
+	klog.Infof("creating zone %s", domain)
+	ctx, cancel := l.writeContext()
+	defer cancel()
+
+	defer func() {
+		event := l.auditEvent(AuditCreateZone, 0)
+		event.Zone = domain
+		if zone != nil {
+			event.ZoneID = zone.ID
+		}
+		l.audit.Record(event, err)
+	}()
+
+	opts := linodego.DomainCreateOptions{
+		Domain:      domain,
+		Type:        linodego.DomainTypeMaster,
+		SOAEmail:    soaEmail,
+		Description: "Created by acme-linode",
+	}
+
+	if l.domainTag != "" {
+		opts.Tags = []string{l.domainTag}
+	}
+
+	if zone, err = l.client.CreateDomain(ctx, opts); err != nil {
+		klog.Errorf("failed to create linode zone %s: %v", domain, err)
+		return nil, apiError(err)
+	}
+
+	l.forgetDomains()
+	l.auditZones.Store(zone.ID, zone.Domain)
+	return zone, nil
+}
+
+// Discards the memoized and pre-warmed domains of the account, e.g. after a zone was
+// created, so that the next lookup lists the domains again.
+func (l *Linode) forgetDomains() {
+	if l.memo != nil {
+		l.memo.Lock()
+		l.memo.domains = nil
+		clear(l.memo.zones)
+		l.memo.Unlock()
+	}
+	l.domains.remove(l.account)
+}
+
+// Creates the zone resolved by cert-manager if no zone in the Linode account hosts the
+// fqdn of the challenge and the issuer sets createZoneIfMissing, returning the zone
+// and the entry of the fqdn in it. The zone is looked up again with the creation lock
+// held, in case a concurrent challenge created it first.
+func (s *LinodeDNSProviderSolver) createMissingZone(linode LinodeAPI, ch *v1alpha1.ChallengeRequest, cfg LinodeDNSProviderConfig) (zone *linodego.Domain, entry string, err error) {
+	client, ok := linode.(*Linode)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: createZoneIfMissing is not supported by the linode client of the solver", ErrInvalidConfig)
+	}
+
+	name := normalizeZone(cfg.resolvedZone(ch))
+	if name == "" {
+		return nil, "", fmt.Errorf("%w for fqdn %q and cert-manager did not resolve the zone to create", ErrNoZone, ch.ResolvedFQDN)
+	}
+
+	// Challenges denied by the policy must not create zones either.
+	created, _ := DomainEntry(normalizeZone(ch.ResolvedFQDN), name)
+	if err = s.authorize(ch, AuditPresent, name, created, 0); err != nil {
+		return nil, "", err
+	}
+
+	zoneCreation.Lock()
+	defer zoneCreation.Unlock()
+
+	client.forgetDomains()
+	if zone, entry, err = s.findZone(client, ch, cfg); !errors.Is(err, ErrNoZone) {
+		return zone, entry, err
+	}
+
+	soaEmail := cfg.ZoneSOAEmail
+	if soaEmail == "" {
+		soaEmail = DefaultZoneSOAEmailUser + "@" + name
+	}
+
+	if zone, err = client.CreateZone(name, soaEmail); err != nil {
+		return nil, "", err
+	}
+
+	klog.Infof("created linode zone %s (id %d) for fqdn %s", zone.Domain, zone.ID, ch.ResolvedFQDN)
+	return zone, created, nil
+}
+
+// Validates the SOA email of created zones, which must be a bare email address.
+func validateSOAEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return fmt.Errorf("%w: zoneSOAEmail must be an email address", ErrInvalidConfig)
+	}
+	return nil
+}
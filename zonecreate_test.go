@@ -0,0 +1,76 @@
+package acme
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestCreateZoneIfMissing(t *testing.T) {
+	solver, fake := newTestSolver(t)
+
+	challenge := func(key, config string) *v1alpha1.ChallengeRequest {
+		ch := &v1alpha1.ChallengeRequest{ResolvedFQDN: "_acme-challenge." + key + ".example.com.", ResolvedZone: "example.com.", ResourceNamespace: "default", Key: key}
+		if config != "" {
+			ch.Config = &extapi.JSON{Raw: []byte(config)}
+		}
+		return ch
+	}
+
+	// Missing zones are not created unless the issuer opts in.
+	if err := solver.Present(challenge("key1", "")); !errors.Is(err, ErrNoZone) {
+		t.Fatalf("expected ErrNoZone, got %v", err)
+	}
+
+	if calls := fake.Calls("POST /v4/domains"); calls != 0 {
+		t.Fatalf("expected no zone to be created, got %d requests", calls)
+	}
+
+	// Concurrent challenges for the missing zone create it only once.
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i, key := range []string{"key2", "key3", "key4", "key5"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = solver.Present(challenge(key, `{"createZoneIfMissing": true, "domainTag": "acme"}`))
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("could not present challenge: %v", err)
+		}
+	}
+
+	if calls := fake.Calls("POST /v4/domains"); calls != 1 {
+		t.Fatalf("expected the zone to be created once, got %d requests", calls)
+	}
+
+	zone, err := NewLinodeWithHTTPClient("linodetest", fake.Client()).SetBaseURL(fake.URL()).FindZone("example.com")
+	if err != nil {
+		t.Fatalf("could not find the created zone: %v", err)
+	}
+
+	if zone.Type != "master" || zone.SOAEmail != "hostmaster@example.com" || len(zone.Tags) != 1 || zone.Tags[0] != "acme" {
+		t.Errorf("unexpected created zone %+v", zone)
+	}
+
+	if records := fake.Records(zone.ID); len(records) != 4 {
+		t.Errorf("expected 4 records in the created zone, got %+v", records)
+	}
+
+	// Invalid configs are rejected.
+	for _, config := range []string{
+		`{"createZoneIfMissing": true, "domainIDs": [1234]}`,
+		`{"createZoneIfMissing": true, "zoneSOAEmail": "Hostmaster <hostmaster@example.com>"}`,
+	} {
+		if _, err := LoadConfig(&extapi.JSON{Raw: []byte(config)}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected %s to be invalid, got %v", config, err)
+		}
+	}
+}